@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/spf13/cobra"
+
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec/fakeapi"
+)
+
+var devCmd = &cobra.Command{
+	Use:   "dev",
+	Short: "Utilities for developing and demoing canyon",
+}
+
+var fakeApiCmd = &cobra.Command{
+	Use:   "fake-api",
+	Short: "Serve a fake Humanitec API seeded from a fixture file",
+	Long: `Serve a fake Humanitec API seeded from a yaml fixture describing an Organization, or from a bundled demo
+Organization when no fixture is given. Point HUMANITEC_API_PREFIX at the printed url to run canyon without network
+access.`,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		fixture := fakeapi.DefaultFixture()
+		if p, _ := cmd.Flags().GetString("fixture"); p != "" {
+			var err error
+			if fixture, err = fakeapi.LoadFixture(p); err != nil {
+				return err
+			}
+		}
+
+		addr, _ := cmd.Flags().GetString("listen")
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("failed to listen: %w", err)
+		}
+		token := fixture.Token
+		if token == "" {
+			token = "fake"
+		}
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), `Serving the fake Humanitec API for Organization '%s'. Configure canyon with:
+
+export HUMANITEC_API_PREFIX=http://%s
+export HUMANITEC_TOKEN=%s
+`, fixture.Org, l.Addr().String(), token)
+
+		fake := fakeapi.New(fixture)
+		server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			slog.Info("fake api request", slog.String("method", r.Method), slog.String("url", r.URL.String()))
+			fake.ServeHTTP(w, r)
+		})}
+		go func() {
+			<-cmd.Context().Done()
+			_ = server.Close()
+		}()
+		if err := server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	},
+}
+
+func init() {
+	fakeApiCmd.Flags().String("fixture", "", "The yaml fixture describing the Organization to serve, defaults to a bundled demo Organization")
+	fakeApiCmd.Flags().String("listen", "127.0.0.1:8080", "The address to listen on")
+	devCmd.AddCommand(fakeApiCmd)
+	rootCmd.AddCommand(devCmd)
+}
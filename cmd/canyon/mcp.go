@@ -6,10 +6,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"os"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec"
 	"github.com/humanitec/canyon-cli/internal/mcp"
 	"github.com/humanitec/canyon-cli/internal/mcp/tools"
 	"github.com/humanitec/canyon-cli/internal/rpc"
@@ -22,6 +24,25 @@ var mcpCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cmd.SilenceUsage = true
 
+		if p, _ := cmd.Flags().GetString("profile"); p != "" {
+			if err := humanitec.SetActiveProfile(p); err != nil {
+				return err
+			}
+		}
+
+		cacheDir, _ := cmd.Flags().GetString("cache-dir")
+		cacheTtl, _ := cmd.Flags().GetDuration("cache-ttl")
+		if err := humanitec.ConfigureDefaultResponseCache(cacheTtl, cacheDir); err != nil {
+			return fmt.Errorf("failed to setup response cache: %w", err)
+		}
+
+		ctx, err := withApiSource(cmd)
+		if err != nil {
+			return err
+		}
+		roots, _ := cmd.Flags().GetStringSlice("root")
+		ctx = tools.WithWorkspaceRoots(ctx, roots)
+
 		h := mcp.AsHandler(tools.New())
 		h = rpc.RecoveryMiddleware(h)
 		h = rpc.LoggingMiddleware(h)
@@ -45,6 +66,20 @@ var mcpCmd = &cobra.Command{
 					if len(scanner.Bytes()) == 0 {
 						break
 					}
+					// messages without a method are the responses of the client to the requests of the server
+					var probe struct {
+						Method string `json:"method"`
+					}
+					if err := json.Unmarshal(scanner.Bytes(), &probe); err == nil && probe.Method == "" {
+						var resp rpc.JsonRpcResponseInner
+						if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+							errChan <- fmt.Errorf("failed to read json formatted line '%q' as a response: %w", scanner.Text(), err)
+							return
+						} else if !server.Respond(resp) {
+							slog.Warn("dropping response to an unknown request", slog.Int("id", resp.Id))
+						}
+						continue
+					}
 					var msg rpc.JsonRpcRequest
 					dec := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
 					dec.DisallowUnknownFields()
@@ -52,7 +87,7 @@ var mcpCmd = &cobra.Command{
 						errChan <- fmt.Errorf("failed to read json formatted line '%q' as a request: %w", scanner.Text(), err)
 						return
 					}
-					server.In() <- msg.WithContext(cmd.Context())
+					server.In() <- msg.WithContext(ctx)
 				}
 			}
 		}()
@@ -74,5 +109,10 @@ var mcpCmd = &cobra.Command{
 }
 
 func init() {
+	addApiSourceFlags(mcpCmd)
+	mcpCmd.Flags().String("profile", os.Getenv("CANYON_PROFILE"), "The named Humanitec credential profile to use, defaults to the current profile or the humctl login")
+	mcpCmd.Flags().String("cache-dir", "", "Persist cached Humanitec API responses in the given directory between sessions")
+	mcpCmd.Flags().Duration("cache-ttl", humanitec.DefaultCacheTTL, "How long to serve cached Humanitec API responses before revalidating them, 0 disables the cache")
+	mcpCmd.Flags().StringSlice("root", nil, "A workspace directory that tools may read local files like Score files from when the client does not share its roots, defaults to the working directory")
 	rootCmd.AddCommand(mcpCmd)
 }
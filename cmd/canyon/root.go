@@ -9,6 +9,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/humanitec/canyon-cli/internal"
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec"
 )
 
 var rootCmd = &cobra.Command{
@@ -35,6 +36,13 @@ var rootCmd = &cobra.Command{
 			}()
 		}
 		internal.SetupLogging(d, w)
+
+		traceHttp, _ := cmd.Flags().GetBool("trace-http")
+		traceHttpBodies, _ := cmd.Flags().GetBool("trace-http-bodies")
+		humanitec.ConfigureHttpTracing(humanitec.TraceOptions{
+			Enabled: traceHttp || traceHttpBodies || strings.ToLower(os.Getenv("CANYON_TRACE_HTTP")) == "true",
+			Bodies:  traceHttpBodies,
+		})
 		return nil
 	},
 }
@@ -43,4 +51,6 @@ func init() {
 	rootCmd.Version = fmt.Sprintf("%s %s", internal.ModulePath, internal.ModuleVersion)
 	rootCmd.PersistentFlags().BoolP("debug", "d", false, "Increase log verbosity to debug level")
 	rootCmd.PersistentFlags().String("log-file", "", "Direct structured logging output to the given log file rather than stderr")
+	rootCmd.PersistentFlags().Bool("trace-http", false, "Log the method, url, status, and latency of every Humanitec API request")
+	rootCmd.PersistentFlags().Bool("trace-http-bodies", false, "Include redacted request and response headers and bodies in the http trace")
 }
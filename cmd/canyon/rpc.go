@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"log/slog"
 	"math/rand/v2"
+	"os"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec"
 	"github.com/humanitec/canyon-cli/internal/mcp"
 	"github.com/humanitec/canyon-cli/internal/mcp/tools"
 	"github.com/humanitec/canyon-cli/internal/ref"
@@ -44,10 +46,21 @@ var rpcCmd = &cobra.Command{
 				}
 			}
 		}
+		if p, _ := cmd.Flags().GetString("profile"); p != "" {
+			if err := humanitec.SetActiveProfile(p); err != nil {
+				return err
+			}
+		}
+
 		requestId := int(rand.Int64())
 		rawRawParams, _ := json.Marshal(intermediate)
 		slog.Info("executing method with params", slog.String("method", args[0]), slog.String("params", string(rawRawParams)), slog.Int("request_id", requestId))
 
+		ctx, err := withApiSource(cmd)
+		if err != nil {
+			return err
+		}
+
 		h := mcp.AsHandler(tools.New())
 		h = rpc.RecoveryMiddleware(h)
 		h = rpc.LoggingMiddleware(h)
@@ -60,7 +73,7 @@ var rpcCmd = &cobra.Command{
 				Method: args[0],
 				Id:     ref.Ref(requestId),
 				Params: rawRawParams,
-			}.WithContext(cmd.Context())
+			}.WithContext(ctx)
 		}()
 
 		out := server.Out()
@@ -84,6 +97,8 @@ var rpcCmd = &cobra.Command{
 
 func init() {
 	rpcCmd.Flags().StringToStringP("set", "s", nil, "Set key-value params")
+	addApiSourceFlags(rpcCmd)
+	rpcCmd.Flags().String("profile", os.Getenv("CANYON_PROFILE"), "The named Humanitec credential profile to use")
 	rpcCmd.Flags().Bool("stdin", false, "Read params from stdin")
 	rootCmd.AddCommand(rpcCmd)
 }
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec"
+	"github.com/humanitec/canyon-cli/internal/score"
+)
+
+var scoreCmd = &cobra.Command{
+	Use:   "score",
+	Short: "Work with Score workload files",
+}
+
+var scoreValidateCmd = &cobra.Command{
+	Use:   "validate FILE",
+	Short: "Validate a Score file and its Humanitec extensions before deploying it",
+	Long: `Validate a Score file against the Score specification and the Humanitec extensions file next to it. When an
+Organization is available, the workload spec is also checked against the schema of its workload profile and every
+resource type is checked for a Resource Definition. Issues are printed with their file, line, and column.`,
+	Args:          cobra.ExactArgs(1),
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		if p, _ := cmd.Flags().GetString("profile"); p != "" {
+			if err := humanitec.SetActiveProfile(p); err != nil {
+				return err
+			}
+		}
+		input, err := score.ReadInput(args[0])
+		if err != nil {
+			return err
+		}
+		opts := score.Options{}
+		opts.Profile, _ = cmd.Flags().GetString("workload-profile")
+		extensionsPath, _ := cmd.Flags().GetString("extensions")
+		if extensionsPath == "" {
+			extensionsPath = score.DefaultExtensionsPath(args[0])
+		}
+		if extensionsPath != "" {
+			ext, err := score.ReadInput(extensionsPath)
+			if err != nil {
+				return err
+			}
+			opts.Extensions = &ext
+		}
+
+		ctx, err := withApiSource(cmd)
+		if err != nil {
+			return err
+		}
+		orgId, _ := cmd.Flags().GetString("org")
+		if orgId == "" {
+			if profile, err := humanitec.ActiveProfile(); err == nil {
+				orgId = profile.DefaultOrg
+			}
+		}
+		if orgId != "" {
+			if hc, err := humanitec.NewHumanitecClientWithCurrentToken(ctx); err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Warning: skipping the Organization checks: %v\n", err)
+			} else if opts, err = score.WithOrganization(ctx, hc, orgId, opts); err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Warning: skipping the Organization checks: %v\n", err)
+			}
+		}
+
+		report := score.Validate(input, opts)
+		for _, i := range report.Issues {
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), i.String())
+		}
+		if report.Errors() > 0 {
+			return fmt.Errorf("%s has %d errors and %d warnings", input.File, report.Errors(), report.Warnings())
+		}
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s is valid for workload profile '%s' with %d warnings\n", input.File, report.Profile, report.Warnings())
+		return nil
+	},
+}
+
+func init() {
+	addApiSourceFlags(scoreValidateCmd)
+	scoreValidateCmd.Flags().String("extensions", "", "The Humanitec extensions file, defaults to "+score.ExtensionsFileName+" next to the Score file if it exists")
+	scoreValidateCmd.Flags().String("org", "", "The Humanitec Organization to check the workload profile and resource types against, defaults to the default org of the profile")
+	scoreValidateCmd.Flags().String("workload-profile", "", "The workload profile to check against, overriding the profile of the extensions file")
+	scoreValidateCmd.Flags().String("profile", os.Getenv("CANYON_PROFILE"), "The named Humanitec credential profile to use")
+	scoreCmd.AddCommand(scoreValidateCmd)
+	rootCmd.AddCommand(scoreCmd)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Export the state of a Humanitec Organization for offline use",
+}
+
+var snapshotExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export a read-only snapshot of an Organization to an archive",
+	Long: `Export the applications, environments, recent deployments and their sets, pipelines with their recent runs and
+waiting approvals, workload profiles, resource definitions, and action pipelines of an Organization to a versioned archive. Serve the archive to an LLM client with
+'canyon mcp --snapshot FILE' to explore the Organization without API access.`,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		if p, _ := cmd.Flags().GetString("profile"); p != "" {
+			if err := humanitec.SetActiveProfile(p); err != nil {
+				return err
+			}
+		}
+		orgId, _ := cmd.Flags().GetString("org")
+		if orgId == "" {
+			if profile, err := humanitec.ActiveProfile(); err == nil {
+				orgId = profile.DefaultOrg
+			}
+		}
+		if orgId == "" {
+			return fmt.Errorf("an --org is required since the profile has no default org")
+		}
+		output, _ := cmd.Flags().GetString("output")
+		if output == "" {
+			output = orgId + "-snapshot.tar.gz"
+		}
+		maxDeploys, _ := cmd.Flags().GetInt("max-deploys")
+		maxRuns, _ := cmd.Flags().GetInt("max-runs")
+
+		hc, err := humanitec.NewHumanitecClientWithCurrentToken(cmd.Context())
+		if err != nil {
+			return err
+		}
+		snapshot, err := humanitec.ExportSnapshot(cmd.Context(), hc, orgId, humanitec.SnapshotOptions{MaxDeploys: maxDeploys, MaxRuns: maxRuns})
+		if err != nil {
+			return err
+		}
+
+		f, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to create snapshot file: %w", err)
+		}
+		if err := snapshot.WriteArchive(f); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("failed to write snapshot: %w", err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to write snapshot: %w", err)
+		}
+		for _, w := range snapshot.Manifest.Warnings {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %s\n", w)
+		}
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Wrote snapshot version %d of Organization '%s' with %d responses to %s\n", snapshot.Manifest.Version, orgId, len(snapshot.Responses), output)
+		return nil
+	},
+}
+
+func init() {
+	snapshotExportCmd.Flags().String("org", "", "The Humanitec Organization to export, defaults to the default org of the profile")
+	snapshotExportCmd.Flags().StringP("output", "o", "", "The archive file to write, defaults to ORG-snapshot.tar.gz")
+	snapshotExportCmd.Flags().Int("max-deploys", humanitec.DefaultSnapshotMaxDeploys, "The number of most recent deployments to export per environment")
+	snapshotExportCmd.Flags().Int("max-runs", humanitec.DefaultSnapshotMaxRuns, "The number of most recent runs to export per pipeline")
+	snapshotExportCmd.Flags().String("profile", os.Getenv("CANYON_PROFILE"), "The named Humanitec credential profile to use")
+	snapshotCmd.AddCommand(snapshotExportCmd)
+	rootCmd.AddCommand(snapshotCmd)
+}
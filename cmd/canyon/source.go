@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec"
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec/fakeapi"
+)
+
+// addApiSourceFlags adds the flags that control where Humanitec API responses come from.
+func addApiSourceFlags(cmd *cobra.Command) {
+	cmd.Flags().String("record", "", "Record every Humanitec API interaction as a cassette in the given directory")
+	cmd.Flags().String("replay", "", "Serve Humanitec API responses from a cassette directory instead of calling the API")
+	cmd.Flags().Bool("demo", false, "Serve every tool from the bundled canyon-demo Organization without calling the API or requiring a login")
+	cmd.Flags().String("snapshot", "", "Answer read tools from an archive written by 'canyon snapshot export' without calling the API")
+	cmd.MarkFlagsMutuallyExclusive("record", "replay", "demo", "snapshot")
+}
+
+// withApiSource returns the command context with the cassette recorder, replayer, demo data, or snapshot applied if
+// requested.
+func withApiSource(cmd *cobra.Command) (context.Context, error) {
+	ctx := cmd.Context()
+	if demo, _ := cmd.Flags().GetBool("demo"); demo {
+		return humanitec.WithHttpRequestDoer(ctx, fakeapi.New(fakeapi.DefaultFixture())), nil
+	}
+	if p, _ := cmd.Flags().GetString("snapshot"); p != "" {
+		s, err := humanitec.LoadSnapshot(p)
+		if err != nil {
+			return nil, err
+		}
+		slog.Info("serving from snapshot", slog.String("org", s.Manifest.Org), slog.Time("created_at", s.Manifest.CreatedAt))
+		return humanitec.WithHttpRequestDoer(ctx, s), nil
+	}
+	if dir, _ := cmd.Flags().GetString("replay"); dir != "" {
+		r, err := humanitec.LoadCassetteReplayer(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load cassette: %w", err)
+		}
+		return humanitec.WithHttpRequestDoer(ctx, r), nil
+	}
+	if dir, _ := cmd.Flags().GetString("record"); dir != "" {
+		hc, err := humanitec.DefaultHttpClient()
+		if err != nil {
+			return nil, err
+		}
+		r, err := humanitec.NewCassetteRecorder(hc, dir)
+		if err != nil {
+			return nil, err
+		}
+		return humanitec.WithHttpRequestDoer(ctx, r), nil
+	}
+	return ctx, nil
+}
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+
+	"github.com/humanitec/humanitec-go-autogen/client"
 )
 
 type ActionPipelineSummary struct {
@@ -27,13 +29,15 @@ func (r ListActionPipelineSummariesResponse) StatusCode() int {
 	return r.HTTPResponse.StatusCode
 }
 
-func (w *WrappedHumanitecClientImpl) ListActionPipelineSummaries(ctx context.Context, orgId string) (*ListActionPipelineSummariesResponse, error) {
+func (w *WrappedHumanitecClientImpl) ListActionPipelineSummaries(ctx context.Context, orgId string, reqEditors ...client.RequestEditorFn) (*ListActionPipelineSummariesResponse, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, w.apiPrefix+fmt.Sprintf("/orgs/%s/action-pipelines", orgId), nil)
 	if err != nil {
 		return &ListActionPipelineSummariesResponse{}, err
 	}
-	if err := w.requestEditor(ctx, req); err != nil {
-		return &ListActionPipelineSummariesResponse{}, err
+	for _, editor := range append([]client.RequestEditorFn{w.requestEditor}, reqEditors...) {
+		if err := editor(ctx, req); err != nil {
+			return &ListActionPipelineSummariesResponse{}, err
+		}
 	}
 	var out ListActionPipelineSummariesResponse
 	out.HTTPResponse, err = w.httpClient.Do(req)
@@ -0,0 +1,276 @@
+package humanitec
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/humanitec/humanitec-go-autogen/client"
+)
+
+// DefaultCacheTTL is how long a cached response is served without revalidating it against the API.
+const DefaultCacheTTL = time.Minute
+
+// CacheStatusHeader is set on responses served by the ResponseCache so that callers can tell hits from misses.
+const CacheStatusHeader = "X-Canyon-Cache"
+
+type cacheEntry struct {
+	Key       string      `json:"key"`
+	Identity  string      `json:"identity"`
+	Url       string      `json:"url"`
+	Status    int         `json:"status"`
+	Header    http.Header `json:"header"`
+	Body      []byte      `json:"body"`
+	StoredAt  time.Time   `json:"stored_at"`
+	Validated time.Time   `json:"validated"`
+}
+
+// ResponseCache stores successful GET responses keyed by the identity of the token used and the request URL. Entries
+// are served directly while younger than the TTL and are revalidated with If-None-Match when they carry an ETag. Any
+// non-GET request invalidates the entries for the same identity within the same organization. When Dir is set,
+// entries are also persisted to disk so that they survive between sessions.
+type ResponseCache struct {
+	TTL time.Duration
+	Dir string
+
+	lock    sync.Mutex
+	entries map[string]*cacheEntry
+	loaded  bool
+}
+
+func NewResponseCache(ttl time.Duration, dir string) *ResponseCache {
+	return &ResponseCache{TTL: ttl, Dir: dir, entries: make(map[string]*cacheEntry)}
+}
+
+var (
+	defaultResponseCache     = NewResponseCache(DefaultCacheTTL, "")
+	defaultResponseCacheLock sync.Mutex
+)
+
+// DefaultResponseCache returns the process-wide response cache shared by all Humanitec clients.
+func DefaultResponseCache() *ResponseCache {
+	defaultResponseCacheLock.Lock()
+	defer defaultResponseCacheLock.Unlock()
+	return defaultResponseCache
+}
+
+// ConfigureDefaultResponseCache replaces the process-wide response cache. A zero ttl disables caching.
+func ConfigureDefaultResponseCache(ttl time.Duration, dir string) error {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+	}
+	defaultResponseCacheLock.Lock()
+	defer defaultResponseCacheLock.Unlock()
+	defaultResponseCache = NewResponseCache(ttl, dir)
+	return nil
+}
+
+type cacheBypassKey struct{}
+
+// WithCacheBypass returns a context in which cached responses are not served. Fresh responses are still stored.
+func WithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+func isCacheBypassed(ctx context.Context) bool {
+	v, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return v
+}
+
+func hashString(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+// orgScope returns the "/orgs/<org>/" part of the url path, or an empty string if the url is not org scoped.
+func orgScope(u string) string {
+	if i := strings.Index(u, "/orgs/"); i >= 0 {
+		rest := u[i+len("/orgs/"):]
+		if j := strings.IndexAny(rest, "/?"); j >= 0 {
+			rest = rest[:j]
+		}
+		return "/orgs/" + rest + "/"
+	}
+	return ""
+}
+
+func (c *ResponseCache) load() {
+	if c.loaded || c.Dir == "" {
+		c.loaded = true
+		return
+	}
+	c.loaded = true
+	items, err := os.ReadDir(c.Dir)
+	if err != nil {
+		slog.Warn("failed to read cache directory", slog.String("dir", c.Dir), slog.Any("err", err))
+		return
+	}
+	for _, item := range items {
+		if item.IsDir() || filepath.Ext(item.Name()) != ".json" {
+			continue
+		}
+		raw, err := os.ReadFile(filepath.Join(c.Dir, item.Name()))
+		if err != nil {
+			continue
+		}
+		var e cacheEntry
+		if err := json.Unmarshal(raw, &e); err != nil || e.Key == "" {
+			_ = os.Remove(filepath.Join(c.Dir, item.Name()))
+			continue
+		}
+		c.entries[e.Key] = &e
+	}
+}
+
+func (c *ResponseCache) get(key string) *cacheEntry {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.load()
+	return c.entries[key]
+}
+
+func (c *ResponseCache) put(e *cacheEntry) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.load()
+	c.entries[e.Key] = e
+	if c.Dir != "" {
+		raw, _ := json.Marshal(e)
+		if err := os.WriteFile(filepath.Join(c.Dir, e.Key+".json"), raw, 0600); err != nil {
+			slog.Warn("failed to persist cache entry", slog.Any("err", err))
+		}
+	}
+}
+
+// Invalidate drops all entries for the given token identity whose url is within the same org scope as u. If u is
+// not org scoped, nothing is invalidated.
+func (c *ResponseCache) Invalidate(identity, u string) {
+	scope := orgScope(u)
+	if scope == "" {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.load()
+	for k, e := range c.entries {
+		if e.Identity == identity && strings.Contains(e.Url, scope) {
+			delete(c.entries, k)
+			if c.Dir != "" {
+				_ = os.Remove(filepath.Join(c.Dir, k+".json"))
+			}
+		}
+	}
+}
+
+// Clear drops all entries.
+func (c *ResponseCache) Clear() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.load()
+	for k := range c.entries {
+		if c.Dir != "" {
+			_ = os.Remove(filepath.Join(c.Dir, k+".json"))
+		}
+	}
+	c.entries = make(map[string]*cacheEntry)
+}
+
+func (e *cacheEntry) toResponse(req *http.Request, status string) *http.Response {
+	h := e.Header.Clone()
+	if h == nil {
+		h = make(http.Header)
+	}
+	h.Set(CacheStatusHeader, status)
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status)),
+		StatusCode:    e.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// cachingDoer serves GET requests from the cache and invalidates it on writes.
+type cachingDoer struct {
+	inner client.HttpRequestDoer
+	cache *ResponseCache
+}
+
+func (d *cachingDoer) Do(req *http.Request) (*http.Response, error) {
+	if d.cache == nil || d.cache.TTL <= 0 {
+		return d.inner.Do(req)
+	}
+	identity := hashString(req.Header.Get("Authorization"))
+	u := req.URL.String()
+	if isReadOnlyQuery(req) {
+		return d.inner.Do(req)
+	} else if req.Method != http.MethodGet {
+		resp, err := d.inner.Do(req)
+		d.cache.Invalidate(identity, u)
+		return resp, err
+	}
+
+	key := hashString(identity + " " + u)
+	entry := d.cache.get(key)
+	if entry != nil && !isCacheBypassed(req.Context()) {
+		if time.Since(entry.Validated) < d.cache.TTL {
+			return entry.toResponse(req, "hit"), nil
+		}
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			req = req.Clone(req.Context())
+			req.Header.Set("If-None-Match", etag)
+		}
+	}
+
+	resp, err := d.inner.Do(req)
+	if err != nil {
+		return resp, err
+	}
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		_ = resp.Body.Close()
+		refreshed := *entry
+		refreshed.Validated = time.Now()
+		d.cache.put(&refreshed)
+		return refreshed.toResponse(req, "revalidated"), nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	entry = &cacheEntry{
+		Key:       key,
+		Identity:  identity,
+		Url:       u,
+		Status:    resp.StatusCode,
+		Header:    resp.Header.Clone(),
+		Body:      body,
+		StoredAt:  now,
+		Validated: now,
+	}
+	d.cache.put(entry)
+	resp.Header.Set(CacheStatusHeader, "miss")
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
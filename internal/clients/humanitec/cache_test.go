@@ -0,0 +1,85 @@
+package humanitec
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachingDoer(t *testing.T) {
+	var calls, notModified int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(r.Method + " " + r.URL.Path))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	cache := NewResponseCache(time.Hour, dir)
+	d := &cachingDoer{inner: http.DefaultClient, cache: cache}
+
+	var statusLine string
+	do := func(ctx context.Context, method, path, token string) (string, string) {
+		req, _ := http.NewRequestWithContext(ctx, method, srv.URL+path, nil)
+		req.Header.Set("Authorization", token)
+		resp, err := d.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		raw, _ := io.ReadAll(resp.Body)
+		statusLine = resp.Status
+		return string(raw), resp.Header.Get(CacheStatusHeader)
+	}
+
+	body, status := do(context.Background(), http.MethodGet, "/orgs/a/apps", "t1")
+	assert.Equal(t, "GET /orgs/a/apps", body)
+	assert.Equal(t, "miss", status)
+	_, status = do(context.Background(), http.MethodGet, "/orgs/a/apps", "t1")
+	assert.Equal(t, "hit", status)
+	assert.Equal(t, "200 OK", statusLine)
+	assert.Equal(t, 1, calls)
+
+	// different token identities do not share entries
+	_, status = do(context.Background(), http.MethodGet, "/orgs/a/apps", "t2")
+	assert.Equal(t, "miss", status)
+
+	// bypass skips the cached entry
+	_, status = do(WithCacheBypass(context.Background()), http.MethodGet, "/orgs/a/apps", "t1")
+	assert.Equal(t, "miss", status)
+
+	// entries are persisted and reloaded
+	d.cache = NewResponseCache(time.Hour, dir)
+	_, status = do(context.Background(), http.MethodGet, "/orgs/a/apps", "t1")
+	assert.Equal(t, "hit", status)
+
+	// read-only queries do not invalidate anything
+	body, _ = do(context.Background(), http.MethodPost, "/orgs/a/apps/b/envs/c/resources/graph", "t1")
+	assert.Equal(t, "POST /orgs/a/apps/b/envs/c/resources/graph", body)
+	_, status = do(context.Background(), http.MethodGet, "/orgs/a/apps", "t1")
+	assert.Equal(t, "hit", status)
+
+	// writes invalidate the org scope for the same identity
+	do(context.Background(), http.MethodPost, "/orgs/a/action-pipelines/x/calls", "t1")
+	_, status = do(context.Background(), http.MethodGet, "/orgs/a/apps", "t1")
+	assert.Equal(t, "miss", status)
+	_, status = do(context.Background(), http.MethodGet, "/orgs/a/apps", "t2")
+	assert.Equal(t, "hit", status)
+
+	// stale entries are revalidated with the etag
+	d.cache.TTL = time.Nanosecond
+	body, status = do(context.Background(), http.MethodGet, "/orgs/a/apps", "t1")
+	assert.Equal(t, "GET /orgs/a/apps", body)
+	assert.Equal(t, "revalidated", status)
+	assert.Equal(t, 1, notModified)
+}
@@ -0,0 +1,240 @@
+package humanitec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/humanitec/humanitec-go-autogen/client"
+)
+
+// OfflineDoer is implemented by request doers that serve responses without contacting Humanitec. Clients using an
+// offline doer do not require the user to be logged in.
+type OfflineDoer interface {
+	client.HttpRequestDoer
+	Offline() bool
+}
+
+// CassetteRequest is the part of a request used to match it against recorded interactions. Credentials are never
+// part of it.
+type CassetteRequest struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Query  string `json:"query,omitempty"`
+	Body   string `json:"body,omitempty"`
+}
+
+// CassetteResponse is a recorded response. JSON bodies are stored as-is for readability, other bodies as text.
+type CassetteResponse struct {
+	Status   int             `json:"status"`
+	Header   http.Header     `json:"header,omitempty"`
+	BodyJson json.RawMessage `json:"body_json,omitempty"`
+	BodyText string          `json:"body_text,omitempty"`
+}
+
+// CassetteInteraction is a single recorded request and response. Each is stored as a json file in the cassette
+// directory, named in the order they were recorded.
+type CassetteInteraction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+func (r CassetteRequest) key() string {
+	return r.Method + " " + r.Path + "?" + r.Query + " " + r.Body
+}
+
+func (r CassetteResponse) body() []byte {
+	if len(r.BodyJson) > 0 {
+		return r.BodyJson
+	}
+	return []byte(r.BodyText)
+}
+
+// recordedHeaders are the response headers worth keeping in a cassette.
+var recordedHeaders = []string{"Content-Type", "Link", "ETag"}
+
+// normalizeBody scrubs secrets from a body and makes json bodies independent of key order and formatting.
+func normalizeBody(body []byte) string {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return ""
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(body, &decoded); err == nil {
+		raw, _ := json.Marshal(redactJson(decoded))
+		return string(raw)
+	}
+	return sensitiveValuePattern.ReplaceAllString(string(body), "${1}"+redacted)
+}
+
+func newCassetteRequest(req *http.Request) (CassetteRequest, error) {
+	out := CassetteRequest{Method: req.Method, Path: req.URL.Path}
+	if q := req.URL.Query(); len(q) > 0 {
+		out.Query = q.Encode()
+	}
+	if req.Body != nil {
+		raw, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return out, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(raw))
+		out.Body = normalizeBody(raw)
+	}
+	return out, nil
+}
+
+// CassetteRecorder passes requests to the inner doer and records each interaction in Dir.
+type CassetteRecorder struct {
+	Inner client.HttpRequestDoer
+	Dir   string
+
+	lock  sync.Mutex
+	count int
+}
+
+func NewCassetteRecorder(inner client.HttpRequestDoer, dir string) (*CassetteRecorder, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+	existing, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	return &CassetteRecorder{Inner: inner, Dir: dir, count: len(existing)}, nil
+}
+
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+func (c *CassetteRecorder) Do(req *http.Request) (*http.Response, error) {
+	cr, err := newCassetteRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Inner.Do(req)
+	if err != nil {
+		return resp, err
+	}
+	raw, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(raw))
+
+	interaction := CassetteInteraction{Request: cr, Response: CassetteResponse{Status: resp.StatusCode, Header: make(http.Header)}}
+	for _, h := range recordedHeaders {
+		if v := resp.Header.Values(h); len(v) > 0 {
+			interaction.Response.Header[h] = v
+		}
+	}
+	if normalized := normalizeBody(raw); normalized != "" && json.Valid([]byte(normalized)) {
+		interaction.Response.BodyJson = json.RawMessage(normalized)
+	} else {
+		interaction.Response.BodyText = normalized
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.count++
+	name := fmt.Sprintf("%04d-%s-%s.json", c.count, req.Method, strings.Trim(unsafeFileNameChars.ReplaceAllString(req.URL.Path, "-"), "-"))
+	content, _ := json.MarshalIndent(interaction, "", "  ")
+	if err := os.WriteFile(filepath.Join(c.Dir, name), content, 0600); err != nil {
+		return nil, fmt.Errorf("failed to record interaction: %w", err)
+	}
+	return resp, nil
+}
+
+// CassetteReplayer serves responses from the interactions recorded in a directory. Requests are matched by method,
+// path, query, and normalized body. When the same request was recorded multiple times the responses are returned in
+// order, repeating the last one.
+type CassetteReplayer struct {
+	lock         sync.Mutex
+	interactions map[string][]CassetteResponse
+	served       map[string]int
+}
+
+func LoadCassetteReplayer(dir string) (*CassetteReplayer, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no recorded interactions found in '%s'", dir)
+	}
+	slices.Sort(files)
+	out := &CassetteReplayer{interactions: make(map[string][]CassetteResponse), served: make(map[string]int)}
+	for _, f := range files {
+		raw, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		var i CassetteInteraction
+		if err := json.Unmarshal(raw, &i); err != nil {
+			return nil, fmt.Errorf("failed to read interaction '%s': %w", f, err)
+		}
+		// normalize hand-written query and body values so that they match the requests
+		if q, err := url.ParseQuery(i.Request.Query); err == nil && len(q) > 0 {
+			i.Request.Query = q.Encode()
+		}
+		i.Request.Body = normalizeBody([]byte(i.Request.Body))
+		if len(i.Response.BodyJson) > 0 {
+			compact := new(bytes.Buffer)
+			if err := json.Compact(compact, i.Response.BodyJson); err == nil {
+				i.Response.BodyJson = compact.Bytes()
+			}
+		}
+		k := i.Request.key()
+		out.interactions[k] = append(out.interactions[k], i.Response)
+	}
+	return out, nil
+}
+
+func (c *CassetteReplayer) Offline() bool {
+	return true
+}
+
+func (c *CassetteReplayer) Do(req *http.Request) (*http.Response, error) {
+	cr, err := newCassetteRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	k := cr.key()
+	c.lock.Lock()
+	responses := c.interactions[k]
+	n := c.served[k]
+	c.served[k] = n + 1
+	c.lock.Unlock()
+	if len(responses) == 0 {
+		return nil, fmt.Errorf("no recorded interaction matches %s %s", req.Method, req.URL.RequestURI())
+	}
+	r := responses[min(n, len(responses)-1)]
+	body := r.body()
+	header := r.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	if header.Get("Content-Type") == "" && len(r.BodyJson) > 0 {
+		header.Set("Content-Type", "application/json")
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.Status, http.StatusText(r.Status)),
+		StatusCode:    r.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,58 @@
+package humanitec
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/humanitec/humanitec-go-autogen/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"path":"` + r.URL.Path + `","body":` + string(raw) + `,"token":"leaked"}`))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	rec, err := NewCassetteRecorder(http.DefaultClient, dir)
+	require.NoError(t, err)
+
+	do := func(d client.HttpRequestDoer, body string) string {
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, srv.URL+"/orgs/a/thing?x=1", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret-token")
+		resp, err := d.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		raw, _ := io.ReadAll(resp.Body)
+		return string(raw)
+	}
+
+	assert.Contains(t, do(rec, `{"b":2,"a":1}`), `"token":"leaked"`)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.Len(t, files, 1)
+	assert.Equal(t, "0001-POST-orgs-a-thing.json", filepath.Base(files[0]))
+	raw, _ := os.ReadFile(files[0])
+	assert.NotContains(t, string(raw), "secret-token")
+	assert.NotContains(t, string(raw), "leaked")
+
+	rep, err := LoadCassetteReplayer(dir)
+	require.NoError(t, err)
+	assert.True(t, rep.Offline())
+	// key order and whitespace do not affect matching
+	assert.Equal(t, `{"body":{"a":1,"b":2},"path":"/orgs/a/thing","token":"REDACTED"}`, do(rep, `{ "a": 1, "b": 2 }`))
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/orgs/a/other", nil)
+	_, err = rep.Do(req)
+	assert.EqualError(t, err, "no recorded interaction matches GET /orgs/a/other")
+}
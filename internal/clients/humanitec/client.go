@@ -5,42 +5,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net"
 	"net/http"
-	"os"
 	"path/filepath"
 	"reflect"
 	"runtime/debug"
 	"slices"
+	"time"
 
 	"github.com/humanitec/humanitec-go-autogen/client"
-	"gopkg.in/yaml.v3"
 )
 
-func GetCurrentHumanitecToken() (string, error) {
-	if v := os.Getenv("HUMANITEC_TOKEN"); v != "" {
-		return v, nil
-	}
-	hd, err := os.UserHomeDir()
-	if err != nil {
-		return "", fmt.Errorf("failed to identify the users home directory: %w", err)
-	}
-	if content, err := os.ReadFile(filepath.Join(hd, ".humctl")); err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return "", nil
-		}
-		return "", fmt.Errorf("failed to read the humctl file: %w", err)
-	} else {
-		s := struct {
-			Token string `yaml:"token"`
-		}{}
-		if err := yaml.Unmarshal(content, &s); err != nil {
-			return "", fmt.Errorf("failed to unmarshal the humctl file: %w", err)
-		}
-		return s.Token, nil
-	}
-}
-
 type contextKey int
 
 const (
@@ -54,38 +28,90 @@ type WrappedHumanitecClient interface {
 type WrappedHumanitecClientImpl struct {
 	client.ClientWithResponsesInterface
 	apiPrefix     string
+	profile       *Profile
+	tokenInfo     TokenInfo
+	identity      string
+	offline       bool
 	httpClient    client.HttpRequestDoer
 	requestEditor client.RequestEditorFn
 }
 
 func NewHumanitecClientWithCurrentToken(ctx context.Context) (*WrappedHumanitecClientImpl, error) {
-	token, err := GetCurrentHumanitecToken()
+	profile, err := ActiveProfile()
 	if err != nil {
 		return nil, err
+	}
+	override, hasOverride := ctx.Value(overrideHumanitecClientKey).(client.HttpRequestDoer)
+	offline := false
+	if od, ok := override.(OfflineDoer); ok {
+		offline = od.Offline()
+	}
+	token, err := profile.ResolveToken()
+	if err != nil {
+		return nil, err
+	} else if token == "" && offline {
+		token = "offline"
 	} else if token == "" {
-		return nil, fmt.Errorf("The user is not currently logged in and should be prompted to run 'humctl login' to fix this.")
+		return nil, &Error{Kind: ErrorKindAuth, Message: "The user is not currently logged in and should be prompted to run 'humctl login' to fix this.", Hint: "Run 'humctl login'."}
 	}
-	apiPrefix := "https://api.humanitec.io"
-	if v := os.Getenv("HUMANITEC_API_PREFIX"); v != "" {
-		apiPrefix = v
+	tokenInfo := InspectToken(token)
+	if tokenInfo.IsExpired(time.Now()) && !offline {
+		return nil, &Error{Kind: ErrorKindAuth, Message: fmt.Sprintf("The users Humanitec session expired at %s and they should be prompted to run 'humctl login' to fix this.", tokenInfo.ExpiresAt.Format(time.RFC3339)), Hint: "Run 'humctl login'."}
 	}
+	warnIfExpiringSoon(token, tokenInfo)
+	apiPrefix := profile.ApiPrefix
 	bi, _ := debug.ReadBuildInfo()
 	wci := &WrappedHumanitecClientImpl{
-		apiPrefix:  apiPrefix,
-		httpClient: http.DefaultClient,
+		apiPrefix: apiPrefix,
+		profile:   profile,
+		tokenInfo: tokenInfo,
+		identity:  hashString(apiPrefix + " " + token),
+		offline:   offline,
 		requestEditor: func(ctx context.Context, req *http.Request) error {
 			req.Header.Set("Authorization", "Bearer "+token)
 			req.Header.Set("Humanitec-User-Agent", fmt.Sprintf("app %s/%s; sdk humanitec-go-autogen/latest", filepath.Base(bi.Main.Path), bi.Main.Version))
 			return nil
 		},
 	}
-	if v, ok := ctx.Value(overrideHumanitecClientKey).(client.HttpRequestDoer); ok {
-		wci.httpClient = v
+	if hasOverride {
+		wci.httpClient = override
+	} else if wci.httpClient, err = DefaultHttpClient(); err != nil {
+		return nil, err
+	}
+	// offline responses must not be rate limited or mixed into the cache of live responses
+	if !offline {
+		wci.httpClient = &rateLimitedDoer{inner: wci.httpClient, limiter: DefaultRateLimiter()}
+		wci.httpClient = &cachingDoer{inner: wci.httpClient, cache: DefaultResponseCache()}
+	}
+	if opts := currentTraceOptions(); opts.Enabled {
+		wci.httpClient = &tracingDoer{inner: wci.httpClient, bodies: opts.Bodies}
 	}
 	wci.ClientWithResponsesInterface, err = client.NewClientWithResponses(apiPrefix, client.WithHTTPClient(wci.httpClient), client.WithRequestEditorFn(wci.requestEditor))
 	return wci, err
 }
 
+// Profile returns the profile that the client was created with.
+func (w *WrappedHumanitecClientImpl) Profile() *Profile {
+	return w.profile
+}
+
+// TokenInfo returns what is known about the token that the client was created with.
+func (w *WrappedHumanitecClientImpl) TokenInfo() TokenInfo {
+	return w.tokenInfo
+}
+
+// Identity returns an opaque value that is the same for clients of the same API and token, used to keep data cached
+// for one session apart from the data of another.
+func (w *WrappedHumanitecClientImpl) Identity() string {
+	return w.identity
+}
+
+// Offline returns true when the client serves responses without contacting the Humanitec API, such as when replaying
+// a cassette or in demo mode.
+func (w *WrappedHumanitecClientImpl) Offline() bool {
+	return w.offline
+}
+
 type checkableResponse interface {
 	StatusCode() int
 }
@@ -97,11 +123,25 @@ type CheckedResponse[k checkableResponse] struct {
 
 func (ac *CheckedResponse[k]) AndStatusCodeEq(code int, codes ...int) *CheckedResponse[k] {
 	var r checkableResponse = ac.Response
-	if r != nil && code != r.StatusCode() && !slices.Contains(codes, r.StatusCode()) {
-		if r.StatusCode() == http.StatusForbidden {
-			ac.Err = errors.Join(ac.Err, fmt.Errorf("The user is not currently logged in and should be prompted to run 'humctl login' to fix this."))
+	if v := reflect.ValueOf(r); !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		return ac
+	}
+	if code != r.StatusCode() && !slices.Contains(codes, r.StatusCode()) {
+		var e *Error
+		if r.StatusCode() == http.StatusUnauthorized {
+			if info := ac.requestTokenInfo(); info.IsExpired(time.Now()) {
+				e = newStatusError(r.StatusCode(), fmt.Sprintf("The users Humanitec session expired at %s and they should be prompted to run 'humctl login' to fix this.", info.ExpiresAt.Format(time.RFC3339)))
+			} else {
+				e = newStatusError(r.StatusCode(), "The API request returned a 401 (Unauthorized) error because the token was not accepted. The user is not currently logged in and should be prompted to run 'humctl login' to fix this.")
+			}
+		} else if r.StatusCode() == http.StatusForbidden {
+			if info := ac.requestTokenInfo(); info.IsExpired(time.Now()) {
+				e = newStatusError(r.StatusCode(), fmt.Sprintf("The users Humanitec session expired at %s and they should be prompted to run 'humctl login' to fix this.", info.ExpiresAt.Format(time.RFC3339)))
+			} else {
+				e = newStatusError(r.StatusCode(), "The API request returned a 403 (Forbidden) error. The user does not have permission to access this resource, they may need a different role or to switch to a different profile or Organization. If the session has ended, they should run 'humctl login'.")
+			}
 		} else if r.StatusCode() == http.StatusNotFound {
-			ac.Err = errors.Join(ac.Err, fmt.Errorf("The API request returned a 404 (Not Found) error which may indicate that the resource does not exist. The user may have misspelt something or the state may have changed."))
+			e = newStatusError(r.StatusCode(), "The API request returned a 404 (Not Found) error which may indicate that the resource does not exist. The user may have misspelt something or the state may have changed.")
 		} else {
 			body := make([]byte, 0)
 			v := reflect.ValueOf(ac.Response)
@@ -120,13 +160,26 @@ func (ac *CheckedResponse[k]) AndStatusCodeEq(code int, codes ...int) *CheckedRe
 			if anon.Message != "" {
 				bodyText = anon.Message
 			}
-			ac.Err = errors.Join(ac.Err, fmt.Errorf(
-				"The API request to Humanitec returned an unexpected status code %d (%s). The content of the error response is '%s' and may provide a hint as to what went wrong.", ac.Response.StatusCode(), http.StatusText(ac.Response.StatusCode()), bodyText))
+			e = newStatusError(r.StatusCode(), fmt.Sprintf(
+				"The API request to Humanitec returned an unexpected status code %d (%s). The content of the error response is '%s' and may provide a hint as to what went wrong.", r.StatusCode(), http.StatusText(r.StatusCode()), bodyText))
+		}
+		if ac.Err == nil {
+			ac.Err = e
+		} else {
+			ac.Err = errors.Join(ac.Err, e)
 		}
 	}
 	return ac
 }
 
+// requestTokenInfo inspects the token that was sent with the request that produced the response, if available.
+func (ac *CheckedResponse[k]) requestTokenInfo() TokenInfo {
+	if hr := httpResponseOf(ac.Response); hr != nil && hr.Request != nil {
+		return InspectToken(hr.Request.Header.Get("Authorization"))
+	}
+	return TokenInfo{Type: TokenTypeNone}
+}
+
 func (ac *CheckedResponse[k]) RespAndError() (k, error) {
 	return ac.Response, ac.Err
 }
@@ -134,11 +187,7 @@ func (ac *CheckedResponse[k]) RespAndError() (k, error) {
 func CheckResponse[k checkableResponse](requester func() (k, error)) *CheckedResponse[k] {
 	resp, err := requester()
 	if err != nil {
-		if ne := (net.Error)(nil); errors.As(err, &ne) {
-			err = fmt.Errorf("The API request to Humanitec hit a temporary network error '%s'. The request may work if the user requests it again.", ne.Error())
-		} else {
-			err = fmt.Errorf("The API request to Humanitec hit an unexpected error '%s'.", ne.Error())
-		}
+		return &CheckedResponse[k]{Response: resp, Err: newRequestError(err)}
 	}
-	return &CheckedResponse[k]{Response: resp, Err: err}
+	return &CheckedResponse[k]{Response: resp}
 }
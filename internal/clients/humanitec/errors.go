@@ -0,0 +1,115 @@
+package humanitec
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// ErrorKind classifies errors returned from the Humanitec API so that callers can react without parsing messages.
+type ErrorKind string
+
+const (
+	ErrorKindAuth        ErrorKind = "auth"
+	ErrorKindNotFound    ErrorKind = "not-found"
+	ErrorKindValidation  ErrorKind = "validation"
+	ErrorKindRateLimited ErrorKind = "rate-limited"
+	ErrorKindUpstream5xx ErrorKind = "upstream-5xx"
+	ErrorKindNetwork     ErrorKind = "network"
+	ErrorKindCancelled   ErrorKind = "cancelled"
+	ErrorKindUnexpected  ErrorKind = "unexpected"
+)
+
+// Error is a classified error from a Humanitec API request. The message is written for the LLM while the remaining
+// fields are exposed as machine-readable data in tool results and JSON-RPC errors.
+type Error struct {
+	Kind       ErrorKind
+	Message    string
+	StatusCode int
+	Retryable  bool
+	Hint       string
+	Err        error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrorData returns the structured representation of the error.
+func (e *Error) ErrorData() map[string]interface{} {
+	out := map[string]interface{}{
+		"kind":      string(e.Kind),
+		"retryable": e.Retryable,
+	}
+	if e.StatusCode != 0 {
+		out["status_code"] = e.StatusCode
+	}
+	if e.Hint != "" {
+		out["hint"] = e.Hint
+	}
+	return out
+}
+
+// ErrorKindOf returns the kind of the first classified error in the chain, or an empty kind.
+func ErrorKindOf(err error) ErrorKind {
+	if e := (*Error)(nil); errors.As(err, &e) {
+		return e.Kind
+	}
+	return ""
+}
+
+// newStatusError classifies an unexpected status code.
+func newStatusError(code int, message string) *Error {
+	out := &Error{Kind: ErrorKindUnexpected, Message: message, StatusCode: code}
+	switch {
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		out.Kind = ErrorKindAuth
+		out.Hint = "Run 'humctl login' or switch to a profile with access to this Organization."
+	case code == http.StatusNotFound:
+		out.Kind = ErrorKindNotFound
+		out.Hint = "Check the spelling of the ids and list the available entities."
+	case code == http.StatusBadRequest || code == http.StatusConflict || code == http.StatusUnprocessableEntity:
+		out.Kind = ErrorKindValidation
+		out.Hint = "Correct the request arguments based on the error message."
+	case code == http.StatusTooManyRequests:
+		out.Kind = ErrorKindRateLimited
+		out.Retryable = true
+		out.Hint = "Wait a few seconds before retrying the request."
+	case code >= 500:
+		out.Kind = ErrorKindUpstream5xx
+		out.Retryable = true
+		out.Hint = "The Humanitec API had a temporary problem, retry the request shortly."
+	}
+	return out
+}
+
+// newRequestError classifies an error returned before any response was received.
+func newRequestError(err error) *Error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return &Error{
+			Kind:      ErrorKindCancelled,
+			Message:   "The API request to Humanitec was cancelled before it completed '" + err.Error() + "'.",
+			Retryable: errors.Is(err, context.DeadlineExceeded),
+			Err:       err,
+		}
+	}
+	if ne := (net.Error)(nil); errors.As(err, &ne) {
+		return &Error{
+			Kind:      ErrorKindNetwork,
+			Message:   "The API request to Humanitec hit a temporary network error '" + ne.Error() + "'. The request may work if the user requests it again.",
+			Retryable: true,
+			Hint:      "Check the network connection and proxy settings, then retry.",
+			Err:       err,
+		}
+	}
+	return &Error{
+		Kind:    ErrorKindUnexpected,
+		Message: "The API request to Humanitec hit an unexpected error '" + err.Error() + "'.",
+		Err:     err,
+	}
+}
@@ -0,0 +1,49 @@
+package humanitec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckResponse_errorKinds(t *testing.T) {
+	for code, kind := range map[int]ErrorKind{
+		http.StatusUnauthorized:        ErrorKindAuth,
+		http.StatusNotFound:            ErrorKindNotFound,
+		http.StatusUnprocessableEntity: ErrorKindValidation,
+		http.StatusTooManyRequests:     ErrorKindRateLimited,
+		http.StatusBadGateway:          ErrorKindUpstream5xx,
+		http.StatusTeapot:              ErrorKindUnexpected,
+	} {
+		t.Run(fmt.Sprint(code), func(t *testing.T) {
+			_, err := CheckResponse(func() (*fakeResponse, error) {
+				return &fakeResponse{HTTPResponse: &http.Response{StatusCode: code}, Body: []byte(`{"message":"nope"}`)}, nil
+			}).AndStatusCodeEq(http.StatusOK).RespAndError()
+			assert.Equal(t, kind, ErrorKindOf(err))
+		})
+	}
+
+	_, err := CheckResponse(func() (*fakeResponse, error) {
+		return nil, &net.OpError{Op: "dial", Err: errors.New("refused")}
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	assert.Equal(t, ErrorKindNetwork, ErrorKindOf(err))
+	var e *Error
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, map[string]interface{}{"kind": "network", "retryable": true, "hint": "Check the network connection and proxy settings, then retry."}, e.ErrorData())
+
+	_, err = CheckResponse(func() (*fakeResponse, error) {
+		return nil, fmt.Errorf("wrapped: %w", context.Canceled)
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	assert.Equal(t, ErrorKindCancelled, ErrorKindOf(err))
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = CheckResponse(func() (*fakeResponse, error) {
+		return nil, errors.New("other")
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	assert.EqualError(t, err, "The API request to Humanitec hit an unexpected error 'other'.")
+}
@@ -0,0 +1,133 @@
+package fakeapi
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// delta is a Deployment Delta as accepted by the sets endpoint and stored in the fixture.
+type delta struct {
+	Modules struct {
+		Add    map[string]interface{}    `json:"add" yaml:"add"`
+		Remove []string                  `json:"remove" yaml:"remove"`
+		Update map[string][]updateAction `json:"update" yaml:"update"`
+	} `json:"modules" yaml:"modules"`
+	Shared []updateAction `json:"shared" yaml:"shared"`
+}
+
+// updateAction is the subset of a json patch operation supported by the orchestrator: add, remove, and replace.
+type updateAction struct {
+	Op    string      `json:"op" yaml:"op"`
+	Path  string      `json:"path" yaml:"path"`
+	Value interface{} `json:"value,omitempty" yaml:"value,omitempty"`
+}
+
+// deepCopy returns a copy of decoded json content that can be modified without affecting the original.
+func deepCopy(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for k, item := range x {
+			out[k] = deepCopy(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, item := range x {
+			out[i] = deepCopy(item)
+		}
+		return out
+	}
+	return v
+}
+
+// applyActions applies the update actions to a map, creating intermediate maps for add and replace.
+func applyActions(target map[string]interface{}, actions []updateAction) error {
+	for _, a := range actions {
+		parts := strings.Split(strings.TrimPrefix(a.Path, "/"), "/")
+		for i, p := range parts {
+			parts[i] = strings.ReplaceAll(strings.ReplaceAll(p, "~1", "/"), "~0", "~")
+		}
+		current := target
+		for _, p := range parts[:len(parts)-1] {
+			next, ok := current[p].(map[string]interface{})
+			if !ok {
+				if a.Op == "remove" {
+					return fmt.Errorf("path '%s' does not exist", a.Path)
+				}
+				next = make(map[string]interface{})
+				current[p] = next
+			}
+			current = next
+		}
+		last := parts[len(parts)-1]
+		switch a.Op {
+		case "add", "replace":
+			current[last] = deepCopy(a.Value)
+		case "remove":
+			if _, ok := current[last]; !ok {
+				return fmt.Errorf("path '%s' does not exist", a.Path)
+			}
+			delete(current, last)
+		default:
+			return fmt.Errorf("unsupported operation '%s'", a.Op)
+		}
+	}
+	return nil
+}
+
+// applyDelta returns the content of the set that results from applying the delta to the set content.
+func applyDelta(set map[string]interface{}, d delta) (map[string]interface{}, error) {
+	out, _ := deepCopy(set).(map[string]interface{})
+	if out == nil {
+		out = make(map[string]interface{})
+	}
+	modules, _ := out["modules"].(map[string]interface{})
+	if modules == nil {
+		modules = make(map[string]interface{})
+	}
+	for id, m := range d.Modules.Add {
+		modules[id] = deepCopy(m)
+	}
+	for _, id := range d.Modules.Remove {
+		delete(modules, id)
+	}
+	for id, actions := range d.Modules.Update {
+		m, ok := modules[id].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("module '%s' does not exist", id)
+		}
+		if err := applyActions(m, actions); err != nil {
+			return nil, fmt.Errorf("module '%s': %w", id, err)
+		}
+	}
+	out["modules"] = modules
+	if len(d.Shared) > 0 {
+		shared, _ := out["shared"].(map[string]interface{})
+		if shared == nil {
+			shared = make(map[string]interface{})
+		}
+		if err := applyActions(shared, d.Shared); err != nil {
+			return nil, fmt.Errorf("shared: %w", err)
+		}
+		out["shared"] = shared
+	}
+	return out, nil
+}
+
+// setId derives the content addressed id of a set the way the orchestrator does: equal content has an equal id.
+func setId(content map[string]interface{}) string {
+	raw, _ := json.Marshal(content)
+	h := sha1.Sum(raw)
+	return hex.EncodeToString(h[:])
+}
+
+// deployId derives a deployment id from its environment and sequence number.
+func deployId(appId, envId string, n int) string {
+	h := sha1.Sum([]byte(appId + "/" + envId + "/" + strconv.Itoa(n)))
+	return hex.EncodeToString(h[:8])
+}
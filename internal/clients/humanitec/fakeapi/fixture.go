@@ -0,0 +1,366 @@
+package fakeapi
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fixture describes the Humanitec Organization served by the fake API.
+type Fixture struct {
+	// Org is the id of the Organization. Requests for any other Organization are rejected as not found.
+	Org string `yaml:"org"`
+	// Token is the bearer token that requests must present. When empty, any bearer token is accepted.
+	Token            string            `yaml:"token,omitempty"`
+	User             User              `yaml:"user"`
+	Apps             []App             `yaml:"apps"`
+	WorkloadProfiles []WorkloadProfile `yaml:"workload_profiles"`
+	ActionPipelines  []ActionPipeline  `yaml:"action_pipelines"`
+	// ResourceDefinitions are served as-is apart from the org_id. Each requires an "id" and a "type".
+	ResourceDefinitions []map[string]interface{} `yaml:"resource_definitions"`
+	Docs                []DocsAnswer             `yaml:"docs"`
+	// DeployDuration is how long deployments created through the API stay in progress.
+	DeployDuration time.Duration `yaml:"deploy_duration,omitempty"`
+	// PipelineStepDuration is how long each step of the pipeline runs created through the API takes.
+	PipelineStepDuration time.Duration `yaml:"pipeline_step_duration,omitempty"`
+}
+
+type User struct {
+	Id    string `yaml:"id"`
+	Name  string `yaml:"name"`
+	Email string `yaml:"email"`
+	// Role is the role of the user in the Organization, defaults to administrator.
+	Role string `yaml:"role"`
+}
+
+type App struct {
+	Id        string    `yaml:"id"`
+	Name      string    `yaml:"name"`
+	CreatedAt time.Time `yaml:"created_at"`
+	Envs      []Env     `yaml:"envs"`
+	// Sets are the Deployment Sets of the Application keyed by set id. Each holds the "modules" and "shared" content.
+	Sets map[string]map[string]interface{} `yaml:"sets"`
+	// Deltas are the Deployment Deltas of the Application keyed by delta id.
+	Deltas map[string]delta `yaml:"deltas,omitempty"`
+	// Pipelines are the Pipelines of the Application.
+	Pipelines []Pipeline `yaml:"pipelines,omitempty"`
+}
+
+type Env struct {
+	Id         string      `yaml:"id"`
+	Name       string      `yaml:"name"`
+	Type       string      `yaml:"type"`
+	CreatedAt  time.Time   `yaml:"created_at"`
+	LastDeploy *Deployment `yaml:"last_deploy,omitempty"`
+	// Deploys is the deployment history, newest first. It defaults to the last deployment and the last deployment
+	// defaults to the first entry.
+	Deploys []Deployment `yaml:"deploys,omitempty"`
+	// Resources are the active resources provisioned in the Environment.
+	Resources []ActiveResource `yaml:"resources,omitempty"`
+}
+
+type Deployment struct {
+	Id        string    `yaml:"id"`
+	SetId     string    `yaml:"set_id"`
+	DeltaId   string    `yaml:"delta_id"`
+	Status    string    `yaml:"status"`
+	Comment   string    `yaml:"comment"`
+	CreatedAt time.Time `yaml:"created_at"`
+	CreatedBy string    `yaml:"created_by"`
+	// Duration is the time between the deployment being created and its status changing.
+	Duration time.Duration     `yaml:"duration"`
+	Errors   []DeploymentError `yaml:"errors"`
+}
+
+type DeploymentError struct {
+	Code      string `yaml:"code" json:"code"`
+	ErrorType string `yaml:"error_type" json:"error_type"`
+	Message   string `yaml:"message" json:"message"`
+	ObjectId  string `yaml:"object_id" json:"object_id"`
+	Scope     string `yaml:"scope" json:"scope"`
+	Summary   string `yaml:"summary" json:"summary"`
+}
+
+type ActiveResource struct {
+	ResId string `yaml:"res_id"`
+	Type  string `yaml:"type"`
+	// Class defaults to default.
+	Class      string `yaml:"class"`
+	DefId      string `yaml:"def_id"`
+	DriverType string `yaml:"driver_type"`
+	// Status is one of pending, active, or deleting and defaults to active.
+	Status   string                 `yaml:"status"`
+	Resource map[string]interface{} `yaml:"resource"`
+	// DependsOn are the res ids of the resources in the same Environment that this resource depends on.
+	DependsOn []string `yaml:"depends_on"`
+}
+
+// Pipeline is a Pipeline of an Application. The jobs and steps of its runs are read from the definition. Steps using
+// actions/humanitec/approve wait until their approval request is approved or denied, all other steps just succeed.
+type Pipeline struct {
+	Id        string    `yaml:"id"`
+	Name      string    `yaml:"name"`
+	Version   string    `yaml:"version"`
+	CreatedAt time.Time `yaml:"created_at"`
+	// Definition is the yaml definition of the Pipeline.
+	Definition string `yaml:"definition"`
+	// Runs is the run history, newest first.
+	Runs []PipelineRun `yaml:"runs,omitempty"`
+
+	definition pipelineDefinition
+}
+
+type PipelineRun struct {
+	Id        string                 `yaml:"id"`
+	Inputs    map[string]interface{} `yaml:"inputs"`
+	CreatedAt time.Time              `yaml:"created_at"`
+	CreatedBy string                 `yaml:"created_by"`
+	// StepDuration is how long each step of the run takes.
+	StepDuration time.Duration `yaml:"step_duration,omitempty"`
+	// FailStep is the name of the step that fails in this run with FailMessage.
+	FailStep    string `yaml:"fail_step,omitempty"`
+	FailMessage string `yaml:"fail_message,omitempty"`
+	// Approvals are the decisions on the approval steps of the run keyed by step name.
+	Approvals map[string]PipelineApproval `yaml:"approvals,omitempty"`
+}
+
+type PipelineApproval struct {
+	// Status is either approved or denied.
+	Status string    `yaml:"status"`
+	At     time.Time `yaml:"at"`
+	By     string    `yaml:"by"`
+}
+
+type WorkloadProfile struct {
+	Id          string      `yaml:"id"`
+	Description string      `yaml:"description"`
+	Version     string      `yaml:"version"`
+	SpecSchema  interface{} `yaml:"spec_schema"`
+}
+
+type ActionPipeline struct {
+	Id               string                 `yaml:"id"`
+	Description      string                 `yaml:"description"`
+	Type             string                 `yaml:"type"`
+	InputsJsonSchema map[string]interface{} `yaml:"inputs_jsonschema"`
+	// Outputs are returned from every call of the pipeline.
+	Outputs map[string]interface{} `yaml:"outputs"`
+}
+
+// DocsAnswer is returned from the documentation query when the query matches the case-insensitive Match regex.
+type DocsAnswer struct {
+	Match  string `yaml:"match"`
+	Answer string `yaml:"answer"`
+}
+
+//go:embed default.yaml
+var defaultFixture []byte
+
+// DefaultFixture returns the bundled fixture describing a small demo Organization.
+func DefaultFixture() *Fixture {
+	f, err := ParseFixture(defaultFixture)
+	if err != nil {
+		panic(fmt.Errorf("invalid bundled fixture: %w", err))
+	}
+	return f
+}
+
+// LoadFixture reads and validates a fixture file.
+func LoadFixture(path string) (*Fixture, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+	f, err := ParseFixture(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fixture '%s': %w", path, err)
+	}
+	return f, nil
+}
+
+// ParseFixture decodes and validates fixture yaml.
+func ParseFixture(raw []byte) (*Fixture, error) {
+	var f Fixture
+	if err := yaml.Unmarshal(raw, &f); err != nil {
+		return nil, err
+	}
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (f *Fixture) validate() error {
+	if f.Org == "" {
+		return fmt.Errorf("org is required")
+	}
+	if f.User.Id == "" {
+		f.User.Id = "fake-user"
+	}
+	if f.User.Role == "" {
+		f.User.Role = "administrator"
+	}
+	seenApps := make(map[string]bool)
+	for _, app := range f.Apps {
+		if app.Id == "" {
+			return fmt.Errorf("app id is required")
+		} else if seenApps[app.Id] {
+			return fmt.Errorf("duplicate app '%s'", app.Id)
+		}
+		seenApps[app.Id] = true
+		seenEnvs := make(map[string]bool)
+		for i, env := range app.Envs {
+			if env.Id == "" {
+				return fmt.Errorf("env id is required in app '%s'", app.Id)
+			} else if seenEnvs[env.Id] {
+				return fmt.Errorf("duplicate env '%s' in app '%s'", env.Id, app.Id)
+			}
+			seenEnvs[env.Id] = true
+			if env.LastDeploy == nil && len(env.Deploys) > 0 {
+				app.Envs[i].LastDeploy = &env.Deploys[0]
+			} else if env.LastDeploy != nil && len(env.Deploys) == 0 {
+				app.Envs[i].Deploys = []Deployment{*env.LastDeploy}
+			}
+			for _, d := range app.Envs[i].Deploys {
+				if _, ok := app.Sets[d.SetId]; !ok {
+					return fmt.Errorf("env '%s' in app '%s' references unknown set '%s'", env.Id, app.Id, d.SetId)
+				}
+			}
+			for j, res := range env.Resources {
+				if res.ResId == "" || res.Type == "" {
+					return fmt.Errorf("resources in env '%s' in app '%s' require a res_id and a type", env.Id, app.Id)
+				}
+				if res.Class == "" {
+					env.Resources[j].Class = "default"
+				}
+				if res.Status == "" {
+					env.Resources[j].Status = "active"
+				}
+				for _, dep := range res.DependsOn {
+					if env.resource(dep) == nil {
+						return fmt.Errorf("resource '%s' in env '%s' in app '%s' depends on unknown resource '%s'", res.ResId, env.Id, app.Id, dep)
+					}
+				}
+			}
+		}
+	}
+	for i := range f.Apps {
+		for j := range f.Apps[i].Pipelines {
+			p := &f.Apps[i].Pipelines[j]
+			if p.Id == "" {
+				return fmt.Errorf("pipeline id is required in app '%s'", f.Apps[i].Id)
+			}
+			if err := yaml.Unmarshal([]byte(p.Definition), &p.definition); err != nil {
+				return fmt.Errorf("invalid definition of pipeline '%s' in app '%s': %w", p.Id, f.Apps[i].Id, err)
+			} else if len(p.definition.Jobs) == 0 {
+				return fmt.Errorf("the definition of pipeline '%s' in app '%s' has no jobs", p.Id, f.Apps[i].Id)
+			}
+			if p.Version == "" {
+				p.Version = "1"
+			}
+			for _, run := range p.Runs {
+				for name, a := range run.Approvals {
+					if a.Status != "approved" && a.Status != "denied" {
+						return fmt.Errorf("approval of step '%s' in run '%s' of pipeline '%s' must be approved or denied", name, run.Id, p.Id)
+					}
+				}
+			}
+		}
+	}
+	for _, def := range f.ResourceDefinitions {
+		if def["id"] == nil || def["type"] == nil {
+			return fmt.Errorf("resource definitions require an id and a type")
+		}
+	}
+	for _, d := range f.Docs {
+		if _, err := regexp.Compile("(?i)" + d.Match); err != nil {
+			return fmt.Errorf("invalid docs match '%s': %w", d.Match, err)
+		}
+	}
+	return nil
+}
+
+func (f *Fixture) app(id string) *App {
+	for i := range f.Apps {
+		if f.Apps[i].Id == id {
+			return &f.Apps[i]
+		}
+	}
+	return nil
+}
+
+func (a *App) env(id string) *Env {
+	for i := range a.Envs {
+		if a.Envs[i].Id == id {
+			return &a.Envs[i]
+		}
+	}
+	return nil
+}
+
+func (e *Env) deploy(id string) *Deployment {
+	for i := range e.Deploys {
+		if e.Deploys[i].Id == id {
+			return &e.Deploys[i]
+		}
+	}
+	return nil
+}
+
+func (e *Env) resource(resId string) *ActiveResource {
+	for i := range e.Resources {
+		if e.Resources[i].ResId == resId {
+			return &e.Resources[i]
+		}
+	}
+	return nil
+}
+
+func (a *App) pipeline(id string) *Pipeline {
+	for i := range a.Pipelines {
+		if a.Pipelines[i].Id == id {
+			return &a.Pipelines[i]
+		}
+	}
+	return nil
+}
+
+func (p *Pipeline) run(id string) *PipelineRun {
+	for i := range p.Runs {
+		if p.Runs[i].Id == id {
+			return &p.Runs[i]
+		}
+	}
+	return nil
+}
+
+func (f *Fixture) resourceDefinition(id string) map[string]interface{} {
+	for _, def := range f.ResourceDefinitions {
+		if def["id"] == id {
+			return def
+		}
+	}
+	return nil
+}
+
+func (f *Fixture) workloadProfile(id string) *WorkloadProfile {
+	for i := range f.WorkloadProfiles {
+		if f.WorkloadProfiles[i].Id == id {
+			return &f.WorkloadProfiles[i]
+		}
+	}
+	return nil
+}
+
+func (f *Fixture) actionPipeline(id string) *ActionPipeline {
+	for i := range f.ActionPipelines {
+		if f.ActionPipelines[i].Id == id {
+			return &f.ActionPipelines[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package fakeapi
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/humanitec/humanitec-go-autogen/client"
+)
+
+// approveAction is the step action that waits for an approval request to be approved or denied.
+const approveAction = "actions/humanitec/approve"
+
+// pipelineDefinition is the subset of a Pipeline definition used to simulate its runs.
+type pipelineDefinition struct {
+	On map[string]struct {
+		Inputs map[string]pipelineInput `yaml:"inputs"`
+	} `yaml:"on"`
+	Jobs map[string]struct {
+		Steps []pipelineStepDefinition `yaml:"steps"`
+	} `yaml:"jobs"`
+}
+
+type pipelineInput struct {
+	Type        string      `yaml:"type"`
+	Description string      `yaml:"description"`
+	Required    bool        `yaml:"required"`
+	Default     interface{} `yaml:"default"`
+}
+
+type pipelineStepDefinition struct {
+	Name string                 `yaml:"name"`
+	Uses string                 `yaml:"uses"`
+	With map[string]interface{} `yaml:"with"`
+}
+
+// triggerTypes returns the trigger types of the definition in name order.
+func (d pipelineDefinition) triggerTypes() []string {
+	return slices.Sorted(maps.Keys(d.On))
+}
+
+// jobIds returns the job ids of the definition in the order they run, which is name order in the fake.
+func (d pipelineDefinition) jobIds() []string {
+	return slices.Sorted(maps.Keys(d.Jobs))
+}
+
+// envIds returns the Environments the steps of the definition act on, taken from their env_id inputs.
+func (d pipelineDefinition) envIds() []string {
+	out := make([]string, 0)
+	for _, jobId := range d.jobIds() {
+		for _, step := range d.Jobs[jobId].Steps {
+			if envId, _ := step.With["env_id"].(string); envId != "" && !slices.Contains(out, envId) {
+				out = append(out, envId)
+			}
+		}
+	}
+	return out
+}
+
+// runInputs checks the inputs of a pipeline_call run against the definition and returns them with defaults applied.
+func (d pipelineDefinition) runInputs(inputs map[string]interface{}) (map[string]interface{}, error) {
+	trigger, ok := d.On["pipeline_call"]
+	if !ok {
+		return nil, fmt.Errorf("the pipeline cannot be triggered by a pipeline call")
+	}
+	out := make(map[string]interface{}, len(trigger.Inputs))
+	for _, name := range slices.Sorted(maps.Keys(inputs)) {
+		if _, ok := trigger.Inputs[name]; !ok {
+			return nil, fmt.Errorf("unknown input '%s'", name)
+		}
+		out[name] = inputs[name]
+	}
+	for _, name := range slices.Sorted(maps.Keys(trigger.Inputs)) {
+		in := trigger.Inputs[name]
+		if _, ok := out[name]; ok {
+			continue
+		} else if in.Default != nil {
+			out[name] = in.Default
+		} else if in.Required {
+			return nil, fmt.Errorf("missing required input '%s'", name)
+		}
+	}
+	return out, nil
+}
+
+// runId derives a run id from its pipeline and sequence number.
+func runId(appId, pipelineId string, n int) string {
+	h := sha1.Sum([]byte(appId + "/" + pipelineId + "/runs/" + strconv.Itoa(n)))
+	return hex.EncodeToString(h[:8])
+}
+
+// approvalId derives the id of the approval request of a step.
+func approvalId(runId, jobId string, stepIndex int) string {
+	h := sha1.Sum([]byte(runId + "/" + jobId + "/" + strconv.Itoa(stepIndex)))
+	return hex.EncodeToString(h[:8])
+}
+
+// runState is the state of a run at a point in time.
+type runState struct {
+	Status        string
+	StatusMessage string
+	CompletedAt   *time.Time
+	WaitingFor    map[string]string
+	Jobs          []jobState
+}
+
+type jobState struct {
+	Id            string
+	Status        string
+	StatusMessage string
+	CreatedAt     time.Time
+	CompletedAt   *time.Time
+	Steps         []stepState
+}
+
+type stepState struct {
+	client.PipelineStep
+	Logs     []client.PipelineStepLog
+	Approval *client.PipelineApprovalRequest
+}
+
+func (j *jobState) step(index int) *stepState {
+	if index < 0 || index >= len(j.Steps) {
+		return nil
+	}
+	return &j.Steps[index]
+}
+
+func (r *runState) job(id string) *jobState {
+	for i := range r.Jobs {
+		if r.Jobs[i].Id == id {
+			return &r.Jobs[i]
+		}
+	}
+	return nil
+}
+
+// approval returns the approval request with the given id and the step it belongs to.
+func (r *runState) approval(id string) (*stepState, *client.PipelineApprovalRequest) {
+	for i := range r.Jobs {
+		for j := range r.Jobs[i].Steps {
+			if a := r.Jobs[i].Steps[j].Approval; a != nil && a.Id == id {
+				return &r.Jobs[i].Steps[j], a
+			}
+		}
+	}
+	return nil, nil
+}
+
+// simulate replays the run up to now. The jobs run one after the other and each step starts when the previous one
+// completed. A step takes the step duration of the run, approval steps also wait for their decision, and the run stops
+// at the first failed step.
+func (s *Server) simulate(app *App, p *Pipeline, run *PipelineRun, now time.Time) runState {
+	out := runState{Status: "executing", WaitingFor: map[string]string{}, Jobs: make([]jobState, 0)}
+	t := run.CreatedAt
+	log := func(st *stepState, at time.Time, level, format string, args ...interface{}) {
+		st.Logs = append(st.Logs, client.PipelineStepLog{At: at, Level: level, Message: fmt.Sprintf(format, args...)})
+	}
+	for _, jobId := range p.definition.jobIds() {
+		job := jobState{Id: jobId, Status: "executing", CreatedAt: t, Steps: make([]stepState, 0)}
+		for i, def := range p.definition.Jobs[jobId].Steps {
+			if t.After(now) {
+				out.Jobs = append(out.Jobs, job)
+				return out
+			}
+			st := stepState{PipelineStep: client.PipelineStep{
+				Index: i, Name: def.Name, Uses: def.Uses, Status: "executing", CreatedAt: t, RelatedEntities: map[string]string{},
+			}}
+			log(&st, t, "INFO", "Starting step '%s' using %s", def.Name, def.Uses)
+			end := t.Add(run.StepDuration)
+			failure := ""
+			if def.Uses == approveAction {
+				message, _ := def.With["message"].(string)
+				envId, _ := def.With["env_id"].(string)
+				st.Approval = &client.PipelineApprovalRequest{
+					Id: approvalId(run.Id, jobId, i), OrgId: s.fixture.Org, AppId: app.Id, EnvId: envId, PipelineId: p.Id,
+					RunId: run.Id, JobId: jobId, Message: message, Status: client.Waiting, CreatedAt: t,
+				}
+				log(&st, t, "INFO", "Waiting for approval: %s", message)
+				decision, ok := run.Approvals[def.Name]
+				if !ok || decision.At.After(now) {
+					st.StatusMessage = "Waiting for approval"
+					job.Status, job.StatusMessage = "waiting", "Waiting for approval"
+					out.WaitingFor["approval"] = st.Approval.Id
+					job.Steps = append(job.Steps, st)
+					out.Jobs = append(out.Jobs, job)
+					return out
+				}
+				st.Approval.Status = client.PipelineApprovalRequestStatus(decision.Status)
+				st.Approval.ApprovedAt, st.Approval.ApprovedBy = &decision.At, &decision.By
+				log(&st, decision.At, "INFO", "The approval request was %s by %s", decision.Status, decision.By)
+				if decision.At.After(end) {
+					end = decision.At
+				}
+				if decision.Status == "denied" {
+					failure = fmt.Sprintf("The approval request was denied by %s", decision.By)
+				}
+			} else if def.Name == run.FailStep {
+				failure = run.FailMessage
+				if failure == "" {
+					failure = "The step failed"
+				}
+			}
+			if end.After(now) {
+				job.Steps = append(job.Steps, st)
+				out.Jobs = append(out.Jobs, job)
+				return out
+			}
+			st.CompletedAt = &end
+			if failure != "" {
+				st.Status, st.StatusMessage = "failed", failure
+				log(&st, end, "ERROR", "%s", failure)
+				job.Steps = append(job.Steps, st)
+				job.Status, job.StatusMessage, job.CompletedAt = "failed", fmt.Sprintf("Step '%s' failed", def.Name), &end
+				out.Jobs = append(out.Jobs, job)
+				out.Status, out.StatusMessage, out.CompletedAt = "failed", fmt.Sprintf("Job '%s' failed", jobId), &end
+				return out
+			}
+			st.Status = "succeeded"
+			log(&st, end, "INFO", "Step '%s' completed", def.Name)
+			job.Steps = append(job.Steps, st)
+			t = end
+		}
+		completed := t
+		job.Status, job.CompletedAt = "succeeded", &completed
+		out.Jobs = append(out.Jobs, job)
+	}
+	out.Status, out.CompletedAt = "succeeded", &t
+	return out
+}
+
+func (s *Server) pipelineResponse(app *App, p *Pipeline) client.Pipeline {
+	return client.Pipeline{
+		Id: p.Id, OrgId: s.fixture.Org, AppId: app.Id, Name: p.Name, Status: "active", Version: p.Version, Etag: p.Version,
+		CreatedAt: p.CreatedAt, TriggerTypes: p.definition.triggerTypes(),
+	}
+}
+
+func (s *Server) runResponse(app *App, p *Pipeline, run *PipelineRun, state runState) client.PipelineRun {
+	out := client.PipelineRun{
+		Id: run.Id, OrgId: s.fixture.Org, AppId: app.Id, PipelineId: p.Id, PipelineVersion: p.Version, Etag: run.Id,
+		CreatedAt: run.CreatedAt, CreatedBy: run.CreatedBy, ExecutingAt: &run.CreatedAt, Inputs: run.Inputs,
+		EnvIds: p.definition.envIds(), RunAs: "s-" + p.Id, Trigger: "pipeline_call", TimeoutSeconds: 3600,
+		Status: state.Status, StatusMessage: state.StatusMessage, CompletedAt: state.CompletedAt, WaitingFor: state.WaitingFor,
+	}
+	if out.Inputs == nil {
+		out.Inputs = map[string]interface{}{}
+	}
+	return out
+}
+
+func (s *Server) jobResponse(app *App, p *Pipeline, run *PipelineRun, job *jobState) client.PipelineJob {
+	out := client.PipelineJob{
+		Id: job.Id, OrgId: s.fixture.Org, AppId: app.Id, PipelineId: p.Id, PipelineVersion: p.Version, RunId: run.Id,
+		Etag: run.Id + "-" + job.Id, CreatedAt: job.CreatedAt, CompletedAt: job.CompletedAt, Status: job.Status,
+		StatusMessage: job.StatusMessage, TimeoutSeconds: 3600, Steps: make([]client.PipelineStep, 0, len(job.Steps)),
+	}
+	for _, st := range job.Steps {
+		out.Steps = append(out.Steps, st.PipelineStep)
+	}
+	return out
+}
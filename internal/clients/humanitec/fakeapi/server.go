@@ -0,0 +1,876 @@
+// Package fakeapi implements an in-process fake of the Humanitec API endpoints used by canyon. The Server is a plain
+// http.Handler so it can be served with httptest.NewServer in tests or on a real listener for local development. It is
+// also a request doer so that Humanitec clients can use it directly without a listener.
+package fakeapi
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/humanitec/humanitec-go-autogen/client"
+	"gopkg.in/yaml.v3"
+)
+
+// Server serves the content of a Fixture using the Humanitec API paths and response formats. Deployments and the sets
+// they create, as well as pipeline runs and approval decisions, are added to the fixture in memory, while action
+// pipeline calls and documentation queries return canned results from the fixture.
+type Server struct {
+	fixture *Fixture
+	mux     *http.ServeMux
+	// mu guards the fixture against concurrent deployments.
+	mu sync.RWMutex
+}
+
+func New(fixture *Fixture) *Server {
+	s := &Server{fixture: fixture, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /current-user", s.getCurrentUser)
+	s.mux.HandleFunc("GET /orgs/{orgId}/apps", s.listApps)
+	s.mux.HandleFunc("GET /orgs/{orgId}/apps/{appId}", s.getApp)
+	s.mux.HandleFunc("GET /orgs/{orgId}/apps/{appId}/envs", s.listEnvs)
+	s.mux.HandleFunc("GET /orgs/{orgId}/apps/{appId}/envs/{envId}", s.getEnv)
+	s.mux.HandleFunc("GET /orgs/{orgId}/apps/{appId}/envs/{envId}/deploys", s.listDeploys)
+	s.mux.HandleFunc("POST /orgs/{orgId}/apps/{appId}/envs/{envId}/deploys", s.createDeploy)
+	s.mux.HandleFunc("GET /orgs/{orgId}/apps/{appId}/envs/{envId}/deploys/{deployId}", s.getDeploy)
+	s.mux.HandleFunc("GET /orgs/{orgId}/apps/{appId}/envs/{envId}/deploys/{deployId}/errors", s.listDeployErrors)
+	s.mux.HandleFunc("GET /orgs/{orgId}/apps/{appId}/envs/{envId}/resources", s.listActiveResources)
+	s.mux.HandleFunc("POST /orgs/{orgId}/apps/{appId}/envs/{envId}/resources/graph", s.queryResourceGraph)
+	s.mux.HandleFunc("GET /orgs/{orgId}/apps/{appId}/sets", s.listSets)
+	s.mux.HandleFunc("GET /orgs/{orgId}/apps/{appId}/sets/{setId}", s.getSet)
+	s.mux.HandleFunc("POST /orgs/{orgId}/apps/{appId}/sets/{setId}", s.applyDeltaToSet)
+	s.mux.HandleFunc("GET /orgs/{orgId}/apps/{appId}/deltas/{deltaId}", s.getDelta)
+	s.mux.HandleFunc("GET /orgs/{orgId}/apps/{appId}/pipelines", s.listPipelines)
+	s.mux.HandleFunc("GET /orgs/{orgId}/apps/{appId}/pipelines/{pipelineId}", s.getPipeline)
+	s.mux.HandleFunc("GET /orgs/{orgId}/apps/{appId}/pipelines/{pipelineId}/schema", s.getPipelineDefinition)
+	s.mux.HandleFunc("GET /orgs/{orgId}/apps/{appId}/pipelines/{pipelineId}/runs", s.listPipelineRuns)
+	s.mux.HandleFunc("POST /orgs/{orgId}/apps/{appId}/pipelines/{pipelineId}/runs", s.createPipelineRun)
+	s.mux.HandleFunc("GET /orgs/{orgId}/apps/{appId}/pipelines/{pipelineId}/runs/{runId}", s.getPipelineRun)
+	s.mux.HandleFunc("GET /orgs/{orgId}/apps/{appId}/pipelines/{pipelineId}/runs/{runId}/jobs", s.listPipelineJobs)
+	s.mux.HandleFunc("GET /orgs/{orgId}/apps/{appId}/pipelines/{pipelineId}/runs/{runId}/jobs/{jobId}", s.getPipelineJob)
+	s.mux.HandleFunc("GET /orgs/{orgId}/apps/{appId}/pipelines/{pipelineId}/runs/{runId}/jobs/{jobId}/steps/{stepIndex}/logs", s.listPipelineStepLogs)
+	s.mux.HandleFunc("GET /orgs/{orgId}/apps/{appId}/pipelines/{pipelineId}/runs/{runId}/jobs/{jobId}/approvals/{approvalId}", s.getPipelineApproval)
+	s.mux.HandleFunc("POST /orgs/{orgId}/apps/{appId}/pipelines/{pipelineId}/runs/{runId}/jobs/{jobId}/approvals/{approvalId}/approve", s.decidePipelineApproval("approved"))
+	s.mux.HandleFunc("POST /orgs/{orgId}/apps/{appId}/pipelines/{pipelineId}/runs/{runId}/jobs/{jobId}/approvals/{approvalId}/deny", s.decidePipelineApproval("denied"))
+	s.mux.HandleFunc("GET /orgs/{orgId}/apps/{appId}/approvals", s.listPipelineApprovals)
+	s.mux.HandleFunc("GET /orgs/{orgId}/resources/defs", s.listResourceDefinitions)
+	s.mux.HandleFunc("GET /orgs/{orgId}/resources/defs/{defId}", s.getResourceDefinition)
+	s.mux.HandleFunc("GET /orgs/{orgId}/workload-profiles", s.listWorkloadProfiles)
+	// profile ids contain a slash, eg: humanitec/default-module
+	s.mux.HandleFunc("GET /orgs/{orgId}/workload-profiles/{profileId...}", s.getWorkloadProfile)
+	s.mux.HandleFunc("GET /orgs/{orgId}/action-pipelines", s.listActionPipelines)
+	s.mux.HandleFunc("GET /orgs/{orgId}/action-pipelines/{pipelineId}", s.getActionPipeline)
+	s.mux.HandleFunc("POST /orgs/{orgId}/action-pipelines/{pipelineId}/calls", s.callActionPipeline)
+	s.mux.HandleFunc("POST /experimental/query-ai-documentation", s.queryDocs)
+	return s
+}
+
+func (s *Server) Fixture() *Fixture {
+	return s.fixture
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" || (s.fixture.Token != "" && token != s.fixture.Token) {
+		writeError(w, http.StatusUnauthorized, "AUTH-401", "Invalid or missing token.")
+		return
+	}
+	if org := orgOf(r.URL.Path); org != "" && org != s.fixture.Org {
+		writeError(w, http.StatusNotFound, "API-404", fmt.Sprintf("Organization '%s' not found.", org))
+		return
+	}
+	if r.Method == http.MethodGet {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	} else {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// Do serves the request in-process. This allows the Server to be used as the request doer of a Humanitec client.
+func (s *Server) Do(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	resp := rec.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+// Offline reports that the Server never contacts the Humanitec API, so clients using it do not require a login.
+func (s *Server) Offline() bool {
+	return true
+}
+
+func orgOf(path string) string {
+	if rest, ok := strings.CutPrefix(path, "/orgs/"); ok {
+		org, _, _ := strings.Cut(rest, "/")
+		return org
+	}
+	return ""
+}
+
+func writeJson(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJson(w, status, map[string]interface{}{"error": code, "message": message})
+}
+
+// paginate returns the requested page of items when the request sets per_page and links to the next page.
+func paginate[T any](w http.ResponseWriter, r *http.Request, items []T) []T {
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if perPage <= 0 {
+		return items
+	}
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	page = max(page, 1)
+	start := min((page-1)*perPage, len(items))
+	end := min(start+perPage, len(items))
+	if end < len(items) {
+		next := url.Values{"page": {strconv.Itoa(page + 1)}, "per_page": {strconv.Itoa(perPage)}}
+		w.Header().Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, next.Encode()))
+	}
+	return items[start:end]
+}
+
+func (s *Server) findApp(w http.ResponseWriter, r *http.Request) *App {
+	app := s.fixture.app(r.PathValue("appId"))
+	if app == nil {
+		writeError(w, http.StatusNotFound, "APP-404", fmt.Sprintf("Application '%s' not found.", r.PathValue("appId")))
+	}
+	return app
+}
+
+func (s *Server) getCurrentUser(w http.ResponseWriter, r *http.Request) {
+	u := s.fixture.User
+	out := client.UserProfileExtendedResponse{
+		Id:         u.Id,
+		Name:       u.Name,
+		Type:       "user",
+		Properties: map[string]interface{}{},
+		Roles:      client.RolesResponse{"/orgs/" + s.fixture.Org: u.Role},
+	}
+	if u.Email != "" {
+		out.Email = &u.Email
+	}
+	writeJson(w, http.StatusOK, out)
+}
+
+func (s *Server) appResponse(app *App) client.ApplicationResponse {
+	out := client.ApplicationResponse{
+		Id:        app.Id,
+		Name:      app.Name,
+		OrgId:     s.fixture.Org,
+		CreatedAt: app.CreatedAt.Format(time.RFC3339),
+		CreatedBy: s.fixture.User.Id,
+		Envs:      make([]client.EnvironmentBaseResponse, 0, len(app.Envs)),
+	}
+	for _, env := range app.Envs {
+		out.Envs = append(out.Envs, client.EnvironmentBaseResponse{Id: env.Id, Name: env.Name, Type: env.Type})
+	}
+	return out
+}
+
+func (s *Server) listApps(w http.ResponseWriter, r *http.Request) {
+	out := make([]client.ApplicationResponse, 0, len(s.fixture.Apps))
+	for i := range s.fixture.Apps {
+		out = append(out, s.appResponse(&s.fixture.Apps[i]))
+	}
+	writeJson(w, http.StatusOK, paginate(w, r, out))
+}
+
+func (s *Server) getApp(w http.ResponseWriter, r *http.Request) {
+	if app := s.findApp(w, r); app != nil {
+		writeJson(w, http.StatusOK, s.appResponse(app))
+	}
+}
+
+func deployResponse(env *Env, d *Deployment) *client.DeploymentResponse {
+	out := &client.DeploymentResponse{
+		Id:              d.Id,
+		EnvId:           env.Id,
+		SetId:           d.SetId,
+		Status:          d.Status,
+		Comment:         d.Comment,
+		CreatedAt:       d.CreatedAt,
+		CreatedBy:       d.CreatedBy,
+		StatusChangedAt: d.CreatedAt.Add(d.Duration),
+	}
+	// deployments created through the api succeed once their duration passed
+	if d.Status == "in progress" && !time.Now().Before(out.StatusChangedAt) {
+		out.Status = "succeeded"
+	}
+	if d.DeltaId != "" {
+		out.DeltaId = &d.DeltaId
+	}
+	return out
+}
+
+func (s *Server) envResponse(env *Env) client.EnvironmentResponse {
+	out := client.EnvironmentResponse{
+		Id:        env.Id,
+		Name:      env.Name,
+		Type:      env.Type,
+		CreatedAt: env.CreatedAt,
+		CreatedBy: s.fixture.User.Id,
+	}
+	if env.LastDeploy != nil {
+		out.LastDeploy = deployResponse(env, env.LastDeploy)
+	}
+	return out
+}
+
+func (s *Server) listEnvs(w http.ResponseWriter, r *http.Request) {
+	if app := s.findApp(w, r); app != nil {
+		out := make([]client.EnvironmentResponse, 0, len(app.Envs))
+		for i := range app.Envs {
+			out = append(out, s.envResponse(&app.Envs[i]))
+		}
+		writeJson(w, http.StatusOK, paginate(w, r, out))
+	}
+}
+
+func (s *Server) getEnv(w http.ResponseWriter, r *http.Request) {
+	if env := s.findEnv(w, r); env != nil {
+		writeJson(w, http.StatusOK, s.envResponse(env))
+	}
+}
+
+func (s *Server) findEnv(w http.ResponseWriter, r *http.Request) *Env {
+	app := s.findApp(w, r)
+	if app == nil {
+		return nil
+	}
+	env := app.env(r.PathValue("envId"))
+	if env == nil {
+		writeError(w, http.StatusNotFound, "API-404", fmt.Sprintf("Environment '%s' not found.", r.PathValue("envId")))
+	}
+	return env
+}
+
+func (s *Server) listDeploys(w http.ResponseWriter, r *http.Request) {
+	if env := s.findEnv(w, r); env != nil {
+		out := make([]*client.DeploymentResponse, 0, len(env.Deploys))
+		for i := range env.Deploys {
+			out = append(out, deployResponse(env, &env.Deploys[i]))
+		}
+		writeJson(w, http.StatusOK, out)
+	}
+}
+
+func (s *Server) findDeploy(w http.ResponseWriter, r *http.Request) (*Env, *Deployment) {
+	env := s.findEnv(w, r)
+	if env == nil {
+		return nil, nil
+	}
+	d := env.deploy(r.PathValue("deployId"))
+	if d == nil {
+		writeError(w, http.StatusNotFound, "API-404", fmt.Sprintf("Deployment '%s' not found.", r.PathValue("deployId")))
+	}
+	return env, d
+}
+
+func (s *Server) getDeploy(w http.ResponseWriter, r *http.Request) {
+	if env, d := s.findDeploy(w, r); d != nil {
+		writeJson(w, http.StatusOK, deployResponse(env, d))
+	}
+}
+
+func (s *Server) listDeployErrors(w http.ResponseWriter, r *http.Request) {
+	if _, d := s.findDeploy(w, r); d != nil {
+		out := d.Errors
+		if out == nil {
+			out = []DeploymentError{}
+		}
+		writeJson(w, http.StatusOK, out)
+	}
+}
+
+// guResId derives a stable globally unique resource id in place of the hash used by the orchestrator.
+func (s *Server) guResId(appId, envId string, res *ActiveResource) string {
+	h := sha1.Sum([]byte(strings.Join([]string{s.fixture.Org, appId, envId, res.Type, res.Class, res.ResId}, "/")))
+	return hex.EncodeToString(h[:])
+}
+
+func (s *Server) listActiveResources(w http.ResponseWriter, r *http.Request) {
+	if env := s.findEnv(w, r); env != nil {
+		appId := r.PathValue("appId")
+		out := make([]client.ActiveResourceResponse, 0, len(env.Resources))
+		for i := range env.Resources {
+			res := &env.Resources[i]
+			ar := client.ActiveResourceResponse{
+				OrgId:      s.fixture.Org,
+				AppId:      appId,
+				EnvId:      env.Id,
+				EnvType:    env.Type,
+				ResId:      res.ResId,
+				Type:       res.Type,
+				Class:      res.Class,
+				DefId:      res.DefId,
+				DriverType: res.DriverType,
+				Status:     res.Status,
+				GuResId:    s.guResId(appId, env.Id, res),
+				Resource:   res.Resource,
+				SecretRefs: map[string]interface{}{},
+			}
+			if env.LastDeploy != nil {
+				ar.DeployId = env.LastDeploy.Id
+				ar.UpdatedAt = env.LastDeploy.CreatedAt
+			}
+			out = append(out, ar)
+		}
+		writeJson(w, http.StatusOK, out)
+	}
+}
+
+// queryResourceGraph returns the requested resources and everything they depend on.
+func (s *Server) queryResourceGraph(w http.ResponseWriter, r *http.Request) {
+	env := s.findEnv(w, r)
+	if env == nil {
+		return
+	}
+	var body []client.ResourceProvisionRequestRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "API-400", fmt.Sprintf("Invalid request body: %s.", err))
+		return
+	}
+	appId := r.PathValue("appId")
+	out := make([]client.NodeBodyResponse, 0)
+	seen := make(map[string]bool)
+	var visit func(res *ActiveResource)
+	visit = func(res *ActiveResource) {
+		if seen[res.ResId] {
+			return
+		}
+		seen[res.ResId] = true
+		node := client.NodeBodyResponse{
+			Id:             res.ResId,
+			Type:           res.Type,
+			Class:          res.Class,
+			DefId:          res.DefId,
+			DriverType:     res.DriverType,
+			Guresid:        s.guResId(appId, env.Id, res),
+			DependsOn:      make([]string, 0, len(res.DependsOn)),
+			Driver:         map[string]interface{}{},
+			Resource:       res.Resource,
+			ResourceSchema: map[string]interface{}{},
+		}
+		for _, dep := range res.DependsOn {
+			node.DependsOn = append(node.DependsOn, s.guResId(appId, env.Id, env.resource(dep)))
+		}
+		out = append(out, node)
+		for _, dep := range res.DependsOn {
+			visit(env.resource(dep))
+		}
+	}
+	for _, req := range body {
+		if res := env.resource(req.Id); res != nil && res.Type == req.Type {
+			visit(res)
+		}
+	}
+	writeJson(w, http.StatusOK, out)
+}
+
+func (s *Server) listPipelines(w http.ResponseWriter, r *http.Request) {
+	if app := s.findApp(w, r); app != nil {
+		out := make([]client.Pipeline, 0, len(app.Pipelines))
+		for i := range app.Pipelines {
+			out = append(out, s.pipelineResponse(app, &app.Pipelines[i]))
+		}
+		writeJson(w, http.StatusOK, paginate(w, r, out))
+	}
+}
+
+func (s *Server) findPipeline(w http.ResponseWriter, r *http.Request) (*App, *Pipeline) {
+	app := s.findApp(w, r)
+	if app == nil {
+		return nil, nil
+	}
+	p := app.pipeline(r.PathValue("pipelineId"))
+	if p == nil {
+		writeError(w, http.StatusNotFound, "API-404", fmt.Sprintf("Pipeline '%s' not found.", r.PathValue("pipelineId")))
+	}
+	return app, p
+}
+
+func (s *Server) getPipeline(w http.ResponseWriter, r *http.Request) {
+	if app, p := s.findPipeline(w, r); p != nil {
+		writeJson(w, http.StatusOK, s.pipelineResponse(app, p))
+	}
+}
+
+// getPipelineDefinition returns the definition as yaml when the request accepts the pipelines yaml media type,
+// otherwise as json.
+func (s *Server) getPipelineDefinition(w http.ResponseWriter, r *http.Request) {
+	if _, p := s.findPipeline(w, r); p != nil {
+		if strings.Contains(r.Header.Get("Accept"), "yaml") {
+			w.Header().Set("Content-Type", "application/x.humanitec-pipelines-v1.0+yaml")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(p.Definition))
+			return
+		}
+		var out map[string]interface{}
+		_ = yaml.Unmarshal([]byte(p.Definition), &out)
+		writeJson(w, http.StatusOK, out)
+	}
+}
+
+func (s *Server) listPipelineRuns(w http.ResponseWriter, r *http.Request) {
+	if app, p := s.findPipeline(w, r); p != nil {
+		now := time.Now()
+		out := make([]client.PipelineRun, 0, len(p.Runs))
+		for i := range p.Runs {
+			run := s.runResponse(app, p, &p.Runs[i], s.simulate(app, p, &p.Runs[i], now))
+			if status := r.URL.Query()["status"]; len(status) > 0 && !slices.Contains(status, run.Status) {
+				continue
+			} else if env := r.URL.Query().Get("env"); env != "" && !slices.Contains(run.EnvIds, env) {
+				continue
+			} else if completed := r.URL.Query().Get("completed"); completed != "" && (completed == "true") != (run.CompletedAt != nil) {
+				continue
+			}
+			out = append(out, run)
+		}
+		writeJson(w, http.StatusOK, paginate(w, r, out))
+	}
+}
+
+func (s *Server) createPipelineRun(w http.ResponseWriter, r *http.Request) {
+	app, p := s.findPipeline(w, r)
+	if p == nil {
+		return
+	}
+	var body struct {
+		Inputs map[string]interface{} `json:"inputs"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "API-400", fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	inputs, err := p.definition.runInputs(body.Inputs)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "API-422", fmt.Sprintf("The run of pipeline '%s' is invalid: %v.", p.Id, err))
+		return
+	} else if r.URL.Query().Get("dry_run") == "true" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	run := PipelineRun{
+		Id:           runId(app.Id, p.Id, len(p.Runs)),
+		Inputs:       inputs,
+		CreatedAt:    time.Now().UTC(),
+		CreatedBy:    s.fixture.User.Id,
+		StepDuration: s.fixture.PipelineStepDuration,
+	}
+	p.Runs = append([]PipelineRun{run}, p.Runs...)
+	writeJson(w, http.StatusCreated, s.runResponse(app, p, &p.Runs[0], s.simulate(app, p, &p.Runs[0], run.CreatedAt)))
+}
+
+// findPipelineRun returns the run and its state at the time of the request.
+func (s *Server) findPipelineRun(w http.ResponseWriter, r *http.Request) (*App, *Pipeline, *PipelineRun, *runState) {
+	app, p := s.findPipeline(w, r)
+	if p == nil {
+		return nil, nil, nil, nil
+	}
+	run := p.run(r.PathValue("runId"))
+	if run == nil {
+		writeError(w, http.StatusNotFound, "API-404", fmt.Sprintf("Run '%s' not found.", r.PathValue("runId")))
+		return nil, nil, nil, nil
+	}
+	state := s.simulate(app, p, run, time.Now())
+	return app, p, run, &state
+}
+
+func (s *Server) getPipelineRun(w http.ResponseWriter, r *http.Request) {
+	if app, p, run, state := s.findPipelineRun(w, r); run != nil {
+		writeJson(w, http.StatusOK, s.runResponse(app, p, run, *state))
+	}
+}
+
+func (s *Server) listPipelineJobs(w http.ResponseWriter, r *http.Request) {
+	if app, p, run, state := s.findPipelineRun(w, r); run != nil {
+		out := make([]client.PipelineJobPartial, 0, len(state.Jobs))
+		for i := range state.Jobs {
+			job := s.jobResponse(app, p, run, &state.Jobs[i])
+			out = append(out, client.PipelineJobPartial{
+				Id: job.Id, OrgId: job.OrgId, AppId: job.AppId, PipelineId: job.PipelineId, PipelineVersion: job.PipelineVersion,
+				RunId: job.RunId, Etag: job.Etag, CreatedAt: job.CreatedAt, CompletedAt: job.CompletedAt, Status: job.Status,
+				StatusMessage: job.StatusMessage, TimeoutSeconds: job.TimeoutSeconds,
+			})
+		}
+		writeJson(w, http.StatusOK, paginate(w, r, out))
+	}
+}
+
+func (s *Server) findPipelineJob(w http.ResponseWriter, r *http.Request) (*App, *Pipeline, *PipelineRun, *jobState) {
+	app, p, run, state := s.findPipelineRun(w, r)
+	if run == nil {
+		return nil, nil, nil, nil
+	}
+	job := state.job(r.PathValue("jobId"))
+	if job == nil {
+		writeError(w, http.StatusNotFound, "API-404", fmt.Sprintf("Job '%s' not found.", r.PathValue("jobId")))
+	}
+	return app, p, run, job
+}
+
+func (s *Server) getPipelineJob(w http.ResponseWriter, r *http.Request) {
+	if app, p, run, job := s.findPipelineJob(w, r); job != nil {
+		writeJson(w, http.StatusOK, s.jobResponse(app, p, run, job))
+	}
+}
+
+func (s *Server) listPipelineStepLogs(w http.ResponseWriter, r *http.Request) {
+	if _, _, _, job := s.findPipelineJob(w, r); job != nil {
+		index, err := strconv.Atoi(r.PathValue("stepIndex"))
+		if st := job.step(index); err != nil || st == nil {
+			writeError(w, http.StatusNotFound, "API-404", fmt.Sprintf("Step '%s' not found.", r.PathValue("stepIndex")))
+		} else {
+			writeJson(w, http.StatusOK, paginate(w, r, st.Logs))
+		}
+	}
+}
+
+func (s *Server) getPipelineApproval(w http.ResponseWriter, r *http.Request) {
+	if _, _, run, state := s.findPipelineRun(w, r); run != nil {
+		if _, a := state.approval(r.PathValue("approvalId")); a == nil || a.JobId != r.PathValue("jobId") {
+			writeError(w, http.StatusNotFound, "API-404", fmt.Sprintf("Approval request '%s' not found.", r.PathValue("approvalId")))
+		} else {
+			writeJson(w, http.StatusOK, a)
+		}
+	}
+}
+
+// decidePipelineApproval records the decision on a waiting approval request, which lets the run continue.
+func (s *Server) decidePipelineApproval(status string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		app, p, run, state := s.findPipelineRun(w, r)
+		if run == nil {
+			return
+		}
+		st, a := state.approval(r.PathValue("approvalId"))
+		if a == nil || a.JobId != r.PathValue("jobId") {
+			writeError(w, http.StatusNotFound, "API-404", fmt.Sprintf("Approval request '%s' not found.", r.PathValue("approvalId")))
+			return
+		} else if a.Status != client.Waiting {
+			writeError(w, http.StatusConflict, "API-409", fmt.Sprintf("Approval request '%s' was already %s.", a.Id, a.Status))
+			return
+		}
+		if run.Approvals == nil {
+			run.Approvals = make(map[string]PipelineApproval)
+		}
+		run.Approvals[st.Name] = PipelineApproval{Status: status, At: time.Now().UTC(), By: s.fixture.User.Id}
+		updated := s.simulate(app, p, run, time.Now())
+		_, a = updated.approval(a.Id)
+		writeJson(w, http.StatusOK, a)
+	}
+}
+
+func (s *Server) listPipelineApprovals(w http.ResponseWriter, r *http.Request) {
+	app := s.findApp(w, r)
+	if app == nil {
+		return
+	}
+	q := r.URL.Query()
+	now := time.Now()
+	out := make([]client.PipelineApprovalRequest, 0)
+	for i := range app.Pipelines {
+		p := &app.Pipelines[i]
+		if len(q["pipeline"]) > 0 && !slices.Contains(q["pipeline"], p.Id) {
+			continue
+		}
+		for j := range p.Runs {
+			if len(q["run"]) > 0 && !slices.Contains(q["run"], p.Runs[j].Id) {
+				continue
+			}
+			state := s.simulate(app, p, &p.Runs[j], now)
+			for _, job := range state.Jobs {
+				for _, st := range job.Steps {
+					if st.Approval != nil && (q.Get("status") == "" || q.Get("status") == string(st.Approval.Status)) {
+						out = append(out, *st.Approval)
+					}
+				}
+			}
+		}
+	}
+	writeJson(w, http.StatusOK, paginate(w, r, out))
+}
+
+func (s *Server) resourceDefinitionResponse(def map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{"org_id": s.fixture.Org, "name": def["id"], "criteria": []interface{}{}}
+	for k, v := range def {
+		out[k] = v
+	}
+	return out
+}
+
+func (s *Server) listResourceDefinitions(w http.ResponseWriter, r *http.Request) {
+	out := make([]map[string]interface{}, 0, len(s.fixture.ResourceDefinitions))
+	for _, def := range s.fixture.ResourceDefinitions {
+		out = append(out, s.resourceDefinitionResponse(def))
+	}
+	writeJson(w, http.StatusOK, out)
+}
+
+func (s *Server) getResourceDefinition(w http.ResponseWriter, r *http.Request) {
+	if def := s.fixture.resourceDefinition(r.PathValue("defId")); def == nil {
+		writeError(w, http.StatusNotFound, "API-404", fmt.Sprintf("Resource Definition '%s' not found.", r.PathValue("defId")))
+	} else {
+		writeJson(w, http.StatusOK, s.resourceDefinitionResponse(def))
+	}
+}
+
+func setResponse(id string, content map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{"id": id, "version": 0, "modules": map[string]interface{}{}, "shared": map[string]interface{}{}}
+	for k, v := range content {
+		out[k] = v
+	}
+	return out
+}
+
+func (s *Server) listSets(w http.ResponseWriter, r *http.Request) {
+	if app := s.findApp(w, r); app != nil {
+		out := make([]map[string]interface{}, 0, len(app.Sets))
+		for _, id := range slices.Sorted(maps.Keys(app.Sets)) {
+			out = append(out, setResponse(id, app.Sets[id]))
+		}
+		writeJson(w, http.StatusOK, out)
+	}
+}
+
+func (s *Server) getSet(w http.ResponseWriter, r *http.Request) {
+	if app := s.findApp(w, r); app != nil {
+		if content, ok := app.Sets[r.PathValue("setId")]; !ok {
+			// the sets endpoint returns its errors as a json string
+			writeJson(w, http.StatusNotFound, fmt.Sprintf("Deployment Set '%s' not found.", r.PathValue("setId")))
+		} else {
+			writeJson(w, http.StatusOK, setResponse(r.PathValue("setId"), content))
+		}
+	}
+}
+
+func (s *Server) getDelta(w http.ResponseWriter, r *http.Request) {
+	if app := s.findApp(w, r); app != nil {
+		if d, ok := app.Deltas[r.PathValue("deltaId")]; !ok {
+			writeJson(w, http.StatusNotFound, fmt.Sprintf("Delta '%s' not found.", r.PathValue("deltaId")))
+		} else {
+			writeJson(w, http.StatusOK, map[string]interface{}{
+				"id": r.PathValue("deltaId"), "metadata": map[string]interface{}{"created_by": s.fixture.User.Id}, "modules": d.Modules, "shared": d.Shared,
+			})
+		}
+	}
+}
+
+// storeDelta applies a delta to a set of the app and stores the resulting set.
+func storeDelta(app *App, baseSetId string, d delta) (string, error) {
+	base, ok := app.Sets[baseSetId]
+	if !ok {
+		return "", fmt.Errorf("set '%s' does not exist", baseSetId)
+	}
+	content, err := applyDelta(base, d)
+	if err != nil {
+		return "", err
+	}
+	id := setId(content)
+	if app.Sets == nil {
+		app.Sets = make(map[string]map[string]interface{})
+	}
+	app.Sets[id] = content
+	return id, nil
+}
+
+func (s *Server) applyDeltaToSet(w http.ResponseWriter, r *http.Request) {
+	if app := s.findApp(w, r); app != nil {
+		var body delta
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "API-400", fmt.Sprintf("Invalid request body: %v", err))
+			return
+		}
+		if _, ok := app.Sets[r.PathValue("setId")]; !ok {
+			writeJson(w, http.StatusNotFound, fmt.Sprintf("Deployment Set '%s' not found.", r.PathValue("setId")))
+		} else if id, err := storeDelta(app, r.PathValue("setId"), body); err != nil {
+			writeError(w, http.StatusBadRequest, "API-400", fmt.Sprintf("The delta cannot be applied: %v", err))
+		} else {
+			writeJson(w, http.StatusOK, id)
+		}
+	}
+}
+
+func (s *Server) createDeploy(w http.ResponseWriter, r *http.Request) {
+	env := s.findEnv(w, r)
+	if env == nil {
+		return
+	}
+	app := s.fixture.app(r.PathValue("appId"))
+	var body struct {
+		SetId   string `json:"set_id"`
+		DeltaId string `json:"delta_id"`
+		Comment string `json:"comment"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "API-400", fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	setId := body.SetId
+	if body.DeltaId != "" {
+		d, ok := app.Deltas[body.DeltaId]
+		if !ok {
+			writeError(w, http.StatusNotFound, "API-404", fmt.Sprintf("Delta '%s' not found.", body.DeltaId))
+			return
+		} else if setId == "" && env.LastDeploy == nil {
+			writeError(w, http.StatusBadRequest, "API-400", "A delta can only be deployed to an Environment that was deployed before.")
+			return
+		} else if setId == "" {
+			setId = env.LastDeploy.SetId
+		}
+		var err error
+		if setId, err = storeDelta(app, setId, d); err != nil {
+			writeError(w, http.StatusBadRequest, "API-400", fmt.Sprintf("The delta cannot be applied: %v", err))
+			return
+		}
+	}
+	if setId == "" {
+		writeError(w, http.StatusBadRequest, "API-400", "Either a set_id or a delta_id is required.")
+		return
+	} else if _, ok := app.Sets[setId]; !ok {
+		writeError(w, http.StatusNotFound, "API-404", fmt.Sprintf("Deployment Set '%s' not found.", setId))
+		return
+	}
+	d := Deployment{
+		Id:        deployId(app.Id, env.Id, len(env.Deploys)),
+		SetId:     setId,
+		DeltaId:   body.DeltaId,
+		Status:    "in progress",
+		Comment:   body.Comment,
+		CreatedAt: time.Now().UTC(),
+		CreatedBy: s.fixture.User.Id,
+		Duration:  s.fixture.DeployDuration,
+	}
+	env.Deploys = append([]Deployment{d}, env.Deploys...)
+	env.LastDeploy = &env.Deploys[0]
+	out := deployResponse(env, env.LastDeploy)
+	out.Status = "in progress"
+	writeJson(w, http.StatusCreated, out)
+}
+
+func (s *Server) workloadProfileResponse(p *WorkloadProfile) client.WorkloadProfileResponse {
+	return client.WorkloadProfileResponse{
+		Id:          p.Id,
+		OrgId:       s.fixture.Org,
+		Description: p.Description,
+		Version:     p.Version,
+		SpecSchema:  p.SpecSchema,
+		CreatedBy:   s.fixture.User.Id,
+		UpdatedBy:   s.fixture.User.Id,
+	}
+}
+
+func (s *Server) listWorkloadProfiles(w http.ResponseWriter, r *http.Request) {
+	out := make([]client.WorkloadProfileResponse, 0, len(s.fixture.WorkloadProfiles))
+	for i := range s.fixture.WorkloadProfiles {
+		out = append(out, s.workloadProfileResponse(&s.fixture.WorkloadProfiles[i]))
+	}
+	writeJson(w, http.StatusOK, out)
+}
+
+func (s *Server) getWorkloadProfile(w http.ResponseWriter, r *http.Request) {
+	if p := s.fixture.workloadProfile(r.PathValue("profileId")); p == nil {
+		writeError(w, http.StatusNotFound, "API-404", fmt.Sprintf("Workload Profile '%s' not found.", r.PathValue("profileId")))
+	} else {
+		writeJson(w, http.StatusOK, s.workloadProfileResponse(p))
+	}
+}
+
+func (s *Server) actionPipelineResponse(p *ActionPipeline) map[string]interface{} {
+	return map[string]interface{}{
+		"org_id":            s.fixture.Org,
+		"id":                p.Id,
+		"description":       p.Description,
+		"type":              p.Type,
+		"created_at":        "",
+		"inputs_jsonschema": p.InputsJsonSchema,
+	}
+}
+
+func (s *Server) listActionPipelines(w http.ResponseWriter, r *http.Request) {
+	out := make([]map[string]interface{}, 0, len(s.fixture.ActionPipelines))
+	for i := range s.fixture.ActionPipelines {
+		summary := s.actionPipelineResponse(&s.fixture.ActionPipelines[i])
+		delete(summary, "inputs_jsonschema")
+		out = append(out, summary)
+	}
+	writeJson(w, http.StatusOK, paginate(w, r, out))
+}
+
+func (s *Server) findActionPipeline(w http.ResponseWriter, r *http.Request) *ActionPipeline {
+	p := s.fixture.actionPipeline(r.PathValue("pipelineId"))
+	if p == nil {
+		writeError(w, http.StatusNotFound, "API-404", fmt.Sprintf("Action pipeline '%s' not found.", r.PathValue("pipelineId")))
+	}
+	return p
+}
+
+func (s *Server) getActionPipeline(w http.ResponseWriter, r *http.Request) {
+	if p := s.findActionPipeline(w, r); p != nil {
+		writeJson(w, http.StatusOK, s.actionPipelineResponse(p))
+	}
+}
+
+func (s *Server) callActionPipeline(w http.ResponseWriter, r *http.Request) {
+	if p := s.findActionPipeline(w, r); p != nil {
+		var body struct {
+			Inputs map[string]interface{} `json:"inputs"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "API-400", fmt.Sprintf("Invalid request body: %v", err))
+			return
+		}
+		if required, ok := p.InputsJsonSchema["required"].([]interface{}); ok {
+			for _, k := range required {
+				if _, ok := body.Inputs[fmt.Sprint(k)]; !ok {
+					writeError(w, http.StatusUnprocessableEntity, "API-422", fmt.Sprintf("Missing required input '%v'.", k))
+					return
+				}
+			}
+		}
+		outputs := p.Outputs
+		if outputs == nil {
+			outputs = map[string]interface{}{}
+		}
+		writeJson(w, http.StatusOK, map[string]interface{}{"outputs": outputs})
+	}
+}
+
+func (s *Server) queryDocs(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Query string `json:"query"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "API-400", fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	for _, d := range s.fixture.Docs {
+		if regexp.MustCompile("(?i)" + d.Match).MatchString(body.Query) {
+			writeJson(w, http.StatusOK, map[string]interface{}{"answer": d.Answer, "is_uncertain": false})
+			return
+		}
+	}
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"answer":       "The documentation available to this fake API does not cover this question.",
+		"is_uncertain": true,
+	})
+}
@@ -0,0 +1,254 @@
+package fakeapi
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/humanitec/humanitec-go-autogen/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec"
+)
+
+func newTestClient(t *testing.T, token string) *humanitec.WrappedHumanitecClientImpl {
+	t.Helper()
+	srv := httptest.NewServer(New(DefaultFixture()))
+	t.Cleanup(srv.Close)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("CANYON_PROFILES_FILE", filepath.Join(home, "profiles.yaml"))
+	t.Setenv("HUMANITEC_TOKEN", token)
+	t.Setenv("HUMANITEC_API_PREFIX", srv.URL)
+	require.NoError(t, humanitec.ConfigureDefaultResponseCache(0, ""))
+	hc, err := humanitec.NewHumanitecClientWithCurrentToken(context.Background())
+	require.NoError(t, err)
+	return hc
+}
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+	hc := newTestClient(t, "anything")
+
+	user, err := humanitec.CheckResponse(func() (*client.GetCurrentUserResponse, error) {
+		return hc.GetCurrentUserWithResponse(ctx)
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	require.NoError(t, err)
+	assert.Equal(t, client.RolesResponse{"/orgs/canyon-demo": "administrator"}, user.JSON200.Roles)
+
+	apps, cursor, err := hc.ListAllApplications(ctx, "canyon-demo", humanitec.PageOptions{PerPage: 1})
+	require.NoError(t, err)
+	assert.Empty(t, cursor)
+	require.Len(t, apps, 3)
+	assert.Equal(t, "backend", apps[1].Id)
+
+	envs, _, err := hc.ListAllEnvironments(ctx, "canyon-demo", "frontend", humanitec.PageOptions{})
+	require.NoError(t, err)
+	require.Len(t, envs, 2)
+	assert.Equal(t, "frontend-set-2", envs[0].LastDeploy.SetId)
+
+	set, err := humanitec.CheckResponse(func() (*client.GetSetResponse, error) {
+		return hc.GetSetWithResponse(ctx, "canyon-demo", "frontend", "frontend-set-2", &client.GetSetParams{})
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	require.NoError(t, err)
+	assert.Contains(t, string(set.Body), `"id":"frontend-set-2"`)
+	assert.Contains(t, string(set.Body), `"image":"ghcr.io/canyon-demo/web:1.4.0"`)
+
+	graph, err := humanitec.CheckResponse(func() (*client.QueryResourceGraphResponse, error) {
+		return hc.QueryResourceGraphWithResponse(ctx, "canyon-demo", "frontend", "development", client.QueryResourceGraphJSONRequestBody{
+			{Id: "k8s-namespace", Type: "k8s-namespace"},
+		})
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	require.NoError(t, err)
+	require.Len(t, *graph.JSON200, 2)
+	assert.Equal(t, []string{(*graph.JSON200)[1].Guresid}, (*graph.JSON200)[0].DependsOn)
+
+	profile, err := humanitec.CheckResponse(func() (*client.GetWorkloadProfileResponse, error) {
+		return hc.GetWorkloadProfileWithResponse(ctx, "canyon-demo", "humanitec/default-module")
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	require.NoError(t, err)
+	assert.NotNil(t, profile.JSON200.SpecSchema)
+
+	summaries, _, err := hc.ListAllActionPipelineSummaries(ctx, "canyon-demo", humanitec.PageOptions{})
+	require.NoError(t, err)
+	require.Len(t, summaries, 2)
+	call, err := hc.CallActionPipeline(ctx, "canyon-demo", summaries[0].Id, nil, humanitec.CallActionPipelineRequestBody{
+		Inputs: map[string]interface{}{"app_id": "frontend", "name": "preview"},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, call.JSON200)
+	assert.Equal(t, "preview-1", call.JSON200.Outputs["env_id"])
+	call, err = hc.CallActionPipeline(ctx, "canyon-demo", summaries[0].Id, nil, humanitec.CallActionPipelineRequestBody{})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnprocessableEntity, call.StatusCode())
+
+	docs, err := hc.QueryAiDocs(ctx, "What is SCORE?")
+	require.NoError(t, err)
+	assert.False(t, docs.JSON200.IsUncertain)
+	docs, err = hc.QueryAiDocs(ctx, "How do I bake bread?")
+	require.NoError(t, err)
+	assert.True(t, docs.JSON200.IsUncertain)
+
+	_, _, err = hc.ListAllApplications(ctx, "other-org", humanitec.PageOptions{})
+	assert.Equal(t, humanitec.ErrorKindNotFound, humanitec.ErrorKindOf(err))
+}
+
+func TestServer_Do(t *testing.T) {
+	s := New(DefaultFixture())
+	assert.True(t, s.Offline())
+	req, _ := http.NewRequest(http.MethodGet, "https://api.humanitec.io/orgs/canyon-demo/apps/checkout/envs/production", nil)
+	req.Header.Set("Authorization", "Bearer x")
+	resp, err := s.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	raw, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(raw), `"set_id":"checkout-set-1"`)
+}
+
+func TestServer_token(t *testing.T) {
+	f := DefaultFixture()
+	f.Token = "expected"
+	srv := httptest.NewServer(New(f))
+	defer srv.Close()
+	for token, status := range map[string]int{"": http.StatusUnauthorized, "wrong": http.StatusUnauthorized, "expected": http.StatusOK} {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/current-user", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, status, resp.StatusCode, token)
+	}
+}
+
+func TestParseFixture(t *testing.T) {
+	_, err := ParseFixture([]byte(`apps: []`))
+	assert.EqualError(t, err, "org is required")
+	_, err = ParseFixture([]byte(`
+org: x
+apps:
+- id: a
+  envs:
+  - id: dev
+    last_deploy: {id: d, set_id: missing}
+`))
+	assert.EqualError(t, err, "env 'dev' in app 'a' references unknown set 'missing'")
+	f, err := ParseFixture([]byte(`org: x`))
+	require.NoError(t, err)
+	assert.Equal(t, "administrator", f.User.Role)
+}
+
+func TestServer_deploy(t *testing.T) {
+	ctx := context.Background()
+	hc := newTestClient(t, "anything")
+
+	delta, err := humanitec.CheckResponse(func() (*client.GetDeltaResponse, error) {
+		return hc.GetDeltaWithResponse(ctx, "canyon-demo", "backend", "upgrade-api")
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	require.NoError(t, err)
+	newSet, err := humanitec.CheckResponse(func() (*client.UpdateSetResponse, error) {
+		return hc.UpdateSetWithBodyWithResponse(ctx, "canyon-demo", "backend", "backend-set-1", "application/json", bytes.NewReader(delta.Body))
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	require.NoError(t, err)
+	set, err := humanitec.CheckResponse(func() (*client.GetSetResponse, error) {
+		return hc.GetSetWithResponse(ctx, "canyon-demo", "backend", *newSet.JSON200, &client.GetSetParams{})
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	require.NoError(t, err)
+	assert.Contains(t, string(set.Body), `"image":"ghcr.io/canyon-demo/api:2.1.0"`)
+
+	deploy, err := humanitec.CheckResponse(func() (*client.CreateDeploymentResponse, error) {
+		return hc.CreateDeploymentWithResponse(ctx, "canyon-demo", "backend", "development", client.CreateDeploymentJSONRequestBody{SetId: newSet.JSON200})
+	}).AndStatusCodeEq(http.StatusCreated).RespAndError()
+	require.NoError(t, err)
+	assert.Equal(t, "in progress", deploy.JSON201.Status)
+
+	env, err := humanitec.CheckResponse(func() (*client.GetEnvironmentResponse, error) {
+		return hc.GetEnvironmentWithResponse(ctx, "canyon-demo", "backend", "development")
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	require.NoError(t, err)
+	assert.Equal(t, deploy.JSON201.Id, env.JSON200.LastDeploy.Id)
+	assert.Equal(t, *newSet.JSON200, env.JSON200.LastDeploy.SetId)
+	assert.Equal(t, "succeeded", env.JSON200.LastDeploy.Status)
+
+	_, err = humanitec.CheckResponse(func() (*client.UpdateSetResponse, error) {
+		return hc.UpdateSetWithBodyWithResponse(ctx, "canyon-demo", "backend", "backend-set-1", "application/json", strings.NewReader(`{"modules":{"update":{"missing":[{"op":"remove","path":"/spec"}]}}}`))
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	assert.Error(t, err)
+}
+
+func TestServer_pipelines(t *testing.T) {
+	ctx := context.Background()
+	hc := newTestClient(t, "anything")
+
+	pipelines, err := humanitec.CheckResponse(func() (*client.ListPipelinesResponse, error) {
+		return hc.ListPipelinesWithResponse(ctx, "canyon-demo", "frontend", &client.ListPipelinesParams{})
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	require.NoError(t, err)
+	require.Len(t, *pipelines.JSON200, 1)
+	assert.Equal(t, []string{"pipeline_call"}, (*pipelines.JSON200)[0].TriggerTypes)
+
+	runs, err := humanitec.CheckResponse(func() (*client.ListPipelineRunsResponse, error) {
+		return hc.ListPipelineRunsWithResponse(ctx, "canyon-demo", "frontend", "promote", &client.ListPipelineRunsParams{})
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	require.NoError(t, err)
+	require.Len(t, *runs.JSON200, 2)
+	assert.Equal(t, "failed", (*runs.JSON200)[0].Status)
+	assert.Equal(t, "succeeded", (*runs.JSON200)[1].Status)
+	assert.Equal(t, "2025-03-03T08:46:00Z", (*runs.JSON200)[1].CompletedAt.Format(time.RFC3339))
+
+	_, err = humanitec.CheckResponse(func() (*client.CreatePipelineRunResponse, error) {
+		return hc.CreatePipelineRunWithResponse(ctx, "canyon-demo", "frontend", "promote", &client.CreatePipelineRunParams{}, client.PipelineRunCreateBody{Inputs: map[string]interface{}{"unknown": 1}})
+	}).AndStatusCodeEq(http.StatusCreated).RespAndError()
+	assert.ErrorContains(t, err, "unknown input 'unknown'")
+
+	run, err := humanitec.CheckResponse(func() (*client.CreatePipelineRunResponse, error) {
+		return hc.CreatePipelineRunWithResponse(ctx, "canyon-demo", "frontend", "promote", &client.CreatePipelineRunParams{}, client.PipelineRunCreateBody{})
+	}).AndStatusCodeEq(http.StatusCreated).RespAndError()
+	require.NoError(t, err)
+	assert.Equal(t, "executing", run.JSON201.Status)
+	assert.Equal(t, "Promoted from development", run.JSON201.Inputs["comment"])
+	approvalId := run.JSON201.WaitingFor["approval"]
+	require.NotEmpty(t, approvalId)
+
+	approvals, err := humanitec.CheckResponse(func() (*client.ListPipelineApprovalRequestsResponse, error) {
+		status := string(client.Waiting)
+		return hc.ListPipelineApprovalRequestsWithResponse(ctx, "canyon-demo", "frontend", &client.ListPipelineApprovalRequestsParams{Status: &status})
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	require.NoError(t, err)
+	require.Len(t, *approvals.JSON200, 1)
+	assert.Equal(t, "production", (*approvals.JSON200)[0].EnvId)
+
+	approved, err := humanitec.CheckResponse(func() (*client.ApprovePipelineApprovalRequestResponse, error) {
+		return hc.ApprovePipelineApprovalRequestWithResponse(ctx, "canyon-demo", "frontend", "promote", run.JSON201.Id, "promote", approvalId)
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	require.NoError(t, err)
+	assert.Equal(t, client.Approved, approved.JSON200.Status)
+	_, err = humanitec.CheckResponse(func() (*client.DenyPipelineApprovalRequestResponse, error) {
+		return hc.DenyPipelineApprovalRequestWithResponse(ctx, "canyon-demo", "frontend", "promote", run.JSON201.Id, "promote", approvalId)
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	assert.ErrorContains(t, err, "already approved")
+
+	job, err := humanitec.CheckResponse(func() (*client.GetPipelineJobResponse, error) {
+		return hc.GetPipelineJobWithResponse(ctx, "canyon-demo", "frontend", "promote", run.JSON201.Id, "promote")
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	require.NoError(t, err)
+	assert.Equal(t, "succeeded", job.JSON200.Status)
+	assert.Len(t, job.JSON200.Steps, 3)
+
+	logs, err := humanitec.CheckResponse(func() (*client.ListPipelineStepLogsResponse, error) {
+		return hc.ListPipelineStepLogsWithResponse(ctx, "canyon-demo", "frontend", "promote", run.JSON201.Id, "promote", 0, &client.ListPipelineStepLogsParams{})
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	require.NoError(t, err)
+	require.Len(t, *logs.JSON200, 4)
+	assert.Equal(t, "The approval request was approved by 0b7a1f0e-demo-user", (*logs.JSON200)[2].Message)
+}
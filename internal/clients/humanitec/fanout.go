@@ -0,0 +1,51 @@
+package humanitec
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultFanOutConcurrency is the number of concurrent workers used by FanOut.
+const DefaultFanOutConcurrency = 10
+
+// FanOutResult is the outcome of a single item processed by FanOut.
+type FanOutResult[I any, O any] struct {
+	Item   I
+	Output O
+	Err    error
+}
+
+// FanOut calls fn for every item with at most DefaultFanOutConcurrency calls in flight and returns the results in the
+// same order as the items. Requests made by fn still pass through the shared rate limiter so that multiple fan-outs
+// running at once do not exceed the API rate limits. Items that have not started when the context is cancelled are
+// returned with the context error.
+func FanOut[I any, O any](ctx context.Context, items []I, fn func(ctx context.Context, item I) (O, error)) []FanOutResult[I, O] {
+	return FanOutN(ctx, DefaultFanOutConcurrency, items, fn)
+}
+
+// FanOutN is FanOut with an explicit concurrency.
+func FanOutN[I any, O any](ctx context.Context, concurrency int, items []I, fn func(ctx context.Context, item I) (O, error)) []FanOutResult[I, O] {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	out := make([]FanOutResult[I, O], len(items))
+	wg := new(sync.WaitGroup)
+	sem := make(chan struct{}, concurrency)
+	for i, item := range items {
+		out[i].Item = item
+		select {
+		case <-ctx.Done():
+			out[i].Err = ctx.Err()
+			continue
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			out[i].Output, out[i].Err = fn(ctx, item)
+		}()
+	}
+	wg.Wait()
+	return out
+}
@@ -0,0 +1,56 @@
+package humanitec
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFanOut(t *testing.T) {
+	var inflight, maxInflight atomic.Int32
+	results := FanOutN(context.Background(), 3, []int{1, 2, 3, 4, 5, 6, 7, 8}, func(ctx context.Context, item int) (int, error) {
+		n := inflight.Add(1)
+		defer inflight.Add(-1)
+		for {
+			m := maxInflight.Load()
+			if n <= m || maxInflight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond * 5)
+		if item == 4 {
+			return 0, errors.New("boom")
+		}
+		return item * 10, nil
+	})
+	assert.LessOrEqual(t, maxInflight.Load(), int32(3))
+	assert.Len(t, results, 8)
+	for i, r := range results {
+		assert.Equal(t, i+1, r.Item)
+		if r.Item == 4 {
+			assert.EqualError(t, r.Err, "boom")
+		} else {
+			assert.NoError(t, r.Err)
+			assert.Equal(t, r.Item*10, r.Output)
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewRateLimiter(2, 2)
+	l.now = func() time.Time { return now }
+	assert.Equal(t, time.Duration(0), l.reserve())
+	assert.Equal(t, time.Duration(0), l.reserve())
+	assert.Equal(t, time.Millisecond*500, l.reserve())
+	now = now.Add(time.Millisecond * 500)
+	assert.Equal(t, time.Duration(0), l.reserve())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, l.Wait(ctx), context.Canceled)
+}
@@ -0,0 +1,110 @@
+package humanitec
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/humanitec/humanitec-go-autogen/client"
+)
+
+const (
+	// DefaultRequestsPerSecond is the sustained rate of requests that all tools in this process may send to Humanitec.
+	DefaultRequestsPerSecond = 20
+	// DefaultRequestBurst is the number of requests that can be sent at once before the sustained rate applies.
+	DefaultRequestBurst = 20
+)
+
+// RateLimiter is a simple token bucket limiter. Tokens are added continuously at Rate per second up to Burst.
+type RateLimiter struct {
+	Rate  float64
+	Burst int
+
+	lock   sync.Mutex
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{Rate: rate, Burst: burst, tokens: float64(burst), now: time.Now}
+}
+
+// reserve takes a token if one is available, otherwise it returns how long the caller must wait before trying again.
+func (l *RateLimiter) reserve() time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.Rate
+		if l.tokens > float64(l.Burst) {
+			l.tokens = float64(l.Burst)
+		}
+	}
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	if l.Rate <= 0 {
+		return time.Second
+	}
+	return time.Duration((1 - l.tokens) / l.Rate * float64(time.Second))
+}
+
+// Wait blocks until a token is available or the context is cancelled.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		d := l.reserve()
+		if d <= 0 {
+			return nil
+		}
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+var (
+	defaultRateLimiter     *RateLimiter
+	defaultRateLimiterOnce sync.Once
+)
+
+// DefaultRateLimiter returns the process-wide limiter shared by every Humanitec client. The rate and burst can be
+// overridden with the CANYON_HUMANITEC_RPS and CANYON_HUMANITEC_BURST environment variables.
+func DefaultRateLimiter() *RateLimiter {
+	defaultRateLimiterOnce.Do(func() {
+		rate, burst := float64(DefaultRequestsPerSecond), DefaultRequestBurst
+		if v, err := strconv.ParseFloat(os.Getenv("CANYON_HUMANITEC_RPS"), 64); err == nil && v > 0 {
+			rate = v
+		}
+		if v, err := strconv.Atoi(os.Getenv("CANYON_HUMANITEC_BURST")); err == nil && v > 0 {
+			burst = v
+		}
+		defaultRateLimiter = NewRateLimiter(rate, burst)
+	})
+	return defaultRateLimiter
+}
+
+// rateLimitedDoer waits for the limiter before passing each request to the inner doer.
+type rateLimitedDoer struct {
+	inner   client.HttpRequestDoer
+	limiter *RateLimiter
+}
+
+func (d *rateLimitedDoer) Do(req *http.Request) (*http.Response, error) {
+	if err := d.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return d.inner.Do(req)
+}
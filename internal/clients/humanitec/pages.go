@@ -0,0 +1,235 @@
+package humanitec
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+
+	"github.com/humanitec/humanitec-go-autogen/client"
+)
+
+// PageOptions controls how many items are collected by ListPages and where listing resumes.
+type PageOptions struct {
+	// Limit is the maximum number of items to return, 0 means no limit.
+	Limit int
+	// Cursor is an opaque value returned by a previous call to resume listing.
+	Cursor string
+	// PerPage is the page size requested from the API, 0 uses the API default.
+	PerPage int
+}
+
+// pageCursor is the decoded form of an opaque cursor. Query holds the query parameters of the next page link and Skip
+// is the number of items of that page that have already been returned.
+type pageCursor struct {
+	Query string `json:"q,omitempty"`
+	Skip  int    `json:"s,omitempty"`
+}
+
+func (c pageCursor) encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	var out pageCursor
+	if s == "" {
+		return out, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return out, fmt.Errorf("invalid cursor: %w", err)
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return out, fmt.Errorf("invalid cursor: %w", err)
+	}
+	return out, nil
+}
+
+// pageEditor returns a request editor that adds the query parameters of the given page.
+func pageEditor(query string, perPage int) client.RequestEditorFn {
+	return func(ctx context.Context, req *http.Request) error {
+		q := req.URL.Query()
+		if query != "" {
+			extra, err := url.ParseQuery(query)
+			if err != nil {
+				return fmt.Errorf("invalid cursor query: %w", err)
+			}
+			for k, vs := range extra {
+				q[k] = vs
+			}
+		}
+		if perPage > 0 && q.Get("per_page") == "" {
+			q.Set("per_page", strconv.Itoa(perPage))
+		}
+		req.URL.RawQuery = q.Encode()
+		return nil
+	}
+}
+
+var linkNextPattern = regexp.MustCompile(`<([^>]+)>\s*;[^,]*rel="?next"?`)
+
+// nextPageQuery returns the query of the next page from the Link header, or an empty string if this is the last page.
+// The Humanitec API returns the opaque page token of the next page only as the page parameter of this link, so
+// following the link also follows the page token, and the cursor keeps the whole query to resume from it.
+func nextPageQuery(resp *http.Response) string {
+	if resp == nil {
+		return ""
+	}
+	for _, link := range resp.Header.Values("Link") {
+		if m := linkNextPattern.FindStringSubmatch(link); m != nil {
+			if u, err := url.Parse(m[1]); err == nil && u.RawQuery != "" {
+				return u.RawQuery
+			}
+		}
+	}
+	return ""
+}
+
+// httpResponseOf returns the HTTPResponse field of a generated response struct.
+func httpResponseOf(r any) *http.Response {
+	v := reflect.ValueOf(r)
+	if !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		return nil
+	}
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if f := v.FieldByName("HTTPResponse"); f.IsValid() {
+		if hr, ok := f.Interface().(*http.Response); ok {
+			return hr
+		}
+	}
+	return nil
+}
+
+// PageCall fetches a single page. The editor must be passed to the generated client method so that the page
+// parameters are applied to the request.
+type PageCall[R checkableResponse] func(ctx context.Context, editor client.RequestEditorFn) (R, error)
+
+func fetchPage[R checkableResponse, T any](ctx context.Context, query string, perPage int, call PageCall[R], items func(R) []T) ([]T, string, error) {
+	r, err := CheckResponse(func() (R, error) {
+		return call(ctx, pageEditor(query, perPage))
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	if err != nil {
+		return nil, "", err
+	}
+	return items(r), nextPageQuery(httpResponseOf(r)), nil
+}
+
+// StreamPages yields every item across all pages starting at the cursor. Iteration stops at the first error.
+func StreamPages[R checkableResponse, T any](ctx context.Context, cursor string, perPage int, call PageCall[R], items func(R) []T) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		c, err := decodeCursor(cursor)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		for {
+			page, next, err := fetchPage(ctx, c.Query, perPage, call, items)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			for i := c.Skip; i < len(page); i++ {
+				if !yield(page[i], nil) {
+					return
+				}
+			}
+			if next == "" {
+				return
+			}
+			c = pageCursor{Query: next}
+		}
+	}
+}
+
+// ListPages collects items across pages until the limit is reached or there are no more pages. The returned cursor is
+// empty when all items have been returned, otherwise it can be passed back in PageOptions to continue.
+func ListPages[R checkableResponse, T any](ctx context.Context, opts PageOptions, call PageCall[R], items func(R) []T) ([]T, string, error) {
+	return ListMatchingPages(ctx, opts, call, items, nil)
+}
+
+// ListMatchingPages is ListPages for the items that match, so that the limit counts matching items and the cursor
+// resumes after the last one returned. A nil match matches every item.
+func ListMatchingPages[R checkableResponse, T any](ctx context.Context, opts PageOptions, call PageCall[R], items func(R) []T, match func(T) bool) ([]T, string, error) {
+	c, err := decodeCursor(opts.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	out := make([]T, 0)
+	for {
+		page, next, err := fetchPage(ctx, c.Query, opts.PerPage, call, items)
+		if err != nil {
+			return out, "", err
+		}
+		for i := min(c.Skip, len(page)); i < len(page); i++ {
+			if match != nil && !match(page[i]) {
+				continue
+			}
+			if opts.Limit > 0 && len(out) == opts.Limit {
+				return out, pageCursor{Query: c.Query, Skip: i}.encode(), nil
+			}
+			out = append(out, page[i])
+		}
+		if next == "" {
+			return out, "", nil
+		}
+		c = pageCursor{Query: next}
+		if opts.Limit > 0 && len(out) == opts.Limit {
+			return out, c.encode(), nil
+		}
+	}
+}
+
+// ListAllApplications returns the applications in the org across all pages.
+func (w *WrappedHumanitecClientImpl) ListAllApplications(ctx context.Context, orgId string, opts PageOptions) ([]client.ApplicationResponse, string, error) {
+	return ListPages(ctx, opts, func(ctx context.Context, editor client.RequestEditorFn) (*client.ListApplicationsResponse, error) {
+		return w.ListApplicationsWithResponse(ctx, orgId, editor)
+	}, func(r *client.ListApplicationsResponse) []client.ApplicationResponse {
+		return DerefSlice(r.JSON200)
+	})
+}
+
+// ListMatchingApplications returns the applications in the org that match across all pages.
+func (w *WrappedHumanitecClientImpl) ListMatchingApplications(ctx context.Context, orgId string, opts PageOptions, match func(client.ApplicationResponse) bool) ([]client.ApplicationResponse, string, error) {
+	return ListMatchingPages(ctx, opts, func(ctx context.Context, editor client.RequestEditorFn) (*client.ListApplicationsResponse, error) {
+		return w.ListApplicationsWithResponse(ctx, orgId, editor)
+	}, func(r *client.ListApplicationsResponse) []client.ApplicationResponse {
+		return DerefSlice(r.JSON200)
+	}, match)
+}
+
+// ListAllEnvironments returns the environments in the app across all pages.
+func (w *WrappedHumanitecClientImpl) ListAllEnvironments(ctx context.Context, orgId, appId string, opts PageOptions) ([]client.EnvironmentResponse, string, error) {
+	return ListPages(ctx, opts, func(ctx context.Context, editor client.RequestEditorFn) (*client.ListEnvironmentsResponse, error) {
+		return w.ListEnvironmentsWithResponse(ctx, orgId, appId, editor)
+	}, func(r *client.ListEnvironmentsResponse) []client.EnvironmentResponse {
+		return DerefSlice(r.JSON200)
+	})
+}
+
+// ListAllActionPipelineSummaries returns the action pipelines in the org across all pages.
+func (w *WrappedHumanitecClientImpl) ListAllActionPipelineSummaries(ctx context.Context, orgId string, opts PageOptions) ([]ActionPipelineSummary, string, error) {
+	return ListPages(ctx, opts, func(ctx context.Context, editor client.RequestEditorFn) (*ListActionPipelineSummariesResponse, error) {
+		return w.ListActionPipelineSummaries(ctx, orgId, editor)
+	}, func(r *ListActionPipelineSummariesResponse) []ActionPipelineSummary {
+		return r.JSON200
+	})
+}
+
+// DerefSlice returns the items of an optional list from a generated response, which is nil when the body was not the
+// expected json.
+func DerefSlice[T any](in *[]T) []T {
+	if in == nil {
+		return nil
+	}
+	return *in
+}
@@ -0,0 +1,81 @@
+package humanitec
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/humanitec/humanitec-go-autogen/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListPages(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set("Link", `<`+"http://"+r.Host+r.URL.Path+`?page=p2>; rel="next"`)
+			_, _ = w.Write([]byte(`[{"id":"a"},{"id":"b"},{"id":"c"}]`))
+		case "p2":
+			_, _ = w.Write([]byte(`[{"id":"d"},{"id":"e"}]`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	hc, err := client.NewClientWithResponses(srv.URL)
+	require.NoError(t, err)
+	w := &WrappedHumanitecClientImpl{ClientWithResponsesInterface: hc}
+
+	ids := func(apps []client.ApplicationResponse) (out []string) {
+		for _, a := range apps {
+			out = append(out, a.Id)
+		}
+		return
+	}
+
+	apps, cursor, err := w.ListAllApplications(context.Background(), "org", PageOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, ids(apps))
+	assert.Empty(t, cursor)
+
+	var seen []string
+	for app, err := range StreamPages(context.Background(), "", 0, func(ctx context.Context, editor client.RequestEditorFn) (*client.ListApplicationsResponse, error) {
+		return hc.ListApplicationsWithResponse(ctx, "org", editor)
+	}, func(r *client.ListApplicationsResponse) []client.ApplicationResponse {
+		return *r.JSON200
+	}) {
+		require.NoError(t, err)
+		seen = append(seen, app.Id)
+	}
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, seen)
+
+	var all []string
+	for i := 0; i < 10; i++ {
+		apps, cursor, err = w.ListAllApplications(context.Background(), "org", PageOptions{Limit: 2, Cursor: cursor})
+		require.NoError(t, err)
+		all = append(all, ids(apps)...)
+		if cursor == "" {
+			break
+		}
+	}
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, all)
+
+	// the limit counts matching items only
+	notC := func(a client.ApplicationResponse) bool { return a.Id != "c" }
+	apps, cursor, err = w.ListMatchingApplications(context.Background(), "org", PageOptions{Limit: 2}, notC)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, ids(apps))
+	require.NotEmpty(t, cursor)
+	apps, cursor, err = w.ListMatchingApplications(context.Background(), "org", PageOptions{Limit: 2, Cursor: cursor}, notC)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d", "e"}, ids(apps))
+	assert.Empty(t, cursor)
+
+	_, _, err = w.ListAllApplications(context.Background(), "org", PageOptions{Cursor: pageCursor{Query: "page=bad"}.encode()})
+	assert.Equal(t, ErrorKindValidation, ErrorKindOf(err), fmt.Sprint(err))
+}
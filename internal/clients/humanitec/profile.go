@@ -0,0 +1,229 @@
+package humanitec
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultApiPrefix is the Humanitec API used when neither the profile nor the environment specify one.
+const DefaultApiPrefix = "https://api.humanitec.io"
+
+// DefaultProfileName is the name of the implicit profile built from the environment and the humctl config.
+const DefaultProfileName = "default"
+
+// Profile describes a set of Humanitec credentials and defaults that the tools operate with.
+type Profile struct {
+	Name       string `yaml:"-" json:"name"`
+	Token      string `yaml:"token,omitempty" json:"-"`
+	TokenFile  string `yaml:"token_file,omitempty" json:"token_file,omitempty"`
+	ApiPrefix  string `yaml:"api_prefix,omitempty" json:"api_prefix,omitempty"`
+	DefaultOrg string `yaml:"default_org,omitempty" json:"default_org,omitempty"`
+	DefaultApp string `yaml:"default_app,omitempty" json:"default_app,omitempty"`
+	DefaultEnv string `yaml:"default_env,omitempty" json:"default_env,omitempty"`
+}
+
+// ProfilesConfig is the content of the canyon profiles file.
+type ProfilesConfig struct {
+	Current  string              `yaml:"current,omitempty"`
+	Profiles map[string]*Profile `yaml:"profiles"`
+}
+
+// humctlConfig is the subset of the ~/.humctl file that canyon understands.
+type humctlConfig struct {
+	Token string `yaml:"token"`
+	Org   string `yaml:"org"`
+	App   string `yaml:"app"`
+	Env   string `yaml:"env"`
+}
+
+// ProfilesFilePath returns the path of the canyon profiles file. This can be overridden with CANYON_PROFILES_FILE.
+func ProfilesFilePath() (string, error) {
+	if v := os.Getenv("CANYON_PROFILES_FILE"); v != "" {
+		return v, nil
+	}
+	hd, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to identify the users home directory: %w", err)
+	}
+	return filepath.Join(hd, ".canyon-profiles.yaml"), nil
+}
+
+// LoadProfilesConfig reads the canyon profiles file. A missing file results in an empty config.
+func LoadProfilesConfig() (*ProfilesConfig, error) {
+	out := &ProfilesConfig{Profiles: make(map[string]*Profile)}
+	p, err := ProfilesFilePath()
+	if err != nil {
+		// without a home directory there is no profiles file, only the implicit default profile
+		slog.Warn("ignoring the profiles file", slog.Any("err", err))
+		return out, nil
+	}
+	content, err := os.ReadFile(p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return out, nil
+		}
+		return nil, fmt.Errorf("failed to read the profiles file: %w", err)
+	}
+	if err := yaml.Unmarshal(content, out); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the profiles file '%s': %w", p, err)
+	}
+	if out.Profiles == nil {
+		out.Profiles = make(map[string]*Profile)
+	}
+	for name, profile := range out.Profiles {
+		if profile == nil {
+			profile = &Profile{}
+			out.Profiles[name] = profile
+		}
+		profile.Name = name
+	}
+	return out, nil
+}
+
+// ProfileNames returns the sorted names of all available profiles including the implicit default profile.
+func (c *ProfilesConfig) ProfileNames() []string {
+	out := []string{DefaultProfileName}
+	for name := range c.Profiles {
+		if name != DefaultProfileName {
+			out = append(out, name)
+		}
+	}
+	slices.Sort(out[1:])
+	return out
+}
+
+func readHumctlConfig() (*humctlConfig, error) {
+	hd, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to identify the users home directory: %w", err)
+	}
+	out := &humctlConfig{}
+	if content, err := os.ReadFile(filepath.Join(hd, ".humctl")); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return out, nil
+		}
+		return nil, fmt.Errorf("failed to read the humctl file: %w", err)
+	} else if err := yaml.Unmarshal(content, out); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the humctl file: %w", err)
+	}
+	return out, nil
+}
+
+// defaultProfile builds the implicit profile from the HUMANITEC_* environment variables and the humctl config file,
+// in that order of precedence. The humctl file is only read when a variable is missing, and a file that cannot be
+// read is ignored with a warning so that it never breaks credentials given in the environment.
+func defaultProfile() *Profile {
+	hc := &humctlConfig{}
+	for _, key := range []string{"HUMANITEC_TOKEN", "HUMANITEC_ORG", "HUMANITEC_APP", "HUMANITEC_ENV"} {
+		if os.Getenv(key) == "" {
+			if c, err := readHumctlConfig(); err != nil {
+				slog.Warn("ignoring the humctl config", slog.Any("err", err))
+			} else {
+				hc = c
+			}
+			break
+		}
+	}
+	env := func(key, fallback string) string {
+		if v := os.Getenv(key); v != "" {
+			return v
+		}
+		return fallback
+	}
+	return &Profile{
+		Name:       DefaultProfileName,
+		Token:      env("HUMANITEC_TOKEN", hc.Token),
+		ApiPrefix:  env("HUMANITEC_API_PREFIX", DefaultApiPrefix),
+		DefaultOrg: env("HUMANITEC_ORG", hc.Org),
+		DefaultApp: env("HUMANITEC_APP", hc.App),
+		DefaultEnv: env("HUMANITEC_ENV", hc.Env),
+	}
+}
+
+// ResolveToken returns the token of the profile, reading it from the token file if necessary.
+func (p *Profile) ResolveToken() (string, error) {
+	if p.Token != "" {
+		return p.Token, nil
+	}
+	if p.TokenFile != "" {
+		tf := p.TokenFile
+		if strings.HasPrefix(tf, "~/") {
+			if hd, err := os.UserHomeDir(); err == nil {
+				tf = filepath.Join(hd, tf[2:])
+			}
+		}
+		raw, err := os.ReadFile(tf)
+		if err != nil {
+			return "", fmt.Errorf("failed to read the token file of profile '%s': %w", p.Name, err)
+		}
+		return strings.TrimSpace(string(raw)), nil
+	}
+	return "", nil
+}
+
+var (
+	activeProfileName string
+	activeProfileLock sync.Mutex
+)
+
+// SetActiveProfile selects the named profile for all subsequent Humanitec clients in this process. An empty name
+// selects the profile marked as current in the profiles file, or the implicit default profile.
+func SetActiveProfile(name string) error {
+	if name != "" {
+		if _, err := LoadProfile(name); err != nil {
+			return err
+		}
+	}
+	activeProfileLock.Lock()
+	defer activeProfileLock.Unlock()
+	activeProfileName = name
+	return nil
+}
+
+// LoadProfile returns the profile with the given name. The empty name resolves the active profile.
+func LoadProfile(name string) (*Profile, error) {
+	cfg, err := LoadProfilesConfig()
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = cfg.Current
+	}
+	if name == "" || (name == DefaultProfileName && cfg.Profiles[name] == nil) {
+		return defaultProfile(), nil
+	}
+	p, ok := cfg.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("the profile '%s' does not exist, available profiles are: %s", name, strings.Join(cfg.ProfileNames(), ", "))
+	}
+	out := *p
+	if out.ApiPrefix == "" {
+		out.ApiPrefix = DefaultApiPrefix
+	}
+	return &out, nil
+}
+
+// ActiveProfile returns the profile selected with SetActiveProfile.
+func ActiveProfile() (*Profile, error) {
+	activeProfileLock.Lock()
+	name := activeProfileName
+	activeProfileLock.Unlock()
+	return LoadProfile(name)
+}
+
+// GetCurrentHumanitecToken returns the token of the active profile or an empty string if there is none.
+func GetCurrentHumanitecToken() (string, error) {
+	p, err := ActiveProfile()
+	if err != nil {
+		return "", err
+	}
+	return p.ResolveToken()
+}
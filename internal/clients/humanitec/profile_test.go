@@ -0,0 +1,83 @@
+package humanitec
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("HUMANITEC_TOKEN", "")
+	t.Setenv("HUMANITEC_ORG", "")
+	t.Setenv("HUMANITEC_API_PREFIX", "")
+	t.Setenv("CANYON_PROFILES_FILE", filepath.Join(home, "profiles.yaml"))
+	t.Cleanup(func() { _ = SetActiveProfile("") })
+
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".humctl"), []byte("token: humctl-token\norg: humctl-org\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(home, "token"), []byte("file-token\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(home, "profiles.yaml"), []byte(`
+profiles:
+  customer-a:
+    token_file: ~/token
+    api_prefix: https://a.example.com
+    default_org: org-a
+`), 0600))
+
+	p, err := ActiveProfile()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultProfileName, p.Name)
+	assert.Equal(t, "humctl-org", p.DefaultOrg)
+	assert.Equal(t, DefaultApiPrefix, p.ApiPrefix)
+	token, _ := GetCurrentHumanitecToken()
+	assert.Equal(t, "humctl-token", token)
+
+	t.Setenv("HUMANITEC_TOKEN", "env-token")
+	token, _ = GetCurrentHumanitecToken()
+	assert.Equal(t, "env-token", token)
+
+	require.NoError(t, SetActiveProfile("customer-a"))
+	p, err = ActiveProfile()
+	require.NoError(t, err)
+	assert.Equal(t, "org-a", p.DefaultOrg)
+	assert.Equal(t, "https://a.example.com", p.ApiPrefix)
+	token, _ = GetCurrentHumanitecToken()
+	assert.Equal(t, "file-token", token)
+
+	assert.EqualError(t, SetActiveProfile("unknown"), "the profile 'unknown' does not exist, available profiles are: default, customer-a")
+}
+
+func TestDefaultProfile_humctl(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("CANYON_PROFILES_FILE", filepath.Join(home, "profiles.yaml"))
+	t.Setenv("HUMANITEC_TOKEN", "")
+	t.Setenv("HUMANITEC_ORG", "")
+	t.Setenv("HUMANITEC_APP", "")
+	t.Setenv("HUMANITEC_ENV", "env-from-env")
+
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".humctl"), []byte("token: humctl-token\norg: humctl-org\napp: humctl-app\nenv: humctl-env\n"), 0600))
+	p, err := LoadProfile("")
+	require.NoError(t, err)
+	assert.Equal(t, "humctl-app", p.DefaultApp)
+	assert.Equal(t, "env-from-env", p.DefaultEnv)
+
+	// a broken humctl file does not break credentials from the environment
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".humctl"), []byte("token: [\n"), 0600))
+	t.Setenv("HUMANITEC_TOKEN", "env-token")
+	p, err = LoadProfile("")
+	require.NoError(t, err)
+	assert.Equal(t, "env-token", p.Token)
+	assert.Empty(t, p.DefaultOrg)
+
+	// without a home directory the environment is enough
+	t.Setenv("HOME", "")
+	t.Setenv("CANYON_PROFILES_FILE", "")
+	p, err = LoadProfile("")
+	require.NoError(t, err)
+	assert.Equal(t, "env-token", p.Token)
+}
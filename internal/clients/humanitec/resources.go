@@ -0,0 +1,35 @@
+package humanitec
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/humanitec/humanitec-go-autogen/client"
+)
+
+// ListActiveResources returns the resources currently provisioned in an environment.
+func (w *WrappedHumanitecClientImpl) ListActiveResources(ctx context.Context, orgId, appId, envId string) ([]client.ActiveResourceResponse, error) {
+	r, err := CheckResponse(func() (*client.ListActiveResourcesResponse, error) {
+		return w.ListActiveResourcesWithResponse(ctx, orgId, appId, envId, &client.ListActiveResourcesParams{})
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	if err != nil {
+		return nil, err
+	}
+	return DerefSlice(r.JSON200), nil
+}
+
+// QueryActiveResourceGraph returns the dependency graph of the given active resources. Nodes reference the resources
+// they depend on by their globally unique resource id.
+func (w *WrappedHumanitecClientImpl) QueryActiveResourceGraph(ctx context.Context, orgId, appId, envId string, resources []client.ActiveResourceResponse) ([]client.NodeBodyResponse, error) {
+	body := make(client.QueryResourceGraphJSONRequestBody, 0, len(resources))
+	for _, res := range resources {
+		body = append(body, client.ResourceProvisionRequestRequest{Id: res.ResId, Type: res.Type, Class: &res.Class})
+	}
+	r, err := CheckResponse(func() (*client.QueryResourceGraphResponse, error) {
+		return w.QueryResourceGraphWithResponse(ctx, orgId, appId, envId, body)
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	if err != nil {
+		return nil, err
+	}
+	return DerefSlice(r.JSON200), nil
+}
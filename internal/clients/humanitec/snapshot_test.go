@@ -0,0 +1,98 @@
+package humanitec
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/humanitec/humanitec-go-autogen/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec/fakeapi"
+)
+
+func TestSnapshotExportAndServe(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("CANYON_PROFILES_FILE", filepath.Join(home, "profiles.yaml"))
+	t.Setenv("HUMANITEC_TOKEN", "")
+
+	hc, err := NewHumanitecClientWithCurrentToken(WithHttpRequestDoer(context.Background(), fakeapi.New(fakeapi.DefaultFixture())))
+	require.NoError(t, err)
+	exported, err := ExportSnapshot(context.Background(), hc, "canyon-demo", SnapshotOptions{MaxDeploys: 2})
+	require.NoError(t, err)
+	assert.Empty(t, exported.Manifest.Warnings)
+	assert.Equal(t, SnapshotVersion, exported.Manifest.Version)
+	assert.Contains(t, exported.Responses, "/orgs/canyon-demo/apps/frontend/envs/development/deploys/17e3c2a9f1b04d47")
+	assert.NotContains(t, exported.Responses, "/orgs/canyon-demo/apps/frontend/envs/development/deploys/17e3c2a9f1b04d33")
+	assert.Contains(t, string(exported.Responses["/orgs/canyon-demo/apps/frontend/envs/development/deploys/17e3c2a9f1b04d47/errors"]), `"code":"DNS-001"`)
+	assert.Contains(t, exported.Responses, "/orgs/canyon-demo/resources/defs/postgres-dev")
+	assert.Contains(t, string(exported.Responses["/orgs/canyon-demo/apps/backend/envs/development/resources"]), `"status":"pending"`)
+	assert.NotContains(t, exported.Responses, "/orgs/canyon-demo/apps/backend/envs/staging/resources")
+	assert.Contains(t, string(exported.Responses["/orgs/canyon-demo/apps/frontend/pipelines/promote/schema"]), `"name: Promote to production\n`)
+	assert.Contains(t, exported.Responses, "/orgs/canyon-demo/apps/frontend/pipelines/promote/runs/5b2d8e61a9c04f17/jobs/promote/steps/0/logs")
+	assert.Contains(t, exported.Responses, "/orgs/canyon-demo/apps/frontend/approvals")
+
+	buff := new(bytes.Buffer)
+	require.NoError(t, exported.WriteArchive(buff))
+	snapshot, err := ReadSnapshotArchive(bytes.NewReader(buff.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, exported.Manifest.Org, snapshot.Manifest.Org)
+	assert.Equal(t, exported.Responses, snapshot.Responses)
+
+	ctx := WithHttpRequestDoer(context.Background(), snapshot)
+	hc, err = NewHumanitecClientWithCurrentToken(ctx)
+	require.NoError(t, err)
+	assert.True(t, hc.Offline())
+
+	apps, _, err := hc.ListAllApplications(ctx, "canyon-demo", PageOptions{Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "frontend", apps[0].Id)
+
+	resources, err := hc.ListActiveResources(ctx, "canyon-demo", "frontend", "production")
+	require.NoError(t, err)
+	graph, err := hc.QueryActiveResourceGraph(ctx, "canyon-demo", "frontend", "production", resources[:1])
+	require.NoError(t, err)
+	assert.Len(t, graph, 4)
+
+	profile, err := CheckResponse(func() (*client.GetWorkloadProfileResponse, error) {
+		return hc.GetWorkloadProfileWithResponse(ctx, "canyon-demo", "humanitec/default-cronjob")
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	require.NoError(t, err)
+	assert.Equal(t, "humanitec/default-cronjob", profile.JSON200.Id)
+
+	_, err = CheckResponse(func() (*client.GetSetResponse, error) {
+		return hc.GetSetWithResponse(ctx, "canyon-demo", "frontend", "unknown", &client.GetSetParams{})
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	assert.Equal(t, ErrorKindNotFound, ErrorKindOf(err), err)
+
+	definition, err := CheckResponse(func() (*client.GetPipelineDefinitionResponse, error) {
+		accept := PipelineDefinitionMediaType
+		return hc.GetPipelineDefinitionWithResponse(ctx, "canyon-demo", "frontend", "promote", &client.GetPipelineDefinitionParams{Accept: &accept})
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	require.NoError(t, err)
+	assert.Equal(t, PipelineDefinitionMediaType, definition.HTTPResponse.Header.Get("Content-Type"))
+	assert.Contains(t, string(definition.Body), "name: Promote to production\n")
+
+	r, err := hc.CallActionPipeline(ctx, "canyon-demo", "get-workload-owner", nil, CallActionPipelineRequestBody{})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusMethodNotAllowed, r.StatusCode())
+}
+
+func TestReadSnapshotArchive_version(t *testing.T) {
+	buff := new(bytes.Buffer)
+	gw := gzip.NewWriter(buff)
+	tw := tar.NewWriter(gw)
+	content := []byte(`{"version": 99, "org": "x"}`)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "manifest.json", Mode: 0600, Size: int64(len(content))}))
+	_, _ = tw.Write(content)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	_, err := ReadSnapshotArchive(buff)
+	assert.EqualError(t, err, "unsupported snapshot version 99, this version of canyon supports up to version 1")
+}
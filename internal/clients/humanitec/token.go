@@ -0,0 +1,86 @@
+package humanitec
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenExpiryWarningPeriod is how long before the expiry of a token that the user should be warned about it.
+const TokenExpiryWarningPeriod = time.Hour * 24
+
+const (
+	TokenTypeNone   = "none"
+	TokenTypeJwt    = "jwt"
+	TokenTypeOpaque = "opaque"
+)
+
+// TokenInfo is what can be learnt about a token without calling the API. Only JWT-style tokens carry an expiry and
+// subject, other tokens such as long-lived service user tokens are opaque.
+type TokenInfo struct {
+	Type      string     `json:"type"`
+	Subject   string     `json:"subject,omitempty"`
+	Issuer    string     `json:"issuer,omitempty"`
+	IssuedAt  *time.Time `json:"issued_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// InspectToken decodes the claims of a JWT-style token without verifying the signature.
+func InspectToken(token string) TokenInfo {
+	token = strings.TrimSpace(strings.TrimPrefix(token, "Bearer "))
+	if token == "" {
+		return TokenInfo{Type: TokenTypeNone}
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return TokenInfo{Type: TokenTypeOpaque}
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return TokenInfo{Type: TokenTypeOpaque}
+	}
+	claims := struct {
+		Subject   string      `json:"sub"`
+		Issuer    string      `json:"iss"`
+		IssuedAt  json.Number `json:"iat"`
+		ExpiresAt json.Number `json:"exp"`
+	}{}
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		return TokenInfo{Type: TokenTypeOpaque}
+	}
+	out := TokenInfo{Type: TokenTypeJwt, Subject: claims.Subject, Issuer: claims.Issuer}
+	if v, err := claims.IssuedAt.Float64(); err == nil && v > 0 {
+		t := time.Unix(int64(v), 0).UTC()
+		out.IssuedAt = &t
+	}
+	if v, err := claims.ExpiresAt.Float64(); err == nil && v > 0 {
+		t := time.Unix(int64(v), 0).UTC()
+		out.ExpiresAt = &t
+	}
+	return out
+}
+
+// IsExpired returns true if the token has a known expiry that is before now.
+func (t TokenInfo) IsExpired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
+
+// ExpiresWithin returns true if the token has a known expiry within the given duration of now.
+func (t TokenInfo) ExpiresWithin(d time.Duration, now time.Time) bool {
+	return t.ExpiresAt != nil && now.Add(d).After(*t.ExpiresAt)
+}
+
+var warnedExpiringTokens sync.Map
+
+// warnIfExpiringSoon logs a warning once per token when it is close to expiry.
+func warnIfExpiringSoon(token string, info TokenInfo) {
+	if info.IsExpired(time.Now()) || !info.ExpiresWithin(TokenExpiryWarningPeriod, time.Now()) {
+		return
+	}
+	if _, loaded := warnedExpiringTokens.LoadOrStore(hashString(token), true); !loaded {
+		slog.Warn("the humanitec token expires soon, run 'humctl login' to renew it", slog.Time("expires_at", *info.ExpiresAt))
+	}
+}
@@ -0,0 +1,54 @@
+package humanitec
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func fakeJwt(claims string) string {
+	return "eyJhbGciOiJub25lIn0." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".sig"
+}
+
+func TestInspectToken(t *testing.T) {
+	assert.Equal(t, TokenInfo{Type: TokenTypeNone}, InspectToken(""))
+	assert.Equal(t, TokenInfo{Type: TokenTypeOpaque}, InspectToken("abcdef"))
+
+	info := InspectToken("Bearer " + fakeJwt(`{"sub":"user-1","exp":1700000000}`))
+	assert.Equal(t, TokenTypeJwt, info.Type)
+	assert.Equal(t, "user-1", info.Subject)
+	assert.Equal(t, time.Unix(1700000000, 0).UTC(), *info.ExpiresAt)
+	assert.True(t, info.IsExpired(time.Unix(1700000001, 0)))
+	assert.False(t, info.IsExpired(time.Unix(1699999999, 0)))
+	assert.True(t, info.ExpiresWithin(time.Hour, time.Unix(1699999999, 0)))
+}
+
+type fakeResponse struct {
+	HTTPResponse *http.Response
+	Body         []byte
+}
+
+func (f *fakeResponse) StatusCode() int {
+	return f.HTTPResponse.StatusCode
+}
+
+func TestAndStatusCodeEq_auth(t *testing.T) {
+	respWithToken := func(code int, token string) *fakeResponse {
+		req, _ := http.NewRequest(http.MethodGet, "https://example.com", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		return &fakeResponse{HTTPResponse: &http.Response{StatusCode: code, Request: req}}
+	}
+	check := func(r *fakeResponse) error {
+		_, err := CheckResponse(func() (*fakeResponse, error) { return r, nil }).AndStatusCodeEq(http.StatusOK).RespAndError()
+		return err
+	}
+
+	expired := fakeJwt(`{"exp":1000}`)
+	assert.True(t, strings.Contains(check(respWithToken(http.StatusForbidden, expired)).Error(), "session expired at 1970-01-01T00:16:40Z"))
+	assert.True(t, strings.Contains(check(respWithToken(http.StatusUnauthorized, "opaque")).Error(), "401 (Unauthorized)"))
+	assert.True(t, strings.Contains(check(respWithToken(http.StatusForbidden, "opaque")).Error(), "403 (Forbidden)"))
+}
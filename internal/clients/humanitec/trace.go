@@ -0,0 +1,185 @@
+package humanitec
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/humanitec/humanitec-go-autogen/client"
+
+	"github.com/humanitec/canyon-cli/internal/rpc"
+)
+
+// maxTracedBodyLength is the number of body bytes included in a trace record before it is truncated.
+const maxTracedBodyLength = 4096
+
+const redacted = "REDACTED"
+
+// requestIdHeaders are response headers that identify the request in the Humanitec API logs.
+var requestIdHeaders = []string{"X-Request-Id", "Humanitec-Request-Id", "X-Amzn-Trace-Id", "Traceparent"}
+
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+var (
+	sensitiveKeyPattern   = regexp.MustCompile(`(?i)(token|secret|password|passwd|credential|private_?key|api_?key|authorization)`)
+	sensitiveValuePattern = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`)
+)
+
+// TraceOptions controls the http tracing of Humanitec requests.
+type TraceOptions struct {
+	Enabled bool
+	Bodies  bool
+}
+
+var (
+	traceOptions     TraceOptions
+	traceOptionsLock sync.Mutex
+)
+
+// ConfigureHttpTracing enables or disables tracing for all Humanitec clients created afterwards.
+func ConfigureHttpTracing(opts TraceOptions) {
+	traceOptionsLock.Lock()
+	defer traceOptionsLock.Unlock()
+	traceOptions = opts
+}
+
+func currentTraceOptions() TraceOptions {
+	traceOptionsLock.Lock()
+	defer traceOptionsLock.Unlock()
+	return traceOptions
+}
+
+// redactJson replaces the values of sensitive looking keys throughout a decoded json document.
+func redactJson(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for k, item := range x {
+			if sensitiveKeyPattern.MatchString(k) {
+				x[k] = redactAll(item)
+			} else {
+				x[k] = redactJson(item)
+			}
+		}
+		return x
+	case []interface{}:
+		for i, item := range x {
+			x[i] = redactJson(item)
+		}
+		return x
+	case string:
+		return sensitiveValuePattern.ReplaceAllString(x, "${1}"+redacted)
+	default:
+		return v
+	}
+}
+
+// redactAll replaces every value in a sensitive part of a document while keeping objects and arrays so that the document
+// still decodes into the same types.
+func redactAll(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for k, item := range x {
+			x[k] = redactAll(item)
+		}
+		return x
+	case []interface{}:
+		for i, item := range x {
+			x[i] = redactAll(item)
+		}
+		return x
+	default:
+		return redacted
+	}
+}
+
+// RedactBody returns a printable form of the body with secrets removed and the length limited.
+func RedactBody(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	var out string
+	var decoded interface{}
+	if err := json.Unmarshal(body, &decoded); err == nil {
+		raw, _ := json.Marshal(redactJson(decoded))
+		out = string(raw)
+	} else {
+		out = sensitiveValuePattern.ReplaceAllString(string(body), "${1}"+redacted)
+	}
+	if len(out) > maxTracedBodyLength {
+		out = out[:maxTracedBodyLength] + "...(truncated)"
+	}
+	return out
+}
+
+// RedactHeaders returns a copy of the headers with credentials removed.
+func RedactHeaders(h http.Header) http.Header {
+	out := h.Clone()
+	for _, k := range sensitiveHeaders {
+		if out.Get(k) != "" {
+			out.Set(k, redacted)
+		}
+	}
+	return out
+}
+
+// tracingDoer logs a record for every request and response.
+type tracingDoer struct {
+	inner  client.HttpRequestDoer
+	bodies bool
+}
+
+func (d *tracingDoer) Do(req *http.Request) (*http.Response, error) {
+	attrs := []any{
+		slog.String("method", req.Method),
+		slog.String("url", req.URL.String()),
+	}
+	if id, ok := rpc.GetRequestId(req.Context()); ok {
+		attrs = append(attrs, slog.Int("rpc_id", id))
+	}
+	if d.bodies {
+		attrs = append(attrs, slog.Any("request_headers", RedactHeaders(req.Header)))
+		if req.Body != nil {
+			raw, err := io.ReadAll(req.Body)
+			_ = req.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = io.NopCloser(bytes.NewReader(raw))
+			attrs = append(attrs, slog.String("request_body", RedactBody(raw)))
+		}
+	}
+
+	start := time.Now()
+	resp, err := d.inner.Do(req)
+	attrs = append(attrs, slog.Duration("latency", time.Since(start)))
+	if err != nil {
+		slog.Info("http trace", append(attrs, slog.String("err", err.Error()))...)
+		return resp, err
+	}
+	attrs = append(attrs, slog.Int("status", resp.StatusCode))
+	for _, h := range requestIdHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			attrs = append(attrs, slog.String("request_id", v))
+			break
+		}
+	}
+	if v := resp.Header.Get(CacheStatusHeader); v != "" {
+		attrs = append(attrs, slog.String("cache", v))
+	}
+	if d.bodies && resp.Body != nil {
+		raw, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(raw))
+		attrs = append(attrs, slog.String("response_body", RedactBody(raw)))
+	}
+	slog.Info("http trace", attrs...)
+	return resp, nil
+}
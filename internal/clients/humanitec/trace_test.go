@@ -0,0 +1,74 @@
+package humanitec
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/humanitec/canyon-cli/internal/rpc"
+)
+
+func TestRedactBody(t *testing.T) {
+	assert.Equal(t, `{"inputs":{"db_password":"REDACTED","name":"x"},"token":"REDACTED"}`, RedactBody([]byte(`{"token":"abc","inputs":{"name":"x","db_password":"p"}}`)))
+	assert.Equal(t, `{"secret_refs":{"db":{"password":"REDACTED"}}}`, RedactBody([]byte(`{"secret_refs":{"db":{"password":"p"}}}`)))
+	assert.Equal(t, `Authorization: Bearer REDACTED`, RedactBody([]byte(`Authorization: Bearer abc.def`)))
+	assert.Equal(t, "", RedactBody(nil))
+}
+
+func TestTracingDoer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-1")
+		_, _ = w.Write([]byte(`{"secret":"s","ok":true}`))
+	}))
+	defer srv.Close()
+
+	buff := new(bytes.Buffer)
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(buff, nil)))
+	defer slog.SetDefault(prev)
+
+	ctx := context.WithValue(context.Background(), rpc.RequestIdKey, 42)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL+"/orgs/a", strings.NewReader(`{"token":"t"}`))
+	req.Header.Set("Authorization", "Bearer top-secret")
+	resp, err := (&tracingDoer{inner: http.DefaultClient, bodies: true}).Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	out := buff.String()
+	assert.Contains(t, out, "method=POST")
+	assert.Contains(t, out, "status=200")
+	assert.Contains(t, out, "rpc_id=42")
+	assert.Contains(t, out, "request_id=req-1")
+	assert.Contains(t, out, `{\"ok\":true,\"secret\":\"REDACTED\"}`)
+	assert.NotContains(t, out, "top-secret")
+}
+
+func TestTracingDoer_nestedSecrets(t *testing.T) {
+	// active resources carry their secrets as objects, every value below a sensitive key is redacted while the
+	// document keeps its shape
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"res_id":"db","secrets":{"password":"p","hosts":["h1"],"tls":{"key":"k"}}}]`))
+	}))
+	defer srv.Close()
+
+	buff := new(bytes.Buffer)
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(buff, nil)))
+	defer slog.SetDefault(prev)
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/orgs/a/apps/b/envs/c/resources", nil)
+	resp, err := (&tracingDoer{inner: http.DefaultClient, bodies: true}).Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	out := buff.String()
+	assert.Contains(t, out, `[{\"res_id\":\"db\",\"secrets\":{\"hosts\":[\"REDACTED\"],\"password\":\"REDACTED\",\"tls\":{\"key\":\"REDACTED\"}}}]`)
+	assert.NotContains(t, out, `\"h1\"`)
+}
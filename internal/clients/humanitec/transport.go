@@ -0,0 +1,182 @@
+package humanitec
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/humanitec/humanitec-go-autogen/client"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	DefaultConnectTimeout = time.Second * 10
+	DefaultRequestTimeout = time.Minute * 2
+)
+
+// TransportConfig controls how HTTP requests to Humanitec are sent. It is read from the transport config file and
+// can be overridden with CANYON_HTTP_* environment variables.
+type TransportConfig struct {
+	// ConnectTimeout is the maximum time to establish a connection including the TLS handshake.
+	ConnectTimeout time.Duration `yaml:"connect_timeout,omitempty"`
+	// Timeout is the maximum time for a whole request including reading the response body.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// ProxyUrl is the proxy to send requests through. When empty, the standard HTTPS_PROXY and NO_PROXY environment
+	// variables apply.
+	ProxyUrl string `yaml:"proxy_url,omitempty"`
+	// CaFiles are PEM bundles of additional certificate authorities to trust alongside the system pool.
+	CaFiles []string `yaml:"ca_files,omitempty"`
+	// ClientCertFile and ClientKeyFile are the PEM encoded client certificate and key used for mTLS.
+	ClientCertFile string `yaml:"client_cert_file,omitempty"`
+	ClientKeyFile  string `yaml:"client_key_file,omitempty"`
+	// DisableHttp2 forces HTTP/1.1 for proxies or gateways that do not support HTTP/2.
+	DisableHttp2 bool `yaml:"disable_http2,omitempty"`
+}
+
+// TransportConfigFilePath returns the path of the transport config file. This can be overridden with
+// CANYON_TRANSPORT_FILE.
+func TransportConfigFilePath() (string, error) {
+	if v := os.Getenv("CANYON_TRANSPORT_FILE"); v != "" {
+		return v, nil
+	}
+	hd, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to identify the users home directory: %w", err)
+	}
+	return filepath.Join(hd, ".canyon-transport.yaml"), nil
+}
+
+// LoadTransportConfig reads the transport config file and applies the environment variable overrides.
+func LoadTransportConfig() (*TransportConfig, error) {
+	out := &TransportConfig{ConnectTimeout: DefaultConnectTimeout, Timeout: DefaultRequestTimeout}
+	p, err := TransportConfigFilePath()
+	if err != nil {
+		return nil, err
+	}
+	if content, err := os.ReadFile(p); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read the transport config file: %w", err)
+		}
+	} else if err := yaml.Unmarshal(content, out); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the transport config file '%s': %w", p, err)
+	}
+
+	for key, target := range map[string]*time.Duration{
+		"CANYON_HTTP_CONNECT_TIMEOUT": &out.ConnectTimeout,
+		"CANYON_HTTP_TIMEOUT":         &out.Timeout,
+	} {
+		if v := os.Getenv(key); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", key, err)
+			}
+			*target = d
+		}
+	}
+	if v := os.Getenv("CANYON_HTTP_PROXY"); v != "" {
+		out.ProxyUrl = v
+	}
+	if v := os.Getenv("CANYON_HTTP_CA_FILES"); v != "" {
+		out.CaFiles = append(out.CaFiles, filepath.SplitList(v)...)
+	}
+	if v := os.Getenv("CANYON_HTTP_CLIENT_CERT_FILE"); v != "" {
+		out.ClientCertFile = v
+	}
+	if v := os.Getenv("CANYON_HTTP_CLIENT_KEY_FILE"); v != "" {
+		out.ClientKeyFile = v
+	}
+	if v := os.Getenv("CANYON_HTTP2"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CANYON_HTTP2: %w", err)
+		}
+		out.DisableHttp2 = !b
+	}
+	return out, nil
+}
+
+// NewHttpClient builds an http client from the config.
+func (c *TransportConfig) NewHttpClient() (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: c.ConnectTimeout, KeepAlive: time.Second * 30}).DialContext
+	transport.TLSHandshakeTimeout = c.ConnectTimeout
+
+	if c.ProxyUrl != "" {
+		u, err := url.Parse(c.ProxyUrl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if len(c.CaFiles) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		for _, f := range c.CaFiles {
+			raw, err := os.ReadFile(strings.TrimSpace(f))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read ca file: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(raw) {
+				return nil, fmt.Errorf("no PEM certificates found in ca file '%s'", f)
+			}
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if c.ClientCertFile != "" || c.ClientKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.ClientCertFile, c.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	if c.DisableHttp2 {
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = make(map[string]func(authority string, c *tls.Conn) http.RoundTripper)
+	} else {
+		transport.ForceAttemptHTTP2 = true
+	}
+	return &http.Client{Transport: transport, Timeout: c.Timeout}, nil
+}
+
+var (
+	defaultHttpClient     *http.Client
+	defaultHttpClientErr  error
+	defaultHttpClientOnce sync.Once
+)
+
+// DefaultHttpClient returns the process-wide http client built from LoadTransportConfig.
+func DefaultHttpClient() (*http.Client, error) {
+	defaultHttpClientOnce.Do(func() {
+		var cfg *TransportConfig
+		if cfg, defaultHttpClientErr = LoadTransportConfig(); defaultHttpClientErr == nil {
+			defaultHttpClient, defaultHttpClientErr = cfg.NewHttpClient()
+		}
+		if defaultHttpClientErr != nil {
+			defaultHttpClientErr = fmt.Errorf("invalid http transport configuration: %w", defaultHttpClientErr)
+		}
+	})
+	return defaultHttpClient, defaultHttpClientErr
+}
+
+// WithHttpRequestDoer returns a context in which new Humanitec clients send their requests through the given doer
+// instead of the default http client. This is useful for tests and for recording traffic.
+func WithHttpRequestDoer(ctx context.Context, doer client.HttpRequestDoer) context.Context {
+	return context.WithValue(ctx, overrideHumanitecClientKey, doer)
+}
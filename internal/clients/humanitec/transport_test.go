@@ -0,0 +1,55 @@
+package humanitec
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransportConfig(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0600))
+	cfgFile := filepath.Join(dir, "transport.yaml")
+	require.NoError(t, os.WriteFile(cfgFile, []byte("timeout: 5s\ndisable_http2: true\n"), 0600))
+	t.Setenv("CANYON_TRANSPORT_FILE", cfgFile)
+	t.Setenv("CANYON_HTTP_CONNECT_TIMEOUT", "3s")
+	t.Setenv("CANYON_HTTP_CA_FILES", caFile)
+
+	cfg, err := LoadTransportConfig()
+	require.NoError(t, err)
+	assert.Equal(t, time.Second*5, cfg.Timeout)
+	assert.Equal(t, time.Second*3, cfg.ConnectTimeout)
+	assert.True(t, cfg.DisableHttp2)
+	assert.Equal(t, []string{caFile}, cfg.CaFiles)
+
+	c, err := cfg.NewHttpClient()
+	require.NoError(t, err)
+	resp, err := c.Get(srv.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	// without the extra ca the server is not trusted
+	cfg.CaFiles = nil
+	c, err = cfg.NewHttpClient()
+	require.NoError(t, err)
+	_, err = c.Get(srv.URL)
+	assert.Error(t, err)
+
+	cfg.ClientCertFile = filepath.Join(dir, "missing.pem")
+	_, err = cfg.NewHttpClient()
+	assert.ErrorContains(t, err, "failed to load client certificate")
+}
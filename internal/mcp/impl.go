@@ -2,6 +2,9 @@ package mcp
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"path/filepath"
 	"runtime/debug"
 	"slices"
@@ -14,7 +17,8 @@ type Impl struct {
 	Instructions string
 	Tools        []Tool
 
-	lock sync.Mutex
+	lock  sync.Mutex
+	roots clientRoots
 }
 
 var _ McpIo = (*Impl)(nil)
@@ -44,6 +48,8 @@ func (m *Impl) ListResources(ctx context.Context, request ListResourcesRequest)
 }
 
 func (m *Impl) Initialize(ctx context.Context, request InitializeRequest) (*InitializeResponse, error) {
+	_, supportsRoots := request.Capabilities["roots"]
+	m.roots.reset(supportsRoots)
 	bi, _ := debug.ReadBuildInfo()
 	return &InitializeResponse{
 		ProtocolVersion: request.ProtocolVersion,
@@ -57,6 +63,10 @@ func (m *Impl) Initialize(ctx context.Context, request InitializeRequest) (*Init
 	}, nil
 }
 
+func (m *Impl) RootsListChanged(ctx context.Context) {
+	m.roots.changed()
+}
+
 func (m *Impl) ListTools(ctx context.Context, request ListToolsRequest) (*ListToolsResponse, error) {
 	resp := make([]ToolResponse, len(m.Tools))
 	for i, tool := range m.Tools {
@@ -64,6 +74,7 @@ func (m *Impl) ListTools(ctx context.Context, request ListToolsRequest) (*ListTo
 			Name:        tool.Name,
 			Description: tool.Description,
 			InputSchema: tool.InputSchema,
+			Annotations: tool.Annotations,
 		}
 	}
 	return &ListToolsResponse{Tools: resp}, nil
@@ -76,11 +87,29 @@ func (m *Impl) CallTool(ctx context.Context, request CallToolRequest) (*CallTool
 	if i == -1 {
 		return nil, rpc.JsonRpcError{Code: rpc.JsonRpcInvalidRequestError, Message: "tool not found"}
 	}
-	if c, err := m.Tools[i].Callable(ctx, request.Arguments); err != nil {
+	ctx = context.WithValue(ctx, clientRootsKey, &m.roots)
+	if request.Meta != nil && request.Meta.ProgressToken != nil {
+		ctx = context.WithValue(ctx, progressTokenKey, request.Meta.ProgressToken)
+	}
+	c, err := m.Tools[i].Callable(ctx, request.Arguments)
+	if partial := (*PartialResultError)(nil); errors.As(err, &partial) {
+		raw, _ := json.Marshal(partial.Warnings)
 		return &CallToolResponse{
+			Contents: append(c, NewTextToolResponseContentWithAudience(fmt.Sprintf("The result is partial. The following items could not be fetched and are missing from the result, in JSON format: %s", raw), "assistant")),
+			StructuredContent: map[string]interface{}{
+				"partial":  true,
+				"warnings": partial.Warnings,
+			},
+		}, nil
+	} else if err != nil {
+		out := &CallToolResponse{
 			Contents: append(c, NewTextToolResponseContentWithAudience(err.Error(), "assistant")),
 			IsError:  true,
-		}, nil
+		}
+		if data := rpc.GetErrorData(err); data != nil {
+			out.StructuredContent = map[string]interface{}{"error": data}
+		}
+		return out, nil
 	} else {
 		return &CallToolResponse{Contents: c, IsError: false}, nil
 	}
@@ -64,16 +64,34 @@ type ToolResponse struct {
 	Name        string                 `json:"name"`
 	Description string                 `json:"description"`
 	InputSchema map[string]interface{} `json:"inputSchema"`
+	Annotations *ToolAnnotations       `json:"annotations,omitempty"`
+}
+
+// ToolAnnotations are hints to the client about the behavior of a tool. Clients use them to decide whether to ask the
+// user before calling it. All hints are sent since the defaults of the specification assume the worst case.
+type ToolAnnotations struct {
+	Title           string `json:"title,omitempty"`
+	ReadOnlyHint    bool   `json:"readOnlyHint"`
+	DestructiveHint bool   `json:"destructiveHint"`
+	IdempotentHint  bool   `json:"idempotentHint"`
+	OpenWorldHint   bool   `json:"openWorldHint"`
 }
 
 type CallToolRequest struct {
 	Name      string                 `json:"name"`
 	Arguments map[string]interface{} `json:"arguments"`
+	Meta      *RequestMeta           `json:"_meta,omitempty"`
+}
+
+// RequestMeta is the metadata a client can attach to a request. The progress token asks for progress notifications.
+type RequestMeta struct {
+	ProgressToken interface{} `json:"progressToken,omitempty"`
 }
 
 type CallToolResponse struct {
-	IsError  bool                      `json:"is_error,omitempty"`
-	Contents []CallToolResponseContent `json:"content"`
+	IsError           bool                      `json:"is_error,omitempty"`
+	Contents          []CallToolResponseContent `json:"content"`
+	StructuredContent map[string]interface{}    `json:"structuredContent,omitempty"`
 }
 
 type CallToolResponseContent struct {
@@ -290,6 +308,7 @@ type SetLevelResponse struct {
 type ServerNotification struct {
 	*LoggingMessageNotification
 	*ToolListChangedNotification
+	*ProgressNotification
 }
 
 func (sn ServerNotification) ToJsonRpcNotificationInner() rpc.JsonRpcNotificationInner {
@@ -303,6 +322,12 @@ func (sn ServerNotification) ToJsonRpcNotificationInner() rpc.JsonRpcNotificatio
 		return rpc.JsonRpcNotificationInner{
 			Method: "notifications/tools/list_changed",
 		}
+	} else if sn.ProgressNotification != nil {
+		raw, _ := json.Marshal(sn.ProgressNotification)
+		return rpc.JsonRpcNotificationInner{
+			Method: "notifications/progress",
+			Params: raw,
+		}
 	} else {
 		return rpc.JsonRpcNotificationInner{}
 	}
@@ -317,6 +342,13 @@ type LoggingMessageNotification struct {
 type ToolListChangedNotification struct {
 }
 
+type ProgressNotification struct {
+	ProgressToken interface{} `json:"progressToken"`
+	Progress      float64     `json:"progress"`
+	Total         float64     `json:"total,omitempty"`
+	Message       string      `json:"message,omitempty"`
+}
+
 type McpIo interface {
 	Initialize(context.Context, InitializeRequest) (*InitializeResponse, error)
 	ListTools(context.Context, ListToolsRequest) (*ListToolsResponse, error)
@@ -327,6 +359,7 @@ type McpIo interface {
 	ReadResource(context.Context, ReadResourceRequest) (*ReadResourceResponse, error)
 	ListResourcesTemplates(context.Context, ListResourceTemplatesRequest) (*ListResourceTemplatesResponse, error)
 	SetLevel(context.Context, SetLevelRequest) (*SetLevelResponse, error)
+	RootsListChanged(context.Context)
 }
 
 func wrap[x any, y any](request rpc.JsonRpcRequest, f func(context.Context, x) (*y, error)) (*rpc.JsonRpcResponse, error) {
@@ -371,6 +404,9 @@ func AsHandler(inner McpIo) rpc.Handler {
 			return wrap[ReadResourceRequest, ReadResourceResponse](req, inner.ReadResource)
 		case "logging/setLevel":
 			return wrap[SetLevelRequest, SetLevelResponse](req, inner.SetLevel)
+		case "notifications/roots/list_changed":
+			inner.RootsListChanged(req.Context())
+			return nil, nil
 		default:
 			if strings.HasPrefix(req.Method, "notifications/") {
 				slog.Debug("dropping unsupported notification", slog.Any("method", req.Method))
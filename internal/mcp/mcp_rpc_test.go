@@ -1,10 +1,14 @@
 package mcp
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/humanitec/canyon-cli/internal/rpc"
 )
 
 func TestToolContentEncoding(t *testing.T) {
@@ -20,3 +24,109 @@ func TestToolContentEncoding(t *testing.T) {
 	raw, _ = json.Marshal(o)
 	assert.Equal(t, "{\"type\":\"text\",\"text\":\"something\",\"annotations\":{\"audience\":[\"aud\"]}}", string(raw))
 }
+
+type dataErr struct{}
+
+func (d dataErr) Error() string {
+	return "failed"
+}
+
+func (d dataErr) ErrorData() map[string]interface{} {
+	return map[string]interface{}{"kind": "not-found", "retryable": false}
+}
+
+func TestCallToolStructuredError(t *testing.T) {
+	impl := &Impl{Tools: []Tool{{Name: "x", Callable: func(ctx context.Context, arguments map[string]interface{}) ([]CallToolResponseContent, error) {
+		return nil, fmt.Errorf("wrapped: %w", dataErr{})
+	}}}}
+	r, err := impl.CallTool(context.Background(), CallToolRequest{Name: "x"})
+	assert.NoError(t, err)
+	raw, _ := json.Marshal(r)
+	assert.Equal(t, `{"is_error":true,"content":[{"type":"text","text":"wrapped: failed","annotations":{"audience":["assistant"]}}],"structuredContent":{"error":{"kind":"not-found","retryable":false}}}`, string(raw))
+}
+
+func TestCallToolPartialResult(t *testing.T) {
+	impl := &Impl{Tools: []Tool{{Name: "x", Callable: func(ctx context.Context, arguments map[string]interface{}) ([]CallToolResponseContent, error) {
+		return []CallToolResponseContent{NewTextToolResponseContent("ok")}, AsPartialResult([]ToolWarning{NewToolWarning("b", dataErr{})})
+	}}}}
+	r, err := impl.CallTool(context.Background(), CallToolRequest{Name: "x"})
+	assert.NoError(t, err)
+	assert.False(t, r.IsError)
+	raw, _ := json.Marshal(r.StructuredContent)
+	assert.Equal(t, `{"partial":true,"warnings":[{"item":"b","message":"failed","error":{"kind":"not-found","retryable":false}}]}`, string(raw))
+	assert.Len(t, r.Contents, 2)
+
+	assert.NoError(t, AsPartialResult(nil))
+}
+
+func TestListToolsAnnotations(t *testing.T) {
+	impl := &Impl{Tools: []Tool{{Name: "x"}, {Name: "y", Annotations: &ToolAnnotations{Title: "Y", DestructiveHint: true, OpenWorldHint: true}}}}
+	r, err := impl.ListTools(context.Background(), ListToolsRequest{})
+	assert.NoError(t, err)
+	raw, _ := json.Marshal(r.Tools)
+	assert.Equal(t, `[{"name":"x","description":"","inputSchema":null},{"name":"y","description":"","inputSchema":null,"annotations":{"title":"Y","readOnlyHint":false,"destructiveHint":true,"idempotentHint":false,"openWorldHint":true}}]`, string(raw))
+}
+
+func TestCallToolProgress(t *testing.T) {
+	impl := &Impl{Tools: []Tool{{Name: "x", Callable: func(ctx context.Context, arguments map[string]interface{}) ([]CallToolResponseContent, error) {
+		NotifyProgress(ctx, 1, 2, "half way")
+		return []CallToolResponseContent{NewTextToolResponseContent("ok")}, nil
+	}}}}
+	notifications := make(chan rpc.JsonRpcNotification, 1)
+	ctx := context.WithValue(context.Background(), rpc.NotificationChannelKey, (chan<- rpc.JsonRpcNotification)(notifications))
+
+	_, err := impl.CallTool(ctx, CallToolRequest{Name: "x"})
+	assert.NoError(t, err)
+	assert.Empty(t, notifications, "no progress without a progress token")
+
+	var request CallToolRequest
+	assert.NoError(t, json.Unmarshal([]byte(`{"name":"x","_meta":{"progressToken":"abc"}}`), &request))
+	_, err = impl.CallTool(ctx, request)
+	assert.NoError(t, err)
+	inner := (<-notifications).ToJsonRpcNotificationInner()
+	assert.Equal(t, "notifications/progress", inner.Method)
+	assert.Equal(t, `{"progressToken":"abc","progress":1,"total":2,"message":"half way"}`, string(inner.Params))
+}
+
+type fakeCaller struct {
+	calls int
+	roots []Root
+}
+
+func (f *fakeCaller) Call(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	f.calls++
+	if method != "roots/list" {
+		return nil, fmt.Errorf("unexpected method %s", method)
+	}
+	return json.Marshal(ListRootsResponse{Roots: f.roots})
+}
+
+func TestClientRoots(t *testing.T) {
+	var got []string
+	var ok bool
+	impl := &Impl{Tools: []Tool{{Name: "x", Callable: func(ctx context.Context, arguments map[string]interface{}) ([]CallToolResponseContent, error) {
+		got, ok = ClientRoots(ctx)
+		return nil, nil
+	}}}}
+	caller := &fakeCaller{roots: []Root{{Uri: "file:///work/a", Name: "a"}, {Uri: "https://example.com/b"}}}
+	ctx := context.WithValue(context.Background(), rpc.CallerKey, rpc.Caller(caller))
+
+	// clients without the roots capability are not asked
+	_, _ = impl.Initialize(ctx, InitializeRequest{})
+	_, _ = impl.CallTool(ctx, CallToolRequest{Name: "x"})
+	assert.False(t, ok)
+	assert.Equal(t, 0, caller.calls)
+
+	_, _ = impl.Initialize(ctx, InitializeRequest{Capabilities: map[string]interface{}{"roots": map[string]interface{}{"listChanged": true}}})
+	_, _ = impl.CallTool(ctx, CallToolRequest{Name: "x"})
+	_, _ = impl.CallTool(ctx, CallToolRequest{Name: "x"})
+	assert.True(t, ok)
+	assert.Equal(t, []string{"/work/a"}, got)
+	assert.Equal(t, 1, caller.calls)
+
+	caller.roots = nil
+	impl.RootsListChanged(ctx)
+	_, _ = impl.CallTool(ctx, CallToolRequest{Name: "x"})
+	assert.False(t, ok)
+	assert.Equal(t, 2, caller.calls)
+}
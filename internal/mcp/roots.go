@@ -0,0 +1,106 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/humanitec/canyon-cli/internal/rpc"
+)
+
+// listRootsTimeout bounds how long a tool call waits for the client to list its roots.
+const listRootsTimeout = 10 * time.Second
+
+type Root struct {
+	Uri  string `json:"uri"`
+	Name string `json:"name,omitempty"`
+}
+
+type ListRootsResponse struct {
+	Roots []Root `json:"roots"`
+}
+
+// clientRoots are the directories of the roots of a client that declared the roots capability. They are listed when
+// first needed and again after the client notifies that they changed.
+type clientRoots struct {
+	lock      sync.Mutex
+	supported bool
+	listed    bool
+	paths     []string
+}
+
+func (r *clientRoots) reset(supported bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.supported, r.listed, r.paths = supported, false, nil
+}
+
+func (r *clientRoots) changed() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.listed = false
+}
+
+// rootPaths returns the local directories of the file roots, other roots can not be read from.
+func rootPaths(roots []Root) []string {
+	out := make([]string, 0, len(roots))
+	for _, r := range roots {
+		if u, err := url.Parse(r.Uri); err == nil && u.Scheme == "file" && u.Path != "" {
+			out = append(out, filepath.FromSlash(u.Path))
+		}
+	}
+	return out
+}
+
+func (r *clientRoots) get(ctx context.Context) ([]string, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if !r.supported {
+		return nil, false
+	}
+	if !r.listed {
+		paths, err := listRoots(ctx)
+		if err != nil {
+			slog.Warn("failed to list the roots of the client", slog.Any("err", err))
+		}
+		r.listed, r.paths = true, paths
+	}
+	return r.paths, len(r.paths) > 0
+}
+
+func listRoots(ctx context.Context) ([]string, error) {
+	caller := rpc.GetCaller(ctx)
+	if caller == nil {
+		return nil, fmt.Errorf("the server can not send requests to the client")
+	}
+	ctx, cancel := context.WithTimeout(ctx, listRootsTimeout)
+	defer cancel()
+	raw, err := caller.Call(ctx, "roots/list", struct{}{})
+	if err != nil {
+		return nil, err
+	}
+	var resp ListRootsResponse
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode the roots: %w", err)
+	}
+	return rootPaths(resp.Roots), nil
+}
+
+type ctxKeyClientRoots struct{}
+
+var clientRootsKey = &ctxKeyClientRoots{}
+
+// ClientRoots returns the local directories the client shared as its roots for the tool call of the context. It returns
+// false when the client does not support roots or shared no local directories, and the server falls back to its own.
+func ClientRoots(ctx context.Context) ([]string, bool) {
+	r, _ := ctx.Value(clientRootsKey).(*clientRoots)
+	if r == nil {
+		return nil, false
+	}
+	return r.get(ctx)
+}
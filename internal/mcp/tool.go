@@ -1,10 +1,66 @@
 package mcp
 
-import "context"
+import (
+	"context"
+	"fmt"
+
+	"github.com/humanitec/canyon-cli/internal/rpc"
+)
 
 type Tool struct {
 	Name        string
 	Description string
 	InputSchema map[string]interface{}
 	Callable    func(ctx context.Context, arguments map[string]interface{}) ([]CallToolResponseContent, error)
+	// Annotations are optional hints about the behavior of the tool, such as whether it changes the Organization.
+	Annotations *ToolAnnotations
+}
+
+// ToolWarning describes an item that a tool could not process while still returning results for the other items.
+type ToolWarning struct {
+	Item    string                 `json:"item"`
+	Message string                 `json:"message"`
+	Error   map[string]interface{} `json:"error,omitempty"`
+}
+
+func NewToolWarning(item string, err error) ToolWarning {
+	return ToolWarning{Item: item, Message: err.Error(), Error: rpc.GetErrorData(err)}
+}
+
+// PartialResultError can be returned by a tool alongside its content when some items failed. The tool result is not
+// marked as an error, instead it is marked as partial and the warnings are included in the response.
+type PartialResultError struct {
+	Warnings []ToolWarning
+}
+
+func (p *PartialResultError) Error() string {
+	return fmt.Sprintf("the result is partial, %d items failed", len(p.Warnings))
+}
+
+// AsPartialResult returns nil if there are no warnings, otherwise a PartialResultError holding them.
+func AsPartialResult(warnings []ToolWarning) error {
+	if len(warnings) == 0 {
+		return nil
+	}
+	return &PartialResultError{Warnings: warnings}
+}
+
+type ctxKeyProgressToken struct{}
+
+var progressTokenKey = &ctxKeyProgressToken{}
+
+// NotifyProgress sends a progress notification for the tool call of the context when the client asked for progress by
+// sending a progress token, otherwise it does nothing. The progress must increase with every call, total is optional.
+func NotifyProgress(ctx context.Context, progress, total float64, message string) {
+	token := ctx.Value(progressTokenKey)
+	notifications := rpc.GetNotificationChannel(ctx)
+	if token == nil || notifications == nil {
+		return
+	}
+	select {
+	case notifications <- ServerNotification{ProgressNotification: &ProgressNotification{
+		ProgressToken: token, Progress: progress, Total: total, Message: message,
+	}}:
+	case <-ctx.Done():
+	}
 }
@@ -0,0 +1,22 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec"
+)
+
+// cacheControlProperty is the input schema property accepted by read-only tools to control the response cache.
+var cacheControlProperty = map[string]interface{}{
+	"type":        "string",
+	"enum":        []interface{}{"default", "bypass"},
+	"description": "Optional cache behavior. Use 'bypass' to fetch fresh data from Humanitec when the user indicates that the state has recently changed.",
+}
+
+// withCacheControl applies the cache_control argument to the context used for Humanitec API calls.
+func withCacheControl(ctx context.Context, arguments map[string]interface{}) context.Context {
+	if v, _ := arguments["cache_control"].(string); v == "bypass" {
+		return humanitec.WithCacheBypass(ctx)
+	}
+	return ctx
+}
@@ -0,0 +1,50 @@
+package tools
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// confirmationTokenTTL is how long the token of a dry-run can be used to confirm the change.
+const confirmationTokenTTL = 15 * time.Minute
+
+// confirmationKey signs the confirmation tokens of this process so that tokens cannot be made up by the client or
+// reused in another session.
+var confirmationKey = func() []byte {
+	out := make([]byte, 32)
+	_, _ = rand.Read(out)
+	return out
+}()
+
+func signConfirmation(expires string, parts []string) string {
+	mac := hmac.New(sha256.New, confirmationKey)
+	mac.Write([]byte(expires + "\x00" + strings.Join(parts, "\x00")))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// confirmationToken returns the token that a dry-run hands out to confirm exactly the change it previewed. The parts
+// identify the change and the state it was computed against, so the token stops matching when either changes.
+func confirmationToken(now time.Time, parts ...string) string {
+	expires := strconv.FormatInt(now.Add(confirmationTokenTTL).Unix(), 10)
+	return expires + "." + signConfirmation(expires, parts)
+}
+
+// checkConfirmationToken returns an error describing why the token does not confirm the change.
+func checkConfirmationToken(token string, now time.Time, parts ...string) error {
+	expires, signature, _ := strings.Cut(token, ".")
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || signature == "" {
+		return fmt.Errorf("the confirmation token is invalid, call this tool without a confirmation token for a dry-run that returns one")
+	} else if now.Unix() > unix {
+		return fmt.Errorf("the confirmation token expired, call this tool without a confirmation token for a new dry-run and review its diff again")
+	} else if !hmac.Equal([]byte(signature), []byte(signConfirmation(expires, parts))) {
+		return fmt.Errorf("the confirmation token does not match this change or the Environment changed since the dry-run, call this tool without a confirmation token for a new dry-run and review its diff again")
+	}
+	return nil
+}
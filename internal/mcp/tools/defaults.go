@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec"
+	"github.com/humanitec/canyon-cli/internal/mcp"
+)
+
+// profileDefaultArguments are the required tool arguments that fall back to the defaults of the active profile, such
+// as the org, app, and env context of humctl.
+var profileDefaultArguments = []struct {
+	Name    string
+	Entity  string
+	Default func(p *humanitec.Profile) string
+}{
+	{Name: "org_id", Entity: "Organization", Default: func(p *humanitec.Profile) string { return p.DefaultOrg }},
+	{Name: "app_id", Entity: "Application", Default: func(p *humanitec.Profile) string { return p.DefaultApp }},
+	{Name: "env_id", Entity: "Environment", Default: func(p *humanitec.Profile) string { return p.DefaultEnv }},
+}
+
+func requiredNames(v interface{}) []string {
+	switch r := v.(type) {
+	case []string:
+		return r
+	case []interface{}:
+		return stringsFromArgument(r)
+	}
+	return nil
+}
+
+// withProfileDefaults makes the org_id, app_id, and env_id arguments of the tool optional when they are required. A
+// call that omits them uses the default of the active profile, and fails when the profile has no default. Optional
+// arguments are left alone since leaving them out usually widens the scope of the tool, such as to every Application.
+func withProfileDefaults(tool mcp.Tool) mcp.Tool {
+	properties, _ := tool.InputSchema["properties"].(map[string]interface{})
+	required := requiredNames(tool.InputSchema["required"])
+	defaulted := make([]int, 0)
+	for i, a := range profileDefaultArguments {
+		if _, ok := properties[a.Name]; ok && slices.Contains(required, a.Name) {
+			defaulted = append(defaulted, i)
+		}
+	}
+	if len(defaulted) == 0 {
+		return tool
+	}
+
+	schema := maps.Clone(tool.InputSchema)
+	properties = maps.Clone(properties)
+	required = slices.Clone(required)
+	for _, i := range defaulted {
+		a := profileDefaultArguments[i]
+		property := maps.Clone(properties[a.Name].(map[string]interface{}))
+		description, _ := property["description"].(string)
+		property["description"] = fmt.Sprintf("%s Defaults to the default %s of the active profile.", description, a.Entity)
+		properties[a.Name] = property
+		required = slices.DeleteFunc(required, func(name string) bool { return name == a.Name })
+	}
+	schema["properties"] = properties
+	schema["required"] = required
+	tool.InputSchema = schema
+
+	callable := tool.Callable
+	tool.Callable = func(ctx context.Context, arguments map[string]interface{}) ([]mcp.CallToolResponseContent, error) {
+		var profile *humanitec.Profile
+		for _, i := range defaulted {
+			a := profileDefaultArguments[i]
+			if v, _ := arguments[a.Name].(string); v != "" {
+				continue
+			}
+			if profile == nil {
+				p, err := humanitec.ActiveProfile()
+				if err != nil {
+					return nil, err
+				}
+				profile = p
+			}
+			v := a.Default(profile)
+			if v == "" {
+				return nil, fmt.Errorf("%s is required since the active profile '%s' has no default %s", a.Name, profile.Name, a.Entity)
+			}
+			if arguments = maps.Clone(arguments); arguments == nil {
+				arguments = make(map[string]interface{})
+			}
+			arguments[a.Name] = v
+		}
+		return callable(ctx, arguments)
+	}
+	return tool
+}
@@ -0,0 +1,386 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/humanitec/humanitec-go-autogen/client"
+
+	"github.com/humanitec/canyon-cli/internal"
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec"
+	"github.com/humanitec/canyon-cli/internal/mcp"
+)
+
+const (
+	defaultDeploymentWait = 30 * time.Second
+	maxDeploymentWait     = 5 * time.Minute
+)
+
+// deploymentPollInterval is how often the progress of a new deployment is checked.
+var deploymentPollInterval = 2 * time.Second
+
+// deploymentPlan is a deployment of a Deployment Set to an Environment computed by a dry-run.
+type deploymentPlan struct {
+	Action          string
+	OrgId           string
+	AppId           string
+	EnvId           string
+	CurrentDeployId string
+	CurrentSetId    string
+	TargetSetId     string
+	// Description says what is deployed, eg: "Deployment Set 'x'".
+	Description string
+	Changes     []setChange
+}
+
+func (p deploymentPlan) token(now time.Time) string {
+	return confirmationToken(now, p.Action, p.OrgId, p.AppId, p.EnvId, p.CurrentDeployId, p.TargetSetId)
+}
+
+func (p deploymentPlan) checkToken(token string, now time.Time) error {
+	return checkConfirmationToken(token, now, p.Action, p.OrgId, p.AppId, p.EnvId, p.CurrentDeployId, p.TargetSetId)
+}
+
+// getCurrentDeployment returns the last deployment of the Environment, or nothing when it was never deployed.
+func getCurrentDeployment(ctx context.Context, hc *humanitec.WrappedHumanitecClientImpl, orgId, appId, envId string) (*client.DeploymentResponse, error) {
+	r, err := humanitec.CheckResponse(func() (*client.GetEnvironmentResponse, error) {
+		return hc.GetEnvironmentWithResponse(ctx, orgId, appId, envId)
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	if err != nil {
+		return nil, err
+	}
+	return r.JSON200.LastDeploy, nil
+}
+
+// applyDeltaToSet returns the id of the Deployment Set that results from applying a delta to a set. Sets are content
+// addressed so this does not change any Environment.
+func applyDeltaToSet(ctx context.Context, hc *humanitec.WrappedHumanitecClientImpl, orgId, appId, setId string, delta []byte) (string, error) {
+	r, err := humanitec.CheckResponse(func() (*client.UpdateSetResponse, error) {
+		return hc.UpdateSetWithBodyWithResponse(ctx, orgId, appId, setId, "application/json", bytes.NewReader(delta))
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	if err != nil {
+		return "", err
+	} else if r.JSON200 == nil {
+		return "", fmt.Errorf("the Deployment Set returned no id")
+	}
+	return *r.JSON200, nil
+}
+
+// completePlan fetches both Deployment Sets of the plan and diffs them.
+func completePlan(ctx context.Context, hc *humanitec.WrappedHumanitecClientImpl, plan *deploymentPlan) error {
+	current := make(map[string]interface{})
+	if plan.CurrentSetId != "" {
+		var err error
+		if current, err = getSetContent(ctx, hc, plan.OrgId, plan.AppId, plan.CurrentSetId); err != nil {
+			return fmt.Errorf("failed to fetch the current Deployment Set: %w", err)
+		}
+	}
+	target, err := getSetContent(ctx, hc, plan.OrgId, plan.AppId, plan.TargetSetId)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the Deployment Set to deploy: %w", err)
+	}
+	plan.Changes = diffSetValues(nil, current, target, make([]setChange, 0))
+	return nil
+}
+
+// dryRunResponse describes the plan and the token that confirms it.
+func dryRunResponse(plan deploymentPlan, now time.Time) []mcp.CallToolResponseContent {
+	token := plan.token(now)
+	text := fmt.Sprintf("DRY-RUN, nothing was deployed. Deploying %s to Environment '%s' of Application '%s' would make %d changes to its current Deployment Set.", plan.Description, plan.EnvId, plan.AppId, len(plan.Changes))
+	if plan.CurrentSetId == "" {
+		text = fmt.Sprintf("DRY-RUN, nothing was deployed. Deploying %s to Environment '%s' of Application '%s' would be its first deployment with %d changes.", plan.Description, plan.EnvId, plan.AppId, len(plan.Changes))
+	} else if len(plan.Changes) == 0 {
+		text = fmt.Sprintf("DRY-RUN, nothing was deployed. Environment '%s' of Application '%s' already runs %s, deploying it again would only reprovision its resources.", plan.EnvId, plan.AppId, plan.Description)
+	}
+	text += fmt.Sprintf(" Show the changes to the user and only after they explicitly confirm, call this tool again with the same arguments and confirmation_token '%s'. The token expires at %s and stops matching if the Environment is deployed in the meantime.", token, now.Add(confirmationTokenTTL).UTC().Format(time.RFC3339))
+	out := []mcp.CallToolResponseContent{mcp.NewTextToolResponseContent("%s", text)}
+	if len(plan.Changes) > 0 {
+		out = append(out,
+			mcp.NewTextToolResponseContent("The changes in JSON format: %s", string(internal.PrettyJson(plan.Changes))),
+			mcp.NewTextToolResponseContent("The changes as CSV: %s", setChangesAsCsv(plan.Changes)),
+		)
+	}
+	return out
+}
+
+// followDeployment polls the deployment until it finished or the wait elapsed and returns its latest state. Every poll
+// is reported as progress of the tool call.
+func followDeployment(ctx context.Context, hc *humanitec.WrappedHumanitecClientImpl, orgId, appId string, d client.DeploymentResponse, wait time.Duration) client.DeploymentResponse {
+	start := time.Now()
+	mcp.NotifyProgress(ctx, 0, wait.Seconds(), deploymentProgress(d))
+	for d.Status != "succeeded" && d.Status != "failed" && time.Since(start) < wait {
+		select {
+		case <-ctx.Done():
+			return d
+		case <-time.After(deploymentPollInterval):
+		}
+		r, err := humanitec.CheckResponse(func() (*client.GetDeploymentResponse, error) {
+			return hc.GetDeploymentWithResponse(ctx, orgId, appId, d.EnvId, d.Id)
+		}).AndStatusCodeEq(http.StatusOK).RespAndError()
+		if err == nil {
+			d = *r.JSON200
+		}
+		mcp.NotifyProgress(ctx, min(time.Since(start), wait).Seconds(), wait.Seconds(), deploymentProgress(d))
+	}
+	return d
+}
+
+func deploymentProgress(d client.DeploymentResponse) string {
+	return fmt.Sprintf("Deployment '%s' to Environment '%s' has status '%s'.", d.Id, d.EnvId, d.Status)
+}
+
+// executePlan deploys the target set of a confirmed plan and reports the progress of the deployment.
+func executePlan(ctx context.Context, hc *humanitec.WrappedHumanitecClientImpl, plan deploymentPlan, comment string, wait time.Duration) ([]mcp.CallToolResponseContent, error) {
+	body := client.CreateDeploymentJSONRequestBody{SetId: &plan.TargetSetId}
+	if comment != "" {
+		body.Comment = &comment
+	}
+	r, err := humanitec.CheckResponse(func() (*client.CreateDeploymentResponse, error) {
+		return hc.CreateDeploymentWithResponse(ctx, plan.OrgId, plan.AppId, plan.EnvId, body)
+	}).AndStatusCodeEq(http.StatusCreated).RespAndError()
+	if err != nil {
+		return nil, err
+	}
+	d := followDeployment(ctx, hc, plan.OrgId, plan.AppId, *r.JSON201, wait)
+	summary := newDeploymentSummary(d)
+
+	var text string
+	switch d.Status {
+	case "succeeded":
+		text = fmt.Sprintf("Deployment '%s' of %s to Environment '%s' of Application '%s' succeeded.", d.Id, plan.Description, plan.EnvId, plan.AppId)
+	case "failed":
+		text = fmt.Sprintf("Deployment '%s' of %s to Environment '%s' of Application '%s' failed.", d.Id, plan.Description, plan.EnvId, plan.AppId)
+		if errs, err := listDeploymentErrors(ctx, hc, plan.OrgId, plan.AppId, plan.EnvId, d.Id); err == nil {
+			summary.Errors = errs
+		}
+	default:
+		text = fmt.Sprintf("Deployment '%s' of %s to Environment '%s' of Application '%s' was started and is %s. Use the get_humanitec_deployment tool to follow its progress.", d.Id, plan.Description, plan.EnvId, plan.AppId, d.Status)
+	}
+	return []mcp.CallToolResponseContent{
+		mcp.NewTextToolResponseContent("%s The deployment in JSON format: %s", text, string(internal.PrettyJson(summary))),
+	}, nil
+}
+
+// waitFromArgument reads the wait_seconds argument.
+func waitFromArgument(arguments map[string]interface{}) time.Duration {
+	v, ok := arguments["wait_seconds"].(float64)
+	if !ok || v < 0 {
+		return defaultDeploymentWait
+	}
+	return min(time.Duration(v)*time.Second, maxDeploymentWait)
+}
+
+// guardedDeploymentAnnotations mark the tools that deploy to an Environment.
+var guardedDeploymentAnnotations = &mcp.ToolAnnotations{DestructiveHint: true, OpenWorldHint: true}
+
+// guardedDeploymentProperties are the input schema properties shared by the tools that deploy to an Environment.
+var guardedDeploymentProperties = map[string]interface{}{
+	"comment": map[string]interface{}{"type": "string", "description": "Optional comment describing the purpose of the deployment."},
+	"confirmation_token": map[string]interface{}{
+		"type":        "string",
+		"description": "The token returned by the dry-run. Without it the call is a dry-run that changes nothing. Only pass it after the user explicitly confirmed the changes of the dry-run.",
+	},
+	"wait_seconds": map[string]interface{}{
+		"type": "integer", "minimum": 0, "maximum": int(maxDeploymentWait.Seconds()),
+		"description": "Optional number of seconds to wait for the deployment to finish before returning its progress, defaults to 30.",
+	},
+}
+
+func withGuardedDeploymentProperties(properties map[string]interface{}) map[string]interface{} {
+	for k, v := range guardedDeploymentProperties {
+		properties[k] = v
+	}
+	return properties
+}
+
+func NewDeployToEnvironment() mcp.Tool {
+	return mcp.Tool{
+		Name: "deploy_to_humanitec_environment",
+		Description: `This tool deploys a Deployment Set or a Deployment Delta to a Humanitec Environment. This changes the running state of the Environment.
+Give exactly one of: set, to deploy an existing set such as the latest deployment of another Environment; delta_id, to apply an existing Deployment Delta to the current set; or delta, to apply an inline Deployment Delta with modules add, remove, and update actions and shared update actions.
+The first call without a confirmation_token is a dry-run that returns the diff against the current Deployment Set and a confirmation token. Show the diff to the user and only call again with the token after they explicitly confirm.
+The confirmed call returns the new deployment id and its progress.`,
+		Annotations: guardedDeploymentAnnotations,
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": withGuardedDeploymentProperties(map[string]interface{}{
+				"org_id":   map[string]interface{}{"type": "string", "description": "The Humanitec Organization (org) ID to work with."},
+				"app_id":   map[string]interface{}{"type": "string", "description": "The Humanitec Application (app) ID to work with."},
+				"env_id":   map[string]interface{}{"type": "string", "description": "The Humanitec Environment (env) ID to deploy to."},
+				"set":      setSourceSchema("The Deployment Set to deploy."),
+				"delta_id": map[string]interface{}{"type": "string", "description": "The id of a Deployment Delta to apply to the current Deployment Set of the Environment."},
+				"delta":    map[string]interface{}{"type": "object", "description": "A Deployment Delta to apply to the current Deployment Set of the Environment, eg: {\"modules\": {\"update\": {\"my-workload\": [{\"op\": \"replace\", \"path\": \"/spec/containers/main/image\", \"value\": \"my-image:2\"}]}}}."},
+			}),
+			"required":             []string{"org_id", "app_id", "env_id"},
+			"additionalProperties": false,
+		},
+		Callable: func(ctx context.Context, arguments map[string]interface{}) ([]mcp.CallToolResponseContent, error) {
+			plan := deploymentPlan{Action: "deploy"}
+			plan.OrgId, _ = arguments["org_id"].(string)
+			plan.AppId, _ = arguments["app_id"].(string)
+			plan.EnvId, _ = arguments["env_id"].(string)
+			deltaId, _ := arguments["delta_id"].(string)
+			delta, _ := arguments["delta"].(map[string]interface{})
+			given := 0
+			for _, ok := range []bool{arguments["set"] != nil, deltaId != "", delta != nil} {
+				if ok {
+					given++
+				}
+			}
+			if given != 1 {
+				return nil, fmt.Errorf("exactly one of set, delta_id, or delta is required")
+			}
+
+			// the plan must reflect the live state of the Environment
+			ctx = humanitec.WithCacheBypass(ctx)
+			hc, err := humanitec.NewHumanitecClientWithCurrentToken(ctx)
+			if err != nil {
+				return nil, err
+			}
+			current, err := getCurrentDeployment(ctx, hc, plan.OrgId, plan.AppId, plan.EnvId)
+			if err != nil {
+				return nil, err
+			} else if current != nil {
+				plan.CurrentDeployId, plan.CurrentSetId = current.Id, current.SetId
+			}
+
+			switch {
+			case arguments["set"] != nil:
+				src, err := setSourceFromArgument("set", arguments["set"])
+				if err != nil {
+					return nil, err
+				}
+				if plan.TargetSetId, err = resolveSetId(ctx, hc, plan.OrgId, plan.AppId, src); err != nil {
+					return nil, fmt.Errorf("failed to resolve %s: %w", src, err)
+				}
+				plan.Description = fmt.Sprintf("Deployment Set '%s'", plan.TargetSetId)
+				if src.SetId == "" {
+					plan.Description += " from " + src.String()
+				}
+			default:
+				if plan.CurrentSetId == "" {
+					return nil, fmt.Errorf("environment '%s' was never deployed, so a delta cannot be applied to it, deploy a set instead", plan.EnvId)
+				}
+				var raw []byte
+				if deltaId != "" {
+					r, err := humanitec.CheckResponse(func() (*client.GetDeltaResponse, error) {
+						return hc.GetDeltaWithResponse(ctx, plan.OrgId, plan.AppId, deltaId)
+					}).AndStatusCodeEq(http.StatusOK).RespAndError()
+					if err != nil {
+						return nil, err
+					}
+					raw = r.Body
+					plan.Description = fmt.Sprintf("Deployment Delta '%s'", deltaId)
+				} else {
+					raw, _ = json.Marshal(delta)
+					plan.Description = "the given Deployment Delta"
+				}
+				if plan.TargetSetId, err = applyDeltaToSet(ctx, hc, plan.OrgId, plan.AppId, plan.CurrentSetId, raw); err != nil {
+					return nil, fmt.Errorf("failed to apply the delta to the current Deployment Set: %w", err)
+				}
+			}
+			if err := completePlan(ctx, hc, &plan); err != nil {
+				return nil, err
+			}
+
+			token, _ := arguments["confirmation_token"].(string)
+			if token == "" {
+				return dryRunResponse(plan, time.Now()), nil
+			} else if err := plan.checkToken(token, time.Now()); err != nil {
+				return nil, err
+			}
+			comment, _ := arguments["comment"].(string)
+			return executePlan(ctx, hc, plan, comment, waitFromArgument(arguments))
+		},
+	}
+}
+
+func NewRollbackEnvironment() mcp.Tool {
+	return mcp.Tool{
+		Name: "rollback_humanitec_environment",
+		Description: `This tool rolls a Humanitec Environment back by redeploying the Deployment Set of a previous deployment. This changes the running state of the Environment.
+By default it rolls back to the most recent successful deployment with a different Deployment Set than the current one, or to the given deploy_id.
+The first call without a confirmation_token is a dry-run that returns the diff against the current Deployment Set and a confirmation token. Show the diff to the user and only call again with the token after they explicitly confirm.
+The confirmed call returns the new deployment id and its progress.`,
+		Annotations: guardedDeploymentAnnotations,
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": withGuardedDeploymentProperties(map[string]interface{}{
+				"org_id":    map[string]interface{}{"type": "string", "description": "The Humanitec Organization (org) ID to work with."},
+				"app_id":    map[string]interface{}{"type": "string", "description": "The Humanitec Application (app) ID to work with."},
+				"env_id":    map[string]interface{}{"type": "string", "description": "The Humanitec Environment (env) ID to roll back."},
+				"deploy_id": map[string]interface{}{"type": "string", "description": "Optional id of the previous deployment of the Environment to roll back to."},
+			}),
+			"required":             []string{"org_id", "app_id", "env_id"},
+			"additionalProperties": false,
+		},
+		Callable: func(ctx context.Context, arguments map[string]interface{}) ([]mcp.CallToolResponseContent, error) {
+			plan := deploymentPlan{Action: "rollback"}
+			plan.OrgId, _ = arguments["org_id"].(string)
+			plan.AppId, _ = arguments["app_id"].(string)
+			plan.EnvId, _ = arguments["env_id"].(string)
+			deployId, _ := arguments["deploy_id"].(string)
+
+			ctx = humanitec.WithCacheBypass(ctx)
+			hc, err := humanitec.NewHumanitecClientWithCurrentToken(ctx)
+			if err != nil {
+				return nil, err
+			}
+			current, err := getCurrentDeployment(ctx, hc, plan.OrgId, plan.AppId, plan.EnvId)
+			if err != nil {
+				return nil, err
+			} else if current == nil {
+				return nil, fmt.Errorf("environment '%s' was never deployed, so it cannot be rolled back", plan.EnvId)
+			}
+			plan.CurrentDeployId, plan.CurrentSetId = current.Id, current.SetId
+
+			var target *client.DeploymentResponse
+			if deployId != "" {
+				r, err := humanitec.CheckResponse(func() (*client.GetDeploymentResponse, error) {
+					return hc.GetDeploymentWithResponse(ctx, plan.OrgId, plan.AppId, plan.EnvId, deployId)
+				}).AndStatusCodeEq(http.StatusOK).RespAndError()
+				if err != nil {
+					return nil, err
+				}
+				target = r.JSON200
+			} else {
+				r, err := humanitec.CheckResponse(func() (*client.ListDeploymentsResponse, error) {
+					return hc.ListDeploymentsWithResponse(ctx, plan.OrgId, plan.AppId, plan.EnvId, &client.ListDeploymentsParams{})
+				}).AndStatusCodeEq(http.StatusOK).RespAndError()
+				if err != nil {
+					return nil, err
+				}
+				for _, d := range humanitec.DerefSlice(r.JSON200) {
+					if d.Status == "succeeded" && d.SetId != current.SetId {
+						target = &d
+						break
+					}
+				}
+				if target == nil {
+					return nil, fmt.Errorf("environment '%s' has no previous successful deployment with a different Deployment Set to roll back to", plan.EnvId)
+				}
+			}
+			plan.TargetSetId = target.SetId
+			plan.Description = fmt.Sprintf("Deployment Set '%s' of deployment '%s' from %s", target.SetId, target.Id, target.CreatedAt.UTC().Format(time.RFC3339))
+			if err := completePlan(ctx, hc, &plan); err != nil {
+				return nil, err
+			}
+
+			token, _ := arguments["confirmation_token"].(string)
+			if token == "" {
+				return dryRunResponse(plan, time.Now()), nil
+			} else if err := plan.checkToken(token, time.Now()); err != nil {
+				return nil, err
+			}
+			comment, _ := arguments["comment"].(string)
+			if comment == "" {
+				comment = fmt.Sprintf("Rollback to deployment %s", target.Id)
+			}
+			return executePlan(ctx, hc, plan, comment, waitFromArgument(arguments))
+		},
+	}
+}
@@ -0,0 +1,234 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/humanitec/humanitec-go-autogen/client"
+
+	"github.com/humanitec/canyon-cli/internal"
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec"
+	"github.com/humanitec/canyon-cli/internal/mcp"
+)
+
+// defaultDeploymentsLimit is the number of deployments returned when no limit is given.
+const defaultDeploymentsLimit = 20
+
+var deploymentStatuses = []string{"pending", "in progress", "succeeded", "failed"}
+
+// deploymentSummary is the representation of a deployment returned by the deployment tools.
+type deploymentSummary struct {
+	Id              string                           `json:"id"`
+	Status          string                           `json:"status"`
+	CreatedBy       string                           `json:"createdBy"`
+	Comment         string                           `json:"comment,omitempty"`
+	SetId           string                           `json:"setId"`
+	DeltaId         string                           `json:"deltaId,omitempty"`
+	FromId          string                           `json:"fromDeploymentId,omitempty"`
+	CreatedAt       time.Time                        `json:"createdAt"`
+	StatusChangedAt time.Time                        `json:"statusChangedAt"`
+	Duration        string                           `json:"duration,omitempty"`
+	Errors          []client.DeploymentErrorResponse `json:"errors,omitempty"`
+}
+
+func newDeploymentSummary(d client.DeploymentResponse) deploymentSummary {
+	out := deploymentSummary{
+		Id:              d.Id,
+		Status:          d.Status,
+		CreatedBy:       d.CreatedBy,
+		Comment:         d.Comment,
+		SetId:           d.SetId,
+		FromId:          d.FromId,
+		CreatedAt:       d.CreatedAt,
+		StatusChangedAt: d.StatusChangedAt,
+	}
+	if d.DeltaId != nil {
+		out.DeltaId = *d.DeltaId
+	}
+	if (d.Status == "succeeded" || d.Status == "failed") && d.StatusChangedAt.After(d.CreatedAt) {
+		out.Duration = d.StatusChangedAt.Sub(d.CreatedAt).Round(time.Second).String()
+	}
+	return out
+}
+
+// parseTimeArgument accepts an RFC3339 timestamp, a date, or a duration relative to now such as 72h. A date is the
+// start of that day, or the start of the next day with endOfDay so that an exclusive end of a range includes the day.
+func parseTimeArgument(name, v string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	} else if t, err := time.Parse(time.DateOnly, v); err == nil && endOfDay {
+		return t.AddDate(0, 0, 1), nil
+	} else if err == nil {
+		return t, nil
+	} else if d, err := time.ParseDuration(v); err == nil {
+		return time.Now().Add(-d.Abs()), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid %s '%s': expected an RFC3339 timestamp, a date, or a duration like 72h", name, v)
+}
+
+func listDeploymentErrors(ctx context.Context, hc *humanitec.WrappedHumanitecClientImpl, orgId, appId, envId, deployId string) ([]client.DeploymentErrorResponse, error) {
+	r, err := humanitec.CheckResponse(func() (*client.ListDeploymentErrorsResponse, error) {
+		return hc.ListDeploymentErrorsWithResponse(ctx, orgId, appId, envId, deployId)
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	if err != nil || r.JSON200 == nil {
+		return nil, err
+	}
+	return *r.JSON200, nil
+}
+
+func NewListDeployments() mcp.Tool {
+	return mcp.Tool{
+		Name: "list_humanitec_deployments",
+		Description: `This tool returns the deployment history of a Humanitec Environment, newest first.
+Each deployment includes its status, author, comment, Deployment Set id, Delta id, timings, and the errors of failed deployments.
+Filter by time range with since and until, and by status. Use this to answer what changed in an environment and when a deployment broke.
+The Deployment Set ids can be fetched with the get_humanitec_deployment_sets tool to see the contents of each deployment.`,
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"org_id": map[string]interface{}{"type": "string", "description": "The Humanitec Organization (org) ID to work with."},
+				"app_id": map[string]interface{}{"type": "string", "description": "The Humanitec Application (app) ID to work with."},
+				"env_id": map[string]interface{}{"type": "string", "description": "The Humanitec Environment (env) ID to work with."},
+				"status": map[string]interface{}{"type": "string", "enum": deploymentStatuses, "description": "Optional filter for the deployment status."},
+				"since":  map[string]interface{}{"type": "string", "description": "Optional start of the time range as an RFC3339 timestamp, a date, or a duration ago like 72h."},
+				"until":  map[string]interface{}{"type": "string", "description": "Optional end of the time range as an RFC3339 timestamp, a date, or a duration ago like 24h. A date includes the whole day."},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"minimum":     1,
+					"description": fmt.Sprintf("Optional maximum number of deployments to return, defaults to %d.", defaultDeploymentsLimit),
+				},
+				"cache_control": cacheControlProperty,
+			},
+			"required":             []string{"org_id", "app_id", "env_id"},
+			"additionalProperties": false,
+		},
+		Callable: func(ctx context.Context, arguments map[string]interface{}) ([]mcp.CallToolResponseContent, error) {
+			orgId, _ := arguments["org_id"].(string)
+			appId, _ := arguments["app_id"].(string)
+			envId, _ := arguments["env_id"].(string)
+			status, _ := arguments["status"].(string)
+			if status != "" && !slices.Contains(deploymentStatuses, status) {
+				return nil, fmt.Errorf("invalid status '%s': expected one of %s", status, strings.Join(deploymentStatuses, ", "))
+			}
+			var since, until time.Time
+			if v, ok := arguments["since"].(string); ok && v != "" {
+				var err error
+				if since, err = parseTimeArgument("since", v, false); err != nil {
+					return nil, err
+				}
+			}
+			if v, ok := arguments["until"].(string); ok && v != "" {
+				var err error
+				if until, err = parseTimeArgument("until", v, true); err != nil {
+					return nil, err
+				}
+			}
+			limit := defaultDeploymentsLimit
+			if v, ok := arguments["limit"].(float64); ok && v > 0 {
+				limit = int(v)
+			}
+
+			ctx = withCacheControl(ctx, arguments)
+			hc, err := humanitec.NewHumanitecClientWithCurrentToken(ctx)
+			if err != nil {
+				return nil, err
+			}
+			r, err := humanitec.CheckResponse(func() (*client.ListDeploymentsResponse, error) {
+				return hc.ListDeploymentsWithResponse(ctx, orgId, appId, envId, &client.ListDeploymentsParams{})
+			}).AndStatusCodeEq(http.StatusOK).RespAndError()
+			if err != nil {
+				return nil, err
+			}
+
+			deployments := humanitec.DerefSlice(r.JSON200)
+			slices.SortStableFunc(deployments, func(a, b client.DeploymentResponse) int {
+				return b.CreatedAt.Compare(a.CreatedAt)
+			})
+			selected := make([]deploymentSummary, 0)
+			matching := 0
+			for _, d := range deployments {
+				if (status != "" && d.Status != status) || (!since.IsZero() && d.CreatedAt.Before(since)) || (!until.IsZero() && !d.CreatedAt.Before(until)) {
+					continue
+				}
+				matching++
+				if len(selected) < limit {
+					selected = append(selected, newDeploymentSummary(d))
+				}
+			}
+
+			warnings := make([]mcp.ToolWarning, 0)
+			failed := make([]int, 0)
+			for i, d := range selected {
+				if d.Status == "failed" {
+					failed = append(failed, i)
+				}
+			}
+			for _, result := range humanitec.FanOut(ctx, failed, func(ctx context.Context, i int) ([]client.DeploymentErrorResponse, error) {
+				return listDeploymentErrors(ctx, hc, orgId, appId, envId, selected[i].Id)
+			}) {
+				if result.Err != nil {
+					warnings = append(warnings, mcp.NewToolWarning(selected[result.Item].Id, fmt.Errorf("failed to fetch deployment errors: %w", result.Err)))
+				} else {
+					selected[result.Item].Errors = result.Output
+				}
+			}
+
+			more := ""
+			if matching > len(selected) {
+				more = fmt.Sprintf(" Only the newest %d of %d matching deployments are shown, narrow the time range or raise the limit to see more.", len(selected), matching)
+			}
+			return []mcp.CallToolResponseContent{
+				mcp.NewTextToolResponseContent("The deployments of Environment '%s' in Application '%s', newest first, in JSON format: %s%s", envId, appId, string(internal.PrettyJson(selected)), more),
+			}, mcp.AsPartialResult(warnings)
+		},
+	}
+}
+
+func NewGetDeployment() mcp.Tool {
+	return mcp.Tool{
+		Name: "get_humanitec_deployment",
+		Description: `This tool returns the details of a single Humanitec deployment including its status, author, comment, Deployment Set id, Delta id, timings, and errors.
+Use list_humanitec_deployments to find deployment ids.`,
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"org_id":        map[string]interface{}{"type": "string", "description": "The Humanitec Organization (org) ID to work with."},
+				"app_id":        map[string]interface{}{"type": "string", "description": "The Humanitec Application (app) ID to work with."},
+				"env_id":        map[string]interface{}{"type": "string", "description": "The Humanitec Environment (env) ID to work with."},
+				"deploy_id":     map[string]interface{}{"type": "string", "description": "The Humanitec Deployment (deploy) ID to fetch."},
+				"cache_control": cacheControlProperty,
+			},
+			"required":             []string{"org_id", "app_id", "env_id", "deploy_id"},
+			"additionalProperties": false,
+		},
+		Callable: func(ctx context.Context, arguments map[string]interface{}) ([]mcp.CallToolResponseContent, error) {
+			orgId, _ := arguments["org_id"].(string)
+			appId, _ := arguments["app_id"].(string)
+			envId, _ := arguments["env_id"].(string)
+			deployId, _ := arguments["deploy_id"].(string)
+			ctx = withCacheControl(ctx, arguments)
+			hc, err := humanitec.NewHumanitecClientWithCurrentToken(ctx)
+			if err != nil {
+				return nil, err
+			}
+			r, err := humanitec.CheckResponse(func() (*client.GetDeploymentResponse, error) {
+				return hc.GetDeploymentWithResponse(ctx, orgId, appId, envId, deployId)
+			}).AndStatusCodeEq(http.StatusOK).RespAndError()
+			if err != nil {
+				return nil, err
+			}
+			out := newDeploymentSummary(*r.JSON200)
+			warnings := make([]mcp.ToolWarning, 0)
+			if out.Errors, err = listDeploymentErrors(ctx, hc, orgId, appId, envId, deployId); err != nil {
+				warnings = append(warnings, mcp.NewToolWarning(deployId, fmt.Errorf("failed to fetch deployment errors: %w", err)))
+			}
+			return []mcp.CallToolResponseContent{
+				mcp.NewTextToolResponseContent("The deployment '%s' in JSON format: %s", deployId, string(internal.PrettyJson(out))),
+			}, mcp.AsPartialResult(warnings)
+		},
+	}
+}
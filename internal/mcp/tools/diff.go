@@ -0,0 +1,283 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/humanitec/humanitec-go-autogen/client"
+
+	"github.com/humanitec/canyon-cli/internal"
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec"
+	"github.com/humanitec/canyon-cli/internal/mcp"
+)
+
+// setSource identifies a Deployment Set by its id, by the latest deployment of an environment, or by a deployment.
+type setSource struct {
+	SetId    string
+	EnvId    string
+	DeployId string
+}
+
+func setSourceFromArgument(name string, v interface{}) (setSource, error) {
+	m, _ := v.(map[string]interface{})
+	out := setSource{}
+	out.SetId, _ = m["set_id"].(string)
+	out.EnvId, _ = m["env_id"].(string)
+	out.DeployId, _ = m["deploy_id"].(string)
+	if (out.SetId == "") == (out.EnvId == "") {
+		return out, fmt.Errorf("%s requires exactly one of set_id or env_id", name)
+	} else if out.DeployId != "" && out.EnvId == "" {
+		return out, fmt.Errorf("%s requires env_id when deploy_id is set", name)
+	}
+	return out, nil
+}
+
+func (s setSource) String() string {
+	if s.DeployId != "" {
+		return fmt.Sprintf("deployment '%s' of Environment '%s'", s.DeployId, s.EnvId)
+	} else if s.EnvId != "" {
+		return fmt.Sprintf("the latest deployment of Environment '%s'", s.EnvId)
+	}
+	return fmt.Sprintf("set '%s'", s.SetId)
+}
+
+// resolveSetId returns the id of the Deployment Set the source refers to.
+func resolveSetId(ctx context.Context, hc *humanitec.WrappedHumanitecClientImpl, orgId, appId string, src setSource) (string, error) {
+	if src.SetId != "" {
+		return src.SetId, nil
+	} else if src.DeployId != "" {
+		r, err := humanitec.CheckResponse(func() (*client.GetDeploymentResponse, error) {
+			return hc.GetDeploymentWithResponse(ctx, orgId, appId, src.EnvId, src.DeployId)
+		}).AndStatusCodeEq(http.StatusOK).RespAndError()
+		if err != nil {
+			return "", err
+		}
+		return r.JSON200.SetId, nil
+	}
+	r, err := humanitec.CheckResponse(func() (*client.GetEnvironmentResponse, error) {
+		return hc.GetEnvironmentWithResponse(ctx, orgId, appId, src.EnvId)
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	if err != nil {
+		return "", err
+	} else if r.JSON200.LastDeploy == nil {
+		return "", fmt.Errorf("environment '%s' has never been deployed", src.EnvId)
+	}
+	return r.JSON200.LastDeploy.SetId, nil
+}
+
+// getSetContent fetches a Deployment Set as generic json so that every field can be compared.
+func getSetContent(ctx context.Context, hc *humanitec.WrappedHumanitecClientImpl, orgId, appId, setId string) (map[string]interface{}, error) {
+	r, err := humanitec.CheckResponse(func() (*client.GetSetResponse, error) {
+		return hc.GetSetWithResponse(ctx, orgId, appId, setId, &client.GetSetParams{})
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]interface{})
+	if err := json.Unmarshal(r.Body, &out); err != nil {
+		return nil, fmt.Errorf("failed to decode set %s: %w", setId, err)
+	}
+	// these identify the set rather than describe its content
+	delete(out, "id")
+	delete(out, "version")
+	return out, nil
+}
+
+// resolvedSet is the id and content of the Deployment Set a setSource refers to.
+type resolvedSet struct {
+	Id      string
+	Source  setSource
+	Content map[string]interface{}
+}
+
+func (s resolvedSet) String() string {
+	if s.Source.SetId != "" {
+		return s.Source.String()
+	}
+	return fmt.Sprintf("set '%s' from %s", s.Id, s.Source)
+}
+
+func resolveSet(ctx context.Context, hc *humanitec.WrappedHumanitecClientImpl, orgId, appId string, src setSource) (resolvedSet, error) {
+	setId, err := resolveSetId(ctx, hc, orgId, appId, src)
+	if err != nil {
+		return resolvedSet{}, err
+	}
+	content, err := getSetContent(ctx, hc, orgId, appId, setId)
+	return resolvedSet{Id: setId, Source: src, Content: content}, err
+}
+
+// setChange is a single path-level difference between two Deployment Sets.
+type setChange struct {
+	Path   string      `json:"path"`
+	Kind   string      `json:"kind"`
+	Change string      `json:"change"`
+	From   interface{} `json:"from,omitempty"`
+	To     interface{} `json:"to,omitempty"`
+}
+
+// classifySetPath returns what part of a Deployment Set a path refers to.
+func classifySetPath(path []string) string {
+	switch {
+	case len(path) == 0:
+		return "set"
+	case path[0] == "shared":
+		return "shared resource"
+	case path[0] != "modules":
+		return "other"
+	case len(path) <= 2:
+		return "module"
+	case path[2] == "externals":
+		return "resource"
+	case path[2] == "profile":
+		return "profile"
+	case len(path) == 4 && path[2] == "spec" && path[3] == "replicas":
+		return "replicas"
+	case len(path) >= 5 && path[2] == "spec" && path[3] == "containers":
+		switch {
+		case len(path) == 5:
+			return "container"
+		case path[5] == "image":
+			return "image"
+		case path[5] == "variables":
+			return "variable"
+		}
+		return "container"
+	}
+	return "module"
+}
+
+// diffSetValues appends the differences between from and to. Subtrees that only exist on one side are reported as a
+// single change at the highest path where they differ.
+func diffSetValues(path []string, from, to interface{}, out []setChange) []setChange {
+	fromMap, fromIsMap := from.(map[string]interface{})
+	toMap, toIsMap := to.(map[string]interface{})
+	if fromIsMap && toIsMap {
+		keys := slices.Sorted(maps.Keys(fromMap))
+		for k := range toMap {
+			if _, ok := fromMap[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			out = diffSetValues(append(slices.Clip(path), k), fromMap[k], toMap[k], out)
+		}
+		return out
+	}
+	change := setChange{Path: strings.Join(path, "."), Kind: classifySetPath(path), From: from, To: to}
+	switch {
+	case from == nil && to == nil:
+		return out
+	case from == nil:
+		change.Change = "added"
+	case to == nil:
+		change.Change = "removed"
+	case reflect.DeepEqual(from, to):
+		return out
+	default:
+		change.Change = "changed"
+	}
+	return append(out, change)
+}
+
+// csvValue formats a value for a csv cell, keeping strings unquoted.
+func csvValue(v interface{}) string {
+	if v == nil {
+		return ""
+	} else if s, ok := v.(string); ok {
+		return s
+	}
+	raw, _ := json.Marshal(v)
+	return string(raw)
+}
+
+func setChangesAsCsv(changes []setChange) string {
+	buff := new(bytes.Buffer)
+	w := csv.NewWriter(buff)
+	_ = w.Write([]string{"kind", "change", "path", "from", "to"})
+	for _, c := range changes {
+		_ = w.Write([]string{c.Kind, c.Change, c.Path, csvValue(c.From), csvValue(c.To)})
+	}
+	w.Flush()
+	return buff.String()
+}
+
+func setSourceSchema(description string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "object",
+		"description": description,
+		"properties": map[string]interface{}{
+			"set_id":    map[string]interface{}{"type": "string", "description": "A Humanitec Deployment Set (set) ID."},
+			"env_id":    map[string]interface{}{"type": "string", "description": "A Humanitec Environment (env) ID, the set of its latest deployment is used unless deploy_id is given."},
+			"deploy_id": map[string]interface{}{"type": "string", "description": "A Humanitec Deployment (deploy) ID within env_id."},
+		},
+		"additionalProperties": false,
+	}
+}
+
+func NewDiffHumanitecDeploymentSets() mcp.Tool {
+	return mcp.Tool{
+		Name: "diff_humanitec_deployment_sets",
+		Description: `This tool compares two Humanitec Deployment Sets of an Application and returns a path-level diff of the modules, containers, images, variables, and resources that changed.
+Each side is given as a set id, an environment id to use its latest deployment, or an environment id with a deployment id.
+Use this to explain what a deployment changed or how two environments differ. The diff is also returned as CSV which can be shown with the render_csv_as_table_in_browser tool.`,
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"org_id":        map[string]interface{}{"type": "string", "description": "The Humanitec Organization (org) ID to work with."},
+				"app_id":        map[string]interface{}{"type": "string", "description": "The Humanitec Application (app) ID to work with."},
+				"from":          setSourceSchema("The Deployment Set to compare from, usually the older one."),
+				"to":            setSourceSchema("The Deployment Set to compare to, usually the newer one."),
+				"cache_control": cacheControlProperty,
+			},
+			"required":             []string{"org_id", "app_id", "from", "to"},
+			"additionalProperties": false,
+		},
+		Callable: func(ctx context.Context, arguments map[string]interface{}) ([]mcp.CallToolResponseContent, error) {
+			orgId, _ := arguments["org_id"].(string)
+			appId, _ := arguments["app_id"].(string)
+			from, err := setSourceFromArgument("from", arguments["from"])
+			if err != nil {
+				return nil, err
+			}
+			to, err := setSourceFromArgument("to", arguments["to"])
+			if err != nil {
+				return nil, err
+			}
+
+			ctx = withCacheControl(ctx, arguments)
+			hc, err := humanitec.NewHumanitecClientWithCurrentToken(ctx)
+			if err != nil {
+				return nil, err
+			}
+			sides := make([]resolvedSet, 0, 2)
+			for _, result := range humanitec.FanOut(ctx, []setSource{from, to}, func(ctx context.Context, src setSource) (resolvedSet, error) {
+				return resolveSet(ctx, hc, orgId, appId, src)
+			}) {
+				if result.Err != nil {
+					return nil, fmt.Errorf("failed to fetch %s: %w", result.Item, result.Err)
+				}
+				sides = append(sides, result.Output)
+			}
+
+			changes := diffSetValues(nil, sides[0].Content, sides[1].Content, make([]setChange, 0))
+			if len(changes) == 0 {
+				return []mcp.CallToolResponseContent{
+					mcp.NewTextToolResponseContent("There are no differences between %s and %s.", sides[0], sides[1]),
+				}, nil
+			}
+			return []mcp.CallToolResponseContent{
+				mcp.NewTextToolResponseContent("There are %d differences from %s to %s in JSON format: %s", len(changes), sides[0], sides[1], string(internal.PrettyJson(changes))),
+				mcp.NewTextToolResponseContent("The differences as CSV: %s", setChangesAsCsv(changes)),
+			}, nil
+		},
+	}
+}
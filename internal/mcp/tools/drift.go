@@ -0,0 +1,268 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"maps"
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/humanitec/humanitec-go-autogen/client"
+
+	"github.com/humanitec/canyon-cli/internal"
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec"
+	"github.com/humanitec/canyon-cli/internal/mcp"
+)
+
+// defaultDriftIgnore are the set paths that are expected to differ between environments when no ignore list is given.
+var defaultDriftIgnore = []string{
+	"*.variables.ENV",
+	"*.variables.ENVIRONMENT",
+	"*.variables.HUMANITEC_ENV*",
+}
+
+// driftEntry is a single value of a workload that differs between environments.
+type driftEntry struct {
+	Workload string                 `json:"workload"`
+	Kind     string                 `json:"kind"`
+	Path     string                 `json:"path"`
+	Values   map[string]interface{} `json:"values"`
+}
+
+// envSet is the Deployment Set of the latest deployment of an environment.
+type envSet struct {
+	EnvId   string                 `json:"envId"`
+	SetId   string                 `json:"setId"`
+	Modules map[string]interface{} `json:"-"`
+}
+
+// flattenSetLeaves records every non-map value below v by its dotted path.
+func flattenSetLeaves(p []string, v interface{}, out map[string]interface{}) {
+	if m, ok := v.(map[string]interface{}); ok {
+		for k, child := range m {
+			flattenSetLeaves(append(slices.Clip(p), k), child, out)
+		}
+		return
+	}
+	out[strings.Join(p, ".")] = v
+}
+
+func isDriftIgnored(patterns []string, p string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// detectDrift compares the modules of each environment and returns the values that are not the same everywhere along
+// with the number of differing values that were ignored.
+func detectDrift(envs []envSet, ignore []string) ([]driftEntry, int) {
+	workloads := make(map[string]bool)
+	for _, e := range envs {
+		for w := range e.Modules {
+			workloads[w] = true
+		}
+	}
+	out := make([]driftEntry, 0)
+	ignored := 0
+	for _, w := range slices.Sorted(maps.Keys(workloads)) {
+		presence := make(map[string]interface{}, len(envs))
+		leaves := make(map[string]map[string]interface{}, len(envs))
+		for _, e := range envs {
+			if m, ok := e.Modules[w]; ok {
+				presence[e.EnvId] = "present"
+				leaves[e.EnvId] = make(map[string]interface{})
+				flattenSetLeaves([]string{"modules", w}, m, leaves[e.EnvId])
+			} else {
+				presence[e.EnvId] = "absent"
+			}
+		}
+		if len(leaves) < len(envs) {
+			out = append(out, driftEntry{Workload: w, Kind: "module", Path: "modules." + w, Values: presence})
+		}
+
+		paths := make(map[string]bool)
+		for _, l := range leaves {
+			for p := range l {
+				paths[p] = true
+			}
+		}
+		for _, p := range slices.Sorted(maps.Keys(paths)) {
+			values := make(map[string]interface{}, len(leaves))
+			for envId, l := range leaves {
+				values[envId] = l[p]
+			}
+			same := true
+			for _, v := range values {
+				for _, other := range values {
+					same = same && reflect.DeepEqual(v, other)
+				}
+			}
+			if same {
+				continue
+			} else if isDriftIgnored(ignore, p) {
+				ignored++
+				continue
+			}
+			out = append(out, driftEntry{Workload: w, Kind: classifySetPath(strings.Split(p, ".")), Path: p, Values: values})
+		}
+	}
+	return out, ignored
+}
+
+func driftAsCsv(envs []envSet, entries []driftEntry) string {
+	buff := new(bytes.Buffer)
+	w := csv.NewWriter(buff)
+	header := []string{"workload", "kind", "path"}
+	for _, e := range envs {
+		header = append(header, e.EnvId)
+	}
+	_ = w.Write(header)
+	for _, entry := range entries {
+		row := []string{entry.Workload, entry.Kind, entry.Path}
+		for _, e := range envs {
+			row = append(row, csvValue(entry.Values[e.EnvId]))
+		}
+		_ = w.Write(row)
+	}
+	w.Flush()
+	return buff.String()
+}
+
+// selectDriftEnvironments returns the environments named by id, or the environments of the given types, in the order
+// they were requested.
+func selectDriftEnvironments(environments []client.EnvironmentResponse, envIds, envTypes []string) ([]client.EnvironmentResponse, error) {
+	out := make([]client.EnvironmentResponse, 0)
+	for _, id := range envIds {
+		i := slices.IndexFunc(environments, func(e client.EnvironmentResponse) bool { return e.Id == id })
+		if i < 0 {
+			return nil, fmt.Errorf("environment '%s' does not exist in the application", id)
+		}
+		out = append(out, environments[i])
+	}
+	for _, t := range envTypes {
+		ofType := make([]client.EnvironmentResponse, 0)
+		for _, e := range environments {
+			if e.Type == t {
+				ofType = append(ofType, e)
+			}
+		}
+		slices.SortFunc(ofType, func(a, b client.EnvironmentResponse) int { return strings.Compare(a.Id, b.Id) })
+		out = append(out, ofType...)
+	}
+	return out, nil
+}
+
+func stringsFromArgument(v interface{}) []string {
+	raw, _ := v.([]interface{})
+	out := make([]string, 0, len(raw))
+	for _, r := range raw {
+		if s, ok := r.(string); ok && s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func NewDetectEnvironmentDrift() mcp.Tool {
+	return mcp.Tool{
+		Name: "detect_humanitec_environment_drift",
+		Description: fmt.Sprintf(`This tool compares the latest Deployment Sets of several Environments of a Humanitec Application and reports the drift per workload.
+Drift covers image tags, environment variables, resources, replicas, workload profiles, and any other value of a workload that is not the same in every Environment.
+Give the Environments as an ordered list of env_ids, or as an ordered list of env_types (eg: development, staging, production) to compare every Environment of those types.
+Values that legitimately differ per Environment can be excluded with the ignore argument, a list of glob patterns matched against the dotted path of a value such as 'modules.*.spec.containers.*.variables.LOG_LEVEL'. The default ignore list is %s.
+The drift is also returned as CSV with a column per Environment which can be shown with the render_csv_as_table_in_browser tool.`, strings.Join(defaultDriftIgnore, ", ")),
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"org_id":        map[string]interface{}{"type": "string", "description": "The Humanitec Organization (org) ID to work with."},
+				"app_id":        map[string]interface{}{"type": "string", "description": "The Humanitec Application (app) ID to work with."},
+				"env_ids":       map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "The ordered list of Humanitec Environment (env) IDs to compare."},
+				"env_types":     map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "The ordered list of Environment Types to compare the Environments of, instead of env_ids."},
+				"ignore":        map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "Optional glob patterns of value paths to exclude from the drift, replacing the default ignore list."},
+				"cache_control": cacheControlProperty,
+			},
+			"required":             []string{"org_id", "app_id"},
+			"additionalProperties": false,
+		},
+		Callable: func(ctx context.Context, arguments map[string]interface{}) ([]mcp.CallToolResponseContent, error) {
+			orgId, _ := arguments["org_id"].(string)
+			appId, _ := arguments["app_id"].(string)
+			envIds := stringsFromArgument(arguments["env_ids"])
+			envTypes := stringsFromArgument(arguments["env_types"])
+			if (len(envIds) == 0) == (len(envTypes) == 0) {
+				return nil, fmt.Errorf("exactly one of env_ids or env_types is required")
+			}
+			ignore := defaultDriftIgnore
+			if _, ok := arguments["ignore"]; ok {
+				ignore = stringsFromArgument(arguments["ignore"])
+			}
+			for _, pattern := range ignore {
+				if _, err := path.Match(pattern, ""); err != nil {
+					return nil, fmt.Errorf("invalid ignore pattern '%s': %w", pattern, err)
+				}
+			}
+
+			ctx = withCacheControl(ctx, arguments)
+			hc, err := humanitec.NewHumanitecClientWithCurrentToken(ctx)
+			if err != nil {
+				return nil, err
+			}
+			environments, _, err := hc.ListAllEnvironments(ctx, orgId, appId, humanitec.PageOptions{})
+			if err != nil {
+				return nil, err
+			}
+			selected, err := selectDriftEnvironments(environments, envIds, envTypes)
+			if err != nil {
+				return nil, err
+			}
+
+			warnings := make([]mcp.ToolWarning, 0)
+			deployed := make([]client.EnvironmentResponse, 0, len(selected))
+			for _, e := range selected {
+				if e.LastDeploy == nil {
+					warnings = append(warnings, mcp.NewToolWarning(e.Id, fmt.Errorf("environment has never been deployed and was not compared")))
+				} else {
+					deployed = append(deployed, e)
+				}
+			}
+			envs := make([]envSet, 0, len(deployed))
+			for _, result := range humanitec.FanOut(ctx, deployed, func(ctx context.Context, e client.EnvironmentResponse) (map[string]interface{}, error) {
+				return getSetContent(ctx, hc, orgId, appId, e.LastDeploy.SetId)
+			}) {
+				if result.Err != nil {
+					warnings = append(warnings, mcp.NewToolWarning(result.Item.Id, fmt.Errorf("failed to fetch set %s: %w", result.Item.LastDeploy.SetId, result.Err)))
+					continue
+				}
+				modules, _ := result.Output["modules"].(map[string]interface{})
+				envs = append(envs, envSet{EnvId: result.Item.Id, SetId: result.Item.LastDeploy.SetId, Modules: modules})
+			}
+			if len(envs) < 2 {
+				return nil, fmt.Errorf("at least 2 deployed environments are required to detect drift but %d were found", len(envs))
+			}
+
+			entries, ignored := detectDrift(envs, ignore)
+			workloads := make(map[string]bool)
+			for _, e := range entries {
+				workloads[e.Workload] = true
+			}
+			summary := fmt.Sprintf("Compared the Environments and their sets in order: %s. Found %d drifting values in %d workloads", string(internal.PrettyJson(envs)), len(entries), len(workloads))
+			if ignored > 0 {
+				summary += fmt.Sprintf(", %d differing values were ignored", ignored)
+			}
+			if len(entries) == 0 {
+				return []mcp.CallToolResponseContent{mcp.NewTextToolResponseContent("%s.", summary)}, mcp.AsPartialResult(warnings)
+			}
+			return []mcp.CallToolResponseContent{
+				mcp.NewTextToolResponseContent("%s. The drift in JSON format: %s", summary, string(internal.PrettyJson(entries))),
+				mcp.NewTextToolResponseContent("The drift as CSV: %s", driftAsCsv(envs, entries)),
+			}, mcp.AsPartialResult(warnings)
+		},
+	}
+}
@@ -0,0 +1,233 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/humanitec/humanitec-go-autogen/client"
+
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec"
+	"github.com/humanitec/canyon-cli/internal/mcp"
+)
+
+// setIndex is what the organization wide tools know about the content of a Deployment Set.
+type setIndex struct {
+	Workloads []workloadIndex
+	Shared    []resourceIndex
+}
+
+type workloadIndex struct {
+	Name       string
+	Profile    string
+	Containers []containerIndex
+	Externals  []resourceIndex
+	Metadata   []metadataIndex
+}
+
+type containerIndex struct {
+	Name      string
+	Image     string
+	Variables map[string]interface{}
+}
+
+type resourceIndex struct {
+	Path  string
+	Type  string
+	Class string
+}
+
+type metadataIndex struct {
+	Path   string
+	Source string
+	Key    string
+	Value  interface{}
+}
+
+// workloadMetadataPaths are the maps of a workload module whose keys are metadata keys. Modules deployed from a Score
+// file carry the annotations of its metadata in their spec annotations.
+var workloadMetadataPaths = []struct {
+	Path   []string
+	Source string
+}{
+	{Path: []string{"spec", "annotations"}, Source: "workload annotation"},
+	{Path: []string{"spec", "labels"}, Source: "workload label"},
+}
+
+// envIndex is the index of the latest Deployment Set of an Environment. The Environments of an Application are
+// indexed in the order they were created.
+type envIndex struct {
+	AppId   string
+	EnvId   string
+	EnvType string
+	SetId   string
+	*setIndex
+}
+
+// orgIndex is the index of the latest Deployment Sets of the Environments of an Organization.
+type orgIndex struct {
+	Apps int
+	Envs []envIndex
+	// Undeployed is the number of Environments that have never been deployed and so have no Deployment Set.
+	Undeployed int
+}
+
+func sortedMap(v interface{}) ([]string, map[string]interface{}) {
+	m, _ := v.(map[string]interface{})
+	return slices.Sorted(maps.Keys(m)), m
+}
+
+func nestedValue(v interface{}, p []string) interface{} {
+	for _, k := range p {
+		m, _ := v.(map[string]interface{})
+		v = m[k]
+	}
+	return v
+}
+
+func resourcesFromSet(prefix string, v interface{}) []resourceIndex {
+	keys, m := sortedMap(v)
+	out := make([]resourceIndex, 0, len(keys))
+	for _, k := range keys {
+		r, _ := m[k].(map[string]interface{})
+		resType, _ := r["type"].(string)
+		class, _ := r["class"].(string)
+		out = append(out, resourceIndex{Path: prefix + "." + k, Type: resType, Class: class})
+	}
+	return out
+}
+
+// indexSet extracts the workloads, containers, resources, and metadata of the content of a Deployment Set.
+func indexSet(content map[string]interface{}) *setIndex {
+	out := &setIndex{Workloads: make([]workloadIndex, 0), Shared: resourcesFromSet("shared", content["shared"])}
+	names, modules := sortedMap(content["modules"])
+	for _, name := range names {
+		module, _ := modules[name].(map[string]interface{})
+		w := workloadIndex{
+			Name:       name,
+			Containers: make([]containerIndex, 0),
+			Externals:  resourcesFromSet("modules."+name+".externals", module["externals"]),
+			Metadata:   make([]metadataIndex, 0),
+		}
+		w.Profile, _ = module["profile"].(string)
+		containerNames, containers := sortedMap(nestedValue(module, []string{"spec", "containers"}))
+		for _, cName := range containerNames {
+			c, _ := containers[cName].(map[string]interface{})
+			image, _ := c["image"].(string)
+			variables, _ := c["variables"].(map[string]interface{})
+			w.Containers = append(w.Containers, containerIndex{Name: cName, Image: image, Variables: variables})
+		}
+		for _, p := range workloadMetadataPaths {
+			keys, m := sortedMap(nestedValue(module, p.Path))
+			for _, k := range keys {
+				w.Metadata = append(w.Metadata, metadataIndex{
+					Path: "modules." + name + "." + strings.Join(p.Path, ".") + "." + k, Source: p.Source, Key: k, Value: m[k],
+				})
+			}
+		}
+		out.Workloads = append(out.Workloads, w)
+	}
+	return out
+}
+
+// setIndexCacheSize bounds the number of indexed Deployment Sets kept in memory.
+const setIndexCacheSize = 1000
+
+var setIndexCache = struct {
+	sync.Mutex
+	entries map[string]*setIndex
+}{entries: make(map[string]*setIndex)}
+
+// getSetIndex returns the index of a Deployment Set. Sets are content addressed so the index of a set never changes
+// and is kept in memory for live clients. Offline clients are not cached since their fixtures may reuse set ids.
+func getSetIndex(ctx context.Context, hc *humanitec.WrappedHumanitecClientImpl, orgId, appId, setId string) (*setIndex, error) {
+	key := strings.Join([]string{hc.Identity(), orgId, appId, setId}, "/")
+	if !hc.Offline() {
+		setIndexCache.Lock()
+		cached := setIndexCache.entries[key]
+		setIndexCache.Unlock()
+		if cached != nil {
+			return cached, nil
+		}
+	}
+	content, err := getSetContent(ctx, hc, orgId, appId, setId)
+	if err != nil {
+		return nil, err
+	}
+	out := indexSet(content)
+	if !hc.Offline() {
+		setIndexCache.Lock()
+		if len(setIndexCache.entries) >= setIndexCacheSize {
+			clear(setIndexCache.entries)
+		}
+		setIndexCache.entries[key] = out
+		setIndexCache.Unlock()
+	}
+	return out, nil
+}
+
+// indexOrganization indexes the latest Deployment Set of every Environment of the given Applications, or of every
+// Application when none are given. Only Environments of the env type are indexed and counted when it is given.
+// Applications and Environments that could not be read are returned as warnings.
+func indexOrganization(ctx context.Context, hc *humanitec.WrappedHumanitecClientImpl, orgId string, appIds []string, envType string) (*orgIndex, []mcp.ToolWarning, error) {
+	apps, _, err := hc.ListAllApplications(ctx, orgId, humanitec.PageOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(appIds) > 0 {
+		selected := make([]client.ApplicationResponse, 0, len(appIds))
+		for _, id := range appIds {
+			i := slices.IndexFunc(apps, func(a client.ApplicationResponse) bool { return a.Id == id })
+			if i < 0 {
+				return nil, nil, fmt.Errorf("application '%s' does not exist in the organization", id)
+			}
+			selected = append(selected, apps[i])
+		}
+		apps = selected
+	}
+
+	out := &orgIndex{Apps: len(apps), Envs: make([]envIndex, 0)}
+	warnings := make([]mcp.ToolWarning, 0)
+	for _, result := range humanitec.FanOut(ctx, apps, func(ctx context.Context, a client.ApplicationResponse) ([]client.EnvironmentResponse, error) {
+		envs, _, err := hc.ListAllEnvironments(ctx, orgId, a.Id, humanitec.PageOptions{})
+		return envs, err
+	}) {
+		if result.Err != nil {
+			warnings = append(warnings, mcp.NewToolWarning(result.Item.Id, fmt.Errorf("failed to list environments: %w", result.Err)))
+			continue
+		}
+		envs := slices.Clone(result.Output)
+		// creation order is usually the promotion order, such as development before production
+		slices.SortStableFunc(envs, func(a, b client.EnvironmentResponse) int {
+			return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), strings.Compare(a.Id, b.Id))
+		})
+		for _, e := range envs {
+			if envType != "" && e.Type != envType {
+				continue
+			} else if e.LastDeploy == nil {
+				out.Undeployed++
+				continue
+			}
+			out.Envs = append(out.Envs, envIndex{AppId: result.Item.Id, EnvId: e.Id, EnvType: e.Type, SetId: e.LastDeploy.SetId})
+		}
+	}
+
+	indexed := make([]envIndex, 0, len(out.Envs))
+	for _, result := range humanitec.FanOut(ctx, out.Envs, func(ctx context.Context, e envIndex) (*setIndex, error) {
+		return getSetIndex(ctx, hc, orgId, e.AppId, e.SetId)
+	}) {
+		if result.Err != nil {
+			warnings = append(warnings, mcp.NewToolWarning(result.Item.AppId+"/"+result.Item.EnvId, fmt.Errorf("failed to fetch set %s: %w", result.Item.SetId, result.Err)))
+			continue
+		}
+		e := result.Item
+		e.setIndex = result.Output
+		indexed = append(indexed, e)
+	}
+	out.Envs = indexed
+	return out, warnings, nil
+}
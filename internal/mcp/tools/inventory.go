@@ -0,0 +1,197 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/humanitec/canyon-cli/internal"
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec"
+	"github.com/humanitec/canyon-cli/internal/mcp"
+)
+
+// imageRef is a container image reference split into its repository, tag, and digest.
+type imageRef struct {
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+func parseImageRef(ref string) imageRef {
+	var out imageRef
+	ref, out.Digest, _ = strings.Cut(ref, "@")
+	// a colon before the last slash separates the registry port, not the tag
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		ref, out.Tag = ref[:i], ref[i+1:]
+	}
+	out.Repository = ref
+	return out
+}
+
+// imageInventoryEntry is the images of a container in each Environment column. A column holds every distinct image of
+// the Environments in it.
+type imageInventoryEntry struct {
+	AppId     string              `json:"appId"`
+	Workload  string              `json:"workload"`
+	Container string              `json:"container"`
+	Images    map[string][]string `json:"images"`
+	Skew      bool                `json:"skew,omitempty"`
+	Issues    []string            `json:"issues,omitempty"`
+}
+
+// imageColumn is an Environment column of the inventory, named by env id when inventorying a single Application and
+// by env type across the Organization since Applications name their Environments differently.
+func imageColumn(e envIndex, byType bool) string {
+	if byType {
+		return e.EnvType
+	}
+	return e.EnvId
+}
+
+// imageIssue returns the issue of the image deployed to the Environment, if any.
+func imageIssue(image, envId string) string {
+	switch ref := parseImageRef(image); {
+	case image == "":
+		return fmt.Sprintf("no image in %s", envId)
+	case ref.Tag == "" && ref.Digest == "":
+		return fmt.Sprintf("untagged image in %s", envId)
+	case ref.Tag == "latest" && ref.Digest == "":
+		return fmt.Sprintf("latest tag in %s", envId)
+	}
+	return ""
+}
+
+// buildImageInventory returns the inventory entries in Application and workload order along with the Environment
+// columns in the order they were first seen. Containers that run different images in any two Environments are marked
+// as skewed, and images without a tag or with the latest tag are reported as issues of their Environment.
+func buildImageInventory(index *orgIndex, byType bool) ([]imageInventoryEntry, []string) {
+	columns := make([]string, 0)
+	entries := make([]imageInventoryEntry, 0)
+	for _, e := range index.Envs {
+		column := imageColumn(e, byType)
+		if !slices.Contains(columns, column) {
+			columns = append(columns, column)
+		}
+		for _, w := range e.Workloads {
+			for _, c := range w.Containers {
+				i := slices.IndexFunc(entries, func(entry imageInventoryEntry) bool {
+					return entry.AppId == e.AppId && entry.Workload == w.Name && entry.Container == c.Name
+				})
+				if i < 0 {
+					entries = append(entries, imageInventoryEntry{AppId: e.AppId, Workload: w.Name, Container: c.Name, Images: make(map[string][]string)})
+					i = len(entries) - 1
+				}
+				entry := &entries[i]
+				if images := entry.Images[column]; !slices.Contains(images, c.Image) {
+					issue := fmt.Sprintf("environments of type %s run different images", column)
+					if len(images) > 0 && !slices.Contains(entry.Issues, issue) {
+						entry.Issues = append(entry.Issues, issue)
+					}
+					entry.Images[column] = append(images, c.Image)
+				}
+				if issue := imageIssue(c.Image, e.EnvId); issue != "" && !slices.Contains(entry.Issues, issue) {
+					entry.Issues = append(entry.Issues, issue)
+				}
+			}
+		}
+	}
+	for i := range entries {
+		entry := &entries[i]
+		distinct := make([]string, 0)
+		for _, column := range columns {
+			for _, image := range entry.Images[column] {
+				if !slices.Contains(distinct, image) {
+					distinct = append(distinct, image)
+				}
+			}
+		}
+		if len(distinct) > 1 {
+			entry.Skew = true
+			entry.Issues = append([]string{fmt.Sprintf("version skew across %d images", len(distinct))}, entry.Issues...)
+		}
+	}
+	slices.SortStableFunc(entries, func(a, b imageInventoryEntry) int {
+		return strings.Compare(a.AppId+"\x00"+a.Workload+"\x00"+a.Container, b.AppId+"\x00"+b.Workload+"\x00"+b.Container)
+	})
+	return entries, columns
+}
+
+func imageInventoryAsCsv(entries []imageInventoryEntry, columns []string) string {
+	buff := new(bytes.Buffer)
+	w := csv.NewWriter(buff)
+	_ = w.Write(append(append([]string{"app", "workload", "container"}, columns...), "issues"))
+	for _, entry := range entries {
+		row := []string{entry.AppId, entry.Workload, entry.Container}
+		for _, column := range columns {
+			row = append(row, strings.Join(entry.Images[column], ", "))
+		}
+		_ = w.Write(append(row, strings.Join(entry.Issues, "; ")))
+	}
+	w.Flush()
+	return buff.String()
+}
+
+func NewGetImageInventory() mcp.Tool {
+	return mcp.Tool{
+		Name: "get_humanitec_image_inventory",
+		Description: `This tool builds a matrix of the container image and tag that every workload container runs in each Environment, from the latest Deployment Sets of an Application or of the whole Organization.
+It flags version skew, where a container runs different images in different Environments, and images that are untagged or use the latest tag.
+The columns are Environment ids when an app_id is given and Environment Types across the Organization, where a column lists every image deployed to Environments of that type. Use only_issues to return just the containers with skew or image issues.
+The matrix is returned as CSV which can be shown with the render_csv_as_table_in_browser tool.`,
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"org_id":        map[string]interface{}{"type": "string", "description": "The Humanitec Organization (org) ID to work with."},
+				"app_id":        map[string]interface{}{"type": "string", "description": "Optional Humanitec Application (app) ID, the whole Organization is inventoried when it is not given."},
+				"only_issues":   map[string]interface{}{"type": "boolean", "description": "Optionally only return the containers with version skew or image issues."},
+				"cache_control": cacheControlProperty,
+			},
+			"required":             []string{"org_id"},
+			"additionalProperties": false,
+		},
+		Callable: func(ctx context.Context, arguments map[string]interface{}) ([]mcp.CallToolResponseContent, error) {
+			orgId, _ := arguments["org_id"].(string)
+			appId, _ := arguments["app_id"].(string)
+			onlyIssues, _ := arguments["only_issues"].(bool)
+
+			ctx = withCacheControl(ctx, arguments)
+			hc, err := humanitec.NewHumanitecClientWithCurrentToken(ctx)
+			if err != nil {
+				return nil, err
+			}
+			var appIds []string
+			if appId != "" {
+				appIds = []string{appId}
+			}
+			index, warnings, err := indexOrganization(ctx, hc, orgId, appIds, "")
+			if err != nil {
+				return nil, err
+			}
+
+			entries, columns := buildImageInventory(index, appId == "")
+			skewed, withIssues := 0, 0
+			for _, entry := range entries {
+				if entry.Skew {
+					skewed++
+				}
+				if len(entry.Issues) > 0 {
+					withIssues++
+				}
+			}
+			summary := fmt.Sprintf("Found %d containers in the latest Deployment Sets of %d Environments in %d Applications, %d have version skew and %d have issues", len(entries), len(index.Envs), index.Apps, skewed, withIssues)
+			if onlyIssues {
+				entries = slices.DeleteFunc(entries, func(entry imageInventoryEntry) bool { return len(entry.Issues) == 0 })
+			}
+			if len(entries) == 0 {
+				return []mcp.CallToolResponseContent{mcp.NewTextToolResponseContent("%s.", summary)}, mcp.AsPartialResult(warnings)
+			}
+			return []mcp.CallToolResponseContent{
+				mcp.NewTextToolResponseContent("%s. The inventory in JSON format: %s", summary, string(internal.PrettyJson(entries))),
+				mcp.NewTextToolResponseContent("The inventory as CSV: %s", imageInventoryAsCsv(entries, columns)),
+			}, mcp.AsPartialResult(warnings)
+		},
+	}
+}
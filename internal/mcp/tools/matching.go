@@ -0,0 +1,267 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/humanitec/humanitec-go-autogen/client"
+
+	"github.com/humanitec/canyon-cli/internal"
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec"
+	"github.com/humanitec/canyon-cli/internal/mcp"
+)
+
+// matchingContext is the resource that a Resource Definition is being selected for.
+type matchingContext struct {
+	AppId   string
+	EnvId   string
+	EnvType string
+	Type    string
+	Class   string
+	ResId   string
+}
+
+// criteriaSpecificity ranks a matching criteria the way the orchestrator does: a criteria with res_id beats one with
+// env_id, which beats env_type, which beats app_id. Criteria that set the same fields are equally specific.
+func criteriaSpecificity(c client.MatchingCriteriaResponse) int {
+	out := 0
+	for i, v := range []*string{c.ResId, c.EnvId, c.EnvType, c.AppId} {
+		if v != nil && *v != "" {
+			out |= 1 << (3 - i)
+		}
+	}
+	return out
+}
+
+func criteriaClass(c client.MatchingCriteriaResponse) string {
+	if c.Class == "" {
+		return "default"
+	}
+	return c.Class
+}
+
+func describeCriteria(c client.MatchingCriteriaResponse) string {
+	parts := make([]string, 0)
+	for _, f := range []struct {
+		name  string
+		value *string
+	}{{"res_id", c.ResId}, {"env_id", c.EnvId}, {"env_type", c.EnvType}, {"app_id", c.AppId}} {
+		if f.value != nil && *f.value != "" {
+			parts = append(parts, fmt.Sprintf("%s=%s", f.name, *f.value))
+		}
+	}
+	parts = append(parts, "class="+criteriaClass(c))
+	if c.Id != "" {
+		return fmt.Sprintf("%s (%s)", c.Id, strings.Join(parts, ", "))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// criteriaMismatches returns why a criteria does not match the context, or nothing when it matches.
+func criteriaMismatches(c client.MatchingCriteriaResponse, mc matchingContext) []string {
+	out := make([]string, 0)
+	for _, f := range []struct {
+		name   string
+		value  *string
+		actual string
+	}{{"res_id", c.ResId, mc.ResId}, {"env_id", c.EnvId, mc.EnvId}, {"env_type", c.EnvType, mc.EnvType}, {"app_id", c.AppId, mc.AppId}} {
+		if f.value != nil && *f.value != "" && *f.value != f.actual {
+			if f.actual == "" {
+				out = append(out, fmt.Sprintf("%s '%s' only matches that resource", f.name, *f.value))
+			} else {
+				out = append(out, fmt.Sprintf("%s '%s' is not '%s'", f.name, *f.value, f.actual))
+			}
+		}
+	}
+	if criteriaClass(c) != mc.Class {
+		out = append(out, fmt.Sprintf("class '%s' is not '%s'", criteriaClass(c), mc.Class))
+	}
+	return out
+}
+
+// definitionMatch is the evaluation of one Resource Definition.
+type definitionMatch struct {
+	Rank        int    `json:"rank,omitempty"`
+	DefId       string `json:"defId"`
+	Name        string `json:"name,omitempty"`
+	DriverType  string `json:"driverType"`
+	Outcome     string `json:"outcome"`
+	Criteria    string `json:"criteria,omitempty"`
+	Explanation string `json:"explanation"`
+	specificity int
+}
+
+// rankDefinitions evaluates every criteria of the definitions of the requested type and ranks the definitions with a
+// matching criteria by their most specific match. Ties for the most specific match are reported as ambiguous.
+func rankDefinitions(defs []client.ResourceDefinitionResponse, mc matchingContext) []definitionMatch {
+	matched := make([]definitionMatch, 0)
+	unmatched := make([]definitionMatch, 0)
+	for _, def := range defs {
+		if def.Type != mc.Type || def.IsDeleted {
+			continue
+		}
+		m := definitionMatch{DefId: def.Id, Name: def.Name, DriverType: def.DriverType, specificity: -1}
+		reasons := make([]string, 0)
+		for _, c := range humanitec.DerefSlice(def.Criteria) {
+			if mismatches := criteriaMismatches(c, mc); len(mismatches) > 0 {
+				reasons = append(reasons, fmt.Sprintf("%s: %s", describeCriteria(c), strings.Join(mismatches, ", ")))
+			} else if s := criteriaSpecificity(c); s > m.specificity {
+				m.specificity = s
+				m.Criteria = describeCriteria(c)
+			}
+		}
+		if m.specificity >= 0 {
+			matched = append(matched, m)
+		} else if len(reasons) == 0 {
+			m.Outcome, m.Explanation = "not matched", "the definition has no matching criteria"
+			unmatched = append(unmatched, m)
+		} else {
+			m.Outcome, m.Explanation = "not matched", "no criteria matches: "+strings.Join(reasons, "; ")
+			unmatched = append(unmatched, m)
+		}
+	}
+
+	slices.SortStableFunc(matched, func(a, b definitionMatch) int {
+		if a.specificity != b.specificity {
+			return b.specificity - a.specificity
+		}
+		return strings.Compare(a.DefId, b.DefId)
+	})
+	top := 0
+	for _, m := range matched {
+		if m.specificity == matched[0].specificity {
+			top++
+		}
+	}
+	for i := range matched {
+		m := &matched[i]
+		m.Rank = i + 1
+		if i > 0 && m.specificity == matched[i-1].specificity {
+			m.Rank = matched[i-1].Rank
+		}
+		best := matched[0]
+		switch {
+		case m.specificity < best.specificity:
+			m.Outcome = "lost"
+			m.Explanation = fmt.Sprintf("its criteria %s is less specific than %s of '%s'", m.Criteria, best.Criteria, best.DefId)
+		case top > 1:
+			m.Outcome = "ambiguous"
+			m.Explanation = fmt.Sprintf("its criteria %s is as specific as the criteria of another matching definition, so the selection is not well defined", m.Criteria)
+		default:
+			m.Outcome = "selected"
+			m.Explanation = fmt.Sprintf("its criteria %s is the most specific match", m.Criteria)
+		}
+	}
+	slices.SortStableFunc(unmatched, func(a, b definitionMatch) int { return strings.Compare(a.DefId, b.DefId) })
+	return append(matched, unmatched...)
+}
+
+func definitionMatchesAsCsv(matches []definitionMatch) string {
+	buff := new(bytes.Buffer)
+	w := csv.NewWriter(buff)
+	_ = w.Write([]string{"rank", "definition", "driver", "outcome", "criteria", "explanation"})
+	for _, m := range matches {
+		rank := ""
+		if m.Rank > 0 {
+			rank = strconv.Itoa(m.Rank)
+		}
+		_ = w.Write([]string{rank, m.DefId, m.DriverType, m.Outcome, m.Criteria, m.Explanation})
+	}
+	w.Flush()
+	return buff.String()
+}
+
+func NewExplainResourceDefinitionMatching() mcp.Tool {
+	return mcp.Tool{
+		Name: "explain_humanitec_resource_definition_matching",
+		Description: `This tool explains which Humanitec Resource Definition is selected for a resource and why the other Resource Definitions of the same type lost.
+It evaluates the matching criteria of every Resource Definition against the Application, Environment, Environment Type, resource class, and resource id, and ranks the matches by specificity the way the Platform Orchestrator does: res_id beats env_id, which beats env_type, which beats app_id.
+Use this to answer why a workload got a particular Resource Definition. The ranking is also returned as CSV which can be shown with the render_csv_as_table_in_browser tool.`,
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"org_id":        map[string]interface{}{"type": "string", "description": "The Humanitec Organization (org) ID to work with."},
+				"app_id":        map[string]interface{}{"type": "string", "description": "The Humanitec Application (app) ID to work with."},
+				"env_id":        map[string]interface{}{"type": "string", "description": "The Humanitec Environment (env) ID to work with."},
+				"type":          map[string]interface{}{"type": "string", "description": "The resource type, eg: postgres, dns, or k8s-cluster."},
+				"class":         map[string]interface{}{"type": "string", "description": "Optional resource class, defaults to 'default'."},
+				"res_id":        map[string]interface{}{"type": "string", "description": "Optional resource id, eg: modules.my-workload.externals.my-db or shared.my-dns."},
+				"cache_control": cacheControlProperty,
+			},
+			"required":             []string{"org_id", "app_id", "env_id", "type"},
+			"additionalProperties": false,
+		},
+		Callable: func(ctx context.Context, arguments map[string]interface{}) ([]mcp.CallToolResponseContent, error) {
+			orgId, _ := arguments["org_id"].(string)
+			mc := matchingContext{Class: "default"}
+			mc.AppId, _ = arguments["app_id"].(string)
+			mc.EnvId, _ = arguments["env_id"].(string)
+			mc.Type, _ = arguments["type"].(string)
+			mc.ResId, _ = arguments["res_id"].(string)
+			if v, ok := arguments["class"].(string); ok && v != "" {
+				mc.Class = v
+			}
+
+			ctx = withCacheControl(ctx, arguments)
+			hc, err := humanitec.NewHumanitecClientWithCurrentToken(ctx)
+			if err != nil {
+				return nil, err
+			}
+			env, err := humanitec.CheckResponse(func() (*client.GetEnvironmentResponse, error) {
+				return hc.GetEnvironmentWithResponse(ctx, orgId, mc.AppId, mc.EnvId)
+			}).AndStatusCodeEq(http.StatusOK).RespAndError()
+			if err != nil {
+				return nil, err
+			}
+			mc.EnvType = env.JSON200.Type
+			defs, err := humanitec.CheckResponse(func() (*client.ListResourceDefinitionsResponse, error) {
+				return hc.ListResourceDefinitionsWithResponse(ctx, orgId, &client.ListResourceDefinitionsParams{})
+			}).AndStatusCodeEq(http.StatusOK).RespAndError()
+			if err != nil {
+				return nil, err
+			}
+
+			matches := rankDefinitions(humanitec.DerefSlice(defs.JSON200), mc)
+			subject := fmt.Sprintf("resource type '%s' of class '%s' in Environment '%s' (type '%s') of Application '%s'", mc.Type, mc.Class, mc.EnvId, mc.EnvType, mc.AppId)
+			if mc.ResId != "" {
+				subject = fmt.Sprintf("resource '%s' of %s", mc.ResId, subject)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("there are no Resource Definitions of type '%s' for the %s", mc.Type, subject)
+			}
+
+			var summary string
+			if matches[0].Outcome == "selected" {
+				summary = fmt.Sprintf("Resource Definition '%s' is selected for the %s.", matches[0].DefId, subject)
+			} else if matches[0].Outcome == "ambiguous" {
+				summary = fmt.Sprintf("Several Resource Definitions match the %s equally well, so the selection is ambiguous and should be fixed by making one criteria more specific.", subject)
+			} else {
+				summary = fmt.Sprintf("No Resource Definition matches the %s, a deployment using it would fail.", subject)
+			}
+
+			warnings := make([]mcp.ToolWarning, 0)
+			if mc.ResId != "" {
+				if resources, err := hc.ListActiveResources(ctx, orgId, mc.AppId, mc.EnvId); err != nil {
+					warnings = append(warnings, mcp.NewToolWarning(mc.EnvId, fmt.Errorf("failed to fetch the active resources: %w", err)))
+				} else if i := slices.IndexFunc(resources, func(r client.ActiveResourceResponse) bool {
+					return r.ResId == mc.ResId && r.Type == mc.Type && r.Class == mc.Class
+				}); i >= 0 {
+					summary += fmt.Sprintf(" The active resource was provisioned from Resource Definition '%s' in deployment '%s'.", resources[i].DefId, resources[i].DeployId)
+					if matches[0].Outcome == "selected" && resources[i].DefId != matches[0].DefId {
+						summary += " This differs from the current matching, so the Resource Definitions or their criteria changed since then and the next deployment will reprovision the resource."
+					}
+				}
+			}
+			return []mcp.CallToolResponseContent{
+				mcp.NewTextToolResponseContent("%s The ranked Resource Definitions in JSON format: %s", summary, string(internal.PrettyJson(matches))),
+				mcp.NewTextToolResponseContent("The ranked Resource Definitions as CSV: %s", definitionMatchesAsCsv(matches)),
+			}, mcp.AsPartialResult(warnings)
+		},
+	}
+}
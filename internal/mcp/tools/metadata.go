@@ -0,0 +1,198 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/humanitec/humanitec-go-autogen/client"
+
+	"github.com/humanitec/canyon-cli/internal"
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec"
+	"github.com/humanitec/canyon-cli/internal/mcp"
+)
+
+const (
+	// metadataKeysCacheTTL is how long the metadata keys of an Organization are reused before they are discovered again.
+	metadataKeysCacheTTL = 5 * time.Minute
+	// maxMetadataExamples is the number of distinct example values returned per key.
+	maxMetadataExamples = 3
+	// maxMetadataEntities is the number of entities returned per key, the count includes all of them.
+	maxMetadataEntities = 10
+)
+
+// metadataKeyUsage is a metadata key found in the Organization and where it is used.
+type metadataKeyUsage struct {
+	Key      string        `json:"key"`
+	Count    int           `json:"count"`
+	Sources  []string      `json:"sources"`
+	Examples []interface{} `json:"examples"`
+	Entities []string      `json:"entities"`
+}
+
+// metadataKeysCollector accumulates the usages of metadata keys.
+type metadataKeysCollector map[string]*metadataKeyUsage
+
+func (c metadataKeysCollector) add(key, source, entity string, value interface{}) {
+	u, ok := c[key]
+	if !ok {
+		u = &metadataKeyUsage{Key: key, Sources: make([]string, 0), Examples: make([]interface{}, 0), Entities: make([]string, 0)}
+		c[key] = u
+	}
+	u.Count++
+	if !slices.Contains(u.Sources, source) {
+		u.Sources = append(u.Sources, source)
+	}
+	if len(u.Examples) < maxMetadataExamples && !slices.ContainsFunc(u.Examples, func(e interface{}) bool { return reflect.DeepEqual(e, value) }) {
+		u.Examples = append(u.Examples, value)
+	}
+	if len(u.Entities) < maxMetadataEntities && !slices.Contains(u.Entities, entity) {
+		u.Entities = append(u.Entities, entity)
+	}
+}
+
+// usages returns the keys by descending count and then by name.
+func (c metadataKeysCollector) usages() []metadataKeyUsage {
+	out := make([]metadataKeyUsage, 0, len(c))
+	for _, key := range slices.Sorted(maps.Keys(c)) {
+		out = append(out, *c[key])
+	}
+	slices.SortStableFunc(out, func(a, b metadataKeyUsage) int { return b.Count - a.Count })
+	return out
+}
+
+// resourceDefinitionsMetadata returns the metadata of each Resource Definition by id. The metadata is not part of the
+// generated client so it is read from the raw response.
+func resourceDefinitionsMetadata(ctx context.Context, hc *humanitec.WrappedHumanitecClientImpl, orgId string) (map[string]map[string]interface{}, error) {
+	r, err := humanitec.CheckResponse(func() (*client.ListResourceDefinitionsResponse, error) {
+		return hc.ListResourceDefinitionsWithResponse(ctx, orgId, &client.ListResourceDefinitionsParams{})
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	if err != nil {
+		return nil, err
+	}
+	var defs []struct {
+		Id       string                 `json:"id"`
+		Metadata map[string]interface{} `json:"metadata"`
+	}
+	if err := json.Unmarshal(r.Body, &defs); err != nil {
+		return nil, fmt.Errorf("failed to decode resource definitions: %w", err)
+	}
+	out := make(map[string]map[string]interface{}, len(defs))
+	for _, d := range defs {
+		out[d.Id] = d.Metadata
+	}
+	return out, nil
+}
+
+// discoverMetadataKeys scans the workloads of the latest Deployment Sets and the Resource Definitions of the
+// Organization for metadata keys.
+func discoverMetadataKeys(ctx context.Context, hc *humanitec.WrappedHumanitecClientImpl, orgId string) ([]metadataKeyUsage, []mcp.ToolWarning, error) {
+	index, warnings, err := indexOrganization(ctx, hc, orgId, nil, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	collector := make(metadataKeysCollector)
+	for _, e := range index.Envs {
+		for _, w := range e.Workloads {
+			for _, m := range w.Metadata {
+				collector.add(m.Key, m.Source, fmt.Sprintf("workload %s/%s/%s", e.AppId, e.EnvId, w.Name), m.Value)
+			}
+		}
+	}
+	if defs, err := resourceDefinitionsMetadata(ctx, hc, orgId); err != nil {
+		warnings = append(warnings, mcp.NewToolWarning("resource definitions", err))
+	} else {
+		for _, defId := range slices.Sorted(maps.Keys(defs)) {
+			for _, key := range slices.Sorted(maps.Keys(defs[defId])) {
+				collector.add(key, "resource definition", "resource definition "+defId, defs[defId][key])
+			}
+		}
+	}
+	return collector.usages(), warnings, nil
+}
+
+type metadataKeysCacheEntry struct {
+	At   time.Time
+	Keys []metadataKeyUsage
+}
+
+var metadataKeysCache = struct {
+	sync.Mutex
+	entries map[string]metadataKeysCacheEntry
+}{entries: make(map[string]metadataKeysCacheEntry)}
+
+// getMetadataKeys returns the metadata keys of the Organization, reusing the keys discovered for live clients within
+// the last metadataKeysCacheTTL unless the cache is bypassed. Partial results are not cached.
+func getMetadataKeys(ctx context.Context, hc *humanitec.WrappedHumanitecClientImpl, orgId string, bypass bool) ([]metadataKeyUsage, time.Time, []mcp.ToolWarning, error) {
+	key := hc.Identity() + "/" + orgId
+	if !hc.Offline() && !bypass {
+		metadataKeysCache.Lock()
+		entry, ok := metadataKeysCache.entries[key]
+		metadataKeysCache.Unlock()
+		if ok && time.Since(entry.At) < metadataKeysCacheTTL {
+			return entry.Keys, entry.At, nil, nil
+		}
+	}
+	now := time.Now()
+	keys, warnings, err := discoverMetadataKeys(ctx, hc, orgId)
+	if err != nil {
+		return nil, now, nil, err
+	}
+	if !hc.Offline() && len(warnings) == 0 {
+		metadataKeysCache.Lock()
+		metadataKeysCache.entries[key] = metadataKeysCacheEntry{At: now, Keys: keys}
+		metadataKeysCache.Unlock()
+	}
+	return keys, now, warnings, nil
+}
+
+func NewListMetadataKeys() mcp.Tool {
+	return mcp.Tool{
+		Name: "list_organization_metadata_keys",
+		Description: fmt.Sprintf(`This tool discovers the metadata keys used in a Humanitec Organization, such as the owning team, source repository, or dashboard url of a workload or resource.
+It scans the workload annotations, which include the metadata annotations of Score files, and labels in the latest Deployment Set of every Environment, and the metadata of every Resource Definition.
+Each key has its usage count, the sources it was found in, up to %d example values, and up to %d of the entities that carry it. Workload entities are named app/env/workload.
+The keys are cached per Organization for %d minutes, use cache_control to discover them again.
+Use the search_humanitec_organization tool with metadata_key to find every workload that carries a key.`, maxMetadataExamples, maxMetadataEntities, int(metadataKeysCacheTTL.Minutes())),
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"org_id":        map[string]interface{}{"type": "string", "description": "The Humanitec Organization (org) ID to work with."},
+				"cache_control": cacheControlProperty,
+			},
+			"required":             []string{"org_id"},
+			"additionalProperties": false,
+		},
+		Callable: func(ctx context.Context, arguments map[string]interface{}) ([]mcp.CallToolResponseContent, error) {
+			orgId, _ := arguments["org_id"].(string)
+			ctx = withCacheControl(ctx, arguments)
+			hc, err := humanitec.NewHumanitecClientWithCurrentToken(ctx)
+			if err != nil {
+				return nil, err
+			}
+			cacheControl, _ := arguments["cache_control"].(string)
+			keys, at, warnings, err := getMetadataKeys(ctx, hc, orgId, cacheControl == "bypass")
+			if err != nil {
+				return nil, err
+			}
+			summary := fmt.Sprintf("Found %d metadata keys in Organization '%s' as of %s", len(keys), orgId, at.UTC().Format(time.RFC3339))
+			if len(keys) == 0 {
+				return []mcp.CallToolResponseContent{mcp.NewTextToolResponseContent("%s.", summary)}, mcp.AsPartialResult(warnings)
+			}
+			names := make([]string, 0, len(keys))
+			for _, k := range keys {
+				names = append(names, k.Key)
+			}
+			return []mcp.CallToolResponseContent{
+				mcp.NewTextToolResponseContent("%s: %s. The keys in JSON format: %s", summary, strings.Join(names, ", "), string(internal.PrettyJson(keys))),
+			}, mcp.AsPartialResult(warnings)
+		},
+	}
+}
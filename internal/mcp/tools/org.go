@@ -7,7 +7,6 @@ import (
 	"net/http"
 	"regexp"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/humanitec/humanitec-go-autogen/client"
@@ -23,18 +22,71 @@ func NewListHumanitecOrgsAndSession() mcp.Tool {
 		Description: `This tool checks whether the local humctl (Humanitec CLI) tool has a valid and non-expired session.
 This tool should be used if you don't know whether the user has a valid session or if other related tool commands return errors indicating the user is not authenticated.
 This tool also returns the list of Organizations that the user has access to including their role in the Organization.
+It reports the active profile, the token type and expiry time, and the reason when the session cannot be used.
 `,
-		InputSchema: map[string]interface{}{"type": "object", "additionalProperties": false},
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"cache_control": cacheControlProperty,
+			},
+			"additionalProperties": false,
+		},
 		Callable: func(ctx context.Context, m map[string]interface{}) ([]mcp.CallToolResponseContent, error) {
-			hc, err := humanitec.NewHumanitecClientWithCurrentToken(ctx)
+			ctx = withCacheControl(ctx, m)
+
+			type sessionState struct {
+				Profile     string              `json:"profile"`
+				DefaultOrg  string              `json:"default_org,omitempty"`
+				DefaultApp  string              `json:"default_app,omitempty"`
+				DefaultEnv  string              `json:"default_env,omitempty"`
+				Token       humanitec.TokenInfo `json:"token"`
+				ExpiresSoon bool                `json:"expires_soon,omitempty"`
+				Status      string              `json:"status"`
+				Reason      string              `json:"reason,omitempty"`
+			}
+			session := sessionState{Status: "unknown"}
+			sessionError := func(status string, err error) ([]mcp.CallToolResponseContent, error) {
+				session.Status = status
+				session.Reason = err.Error()
+				return []mcp.CallToolResponseContent{
+					mcp.NewTextToolResponseContent("The session is not usable. The session details in JSON format are: %s", internal.PrettyJson(session)),
+				}, err
+			}
+
+			profile, err := humanitec.ActiveProfile()
 			if err != nil {
 				return nil, err
 			}
+			session.Profile, session.DefaultOrg, session.DefaultApp, session.DefaultEnv = profile.Name, profile.DefaultOrg, profile.DefaultApp, profile.DefaultEnv
+			token, err := profile.ResolveToken()
+			if err != nil {
+				return sessionError("invalid_profile", err)
+			}
+			session.Token = humanitec.InspectToken(token)
+			session.ExpiresSoon = session.Token.ExpiresWithin(humanitec.TokenExpiryWarningPeriod, time.Now())
+
+			hc, err := humanitec.NewHumanitecClientWithCurrentToken(ctx)
+			if err != nil {
+				if session.Token.Type == humanitec.TokenTypeNone {
+					return sessionError("not_logged_in", err)
+				} else if session.Token.IsExpired(time.Now()) {
+					return sessionError("expired", err)
+				}
+				return sessionError("error", err)
+			}
 			if r, err := humanitec.CheckResponse(func() (*client.GetCurrentUserResponse, error) {
 				return hc.GetCurrentUserWithResponse(ctx)
 			}).AndStatusCodeEq(http.StatusOK).RespAndError(); err != nil {
-				return nil, err
+				switch {
+				case r != nil && r.HTTPResponse != nil && r.StatusCode() == http.StatusUnauthorized:
+					return sessionError("unauthorized", err)
+				case r != nil && r.HTTPResponse != nil && r.StatusCode() == http.StatusForbidden:
+					return sessionError("forbidden", err)
+				default:
+					return sessionError("error", err)
+				}
 			} else {
+				session.Status = "authenticated"
 				out := make(map[string]string)
 				seenOrgs := make(map[string]bool)
 				for obj, role := range r.JSON200.Roles {
@@ -48,10 +100,30 @@ This tool also returns the list of Organizations that the user has access to inc
 					}
 				}
 				rawOrgs := internal.PrettyJson(out)
-				return []mcp.CallToolResponseContent{mcp.NewTextToolResponseContent(`The user is currently logged in. The following JSON is map from Humanitec Organization to Role:
+				sessionText := fmt.Sprintf("The active credential profile is '%s'.", session.Profile)
+				if session.DefaultOrg != "" {
+					sessionText += fmt.Sprintf(" The default Organization is '%s'.", session.DefaultOrg)
+				}
+				if session.DefaultApp != "" {
+					sessionText += fmt.Sprintf(" The default Application is '%s'.", session.DefaultApp)
+				}
+				if session.DefaultEnv != "" {
+					sessionText += fmt.Sprintf(" The default Environment is '%s'.", session.DefaultEnv)
+				}
+				if hc.Offline() {
+					sessionText += " Responses are served offline from recorded, demo, or snapshot data rather than the live Humanitec API, so changes made elsewhere are not visible."
+				} else if session.Token.ExpiresAt != nil {
+					sessionText += fmt.Sprintf(" The %s token expires at %s.", session.Token.Type, session.Token.ExpiresAt.Format(time.RFC3339))
+					if session.ExpiresSoon {
+						sessionText += " This is soon, so the user should be warned to run 'humctl login' to renew their session."
+					}
+				} else {
+					sessionText += fmt.Sprintf(" The %s token has no known expiry.", session.Token.Type)
+				}
+				return []mcp.CallToolResponseContent{mcp.NewTextToolResponseContent(`The user is currently logged in. %s The following JSON is map from Humanitec Organization to Role:
 %s
 'administrators' can take all actions in the Organization, 'managers' may create applications and manage users, 'members' only have access to an application level, 'org_viewers' have read access to the whole Organization.`,
-					string(rawOrgs),
+					sessionText, string(rawOrgs),
 				)}, nil
 			}
 		},
@@ -63,18 +135,23 @@ func NewListAppsAndEnvsForOrganization() mcp.Tool {
 		Name: "list_apps_and_envs_for_humanitec_organization",
 		Description: `This tool returns the Applications within the specified Humanitec Organization. It also includes the Environments within each Application including the latest deployment state and status.
 An optional app_id regex argument can filter Application Ids, while the env_type argument can filter by Environment Type (eg: development, staging, production).
+Large Organizations can be listed in batches with the limit and cursor arguments, the limit counts the Applications that match app_id.
 `,
 		InputSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
-				"org_id":   map[string]interface{}{"type": "string", "description": "The Humanitec Organization (org) ID to work with."},
-				"app_id":   map[string]interface{}{"type": "string", "description": "Optional regex pattern to filter for app id"},
-				"env_type": map[string]interface{}{"type": "string", "description": "Optional filter for a specific environment type"},
+				"org_id":        map[string]interface{}{"type": "string", "description": "The Humanitec Organization (org) ID to work with."},
+				"app_id":        map[string]interface{}{"type": "string", "description": "Optional regex pattern to filter for app id"},
+				"env_type":      map[string]interface{}{"type": "string", "description": "Optional filter for a specific environment type"},
+				"limit":         limitProperty,
+				"cursor":        cursorProperty,
+				"cache_control": cacheControlProperty,
 			},
 			"required":             []string{"org_id"},
 			"additionalProperties": false,
 		},
 		Callable: func(ctx context.Context, m map[string]interface{}) ([]mcp.CallToolResponseContent, error) {
+			ctx = withCacheControl(ctx, m)
 			hc, err := humanitec.NewHumanitecClientWithCurrentToken(ctx)
 			if err != nil {
 				return nil, fmt.Errorf("unable to create Humanitec client: %w", err)
@@ -90,9 +167,10 @@ An optional app_id regex argument can filter Application Ids, while the env_type
 			}
 			envTypeFilter, _ := m["env_type"].(string)
 
-			if r, err := humanitec.CheckResponse(func() (*client.ListApplicationsResponse, error) {
-				return hc.ListApplicationsWithResponse(ctx, orgId)
-			}).AndStatusCodeEq(http.StatusOK).RespAndError(); err != nil {
+			// the app_id pattern is applied while paging so that limit counts the matching Applications
+			if apps, nextCursor, err := hc.ListMatchingApplications(ctx, orgId, pageOptionsFromArguments(m), func(app client.ApplicationResponse) bool {
+				return appIdPattern == nil || appIdPattern.MatchString(app.Id)
+			}); err != nil {
 				return nil, err
 			} else {
 
@@ -111,68 +189,53 @@ An optional app_id regex argument can filter Application Ids, while the env_type
 					CreatedTime  string              `json:"createdTime"`
 				}
 
-				apps := new(sync.Map)
-				{
-					wg := new(sync.WaitGroup)
-					sem := make(chan struct{}, 10)
-					for _, app := range *r.JSON200 {
-						if appIdPattern != nil && !appIdPattern.MatchString(app.Id) {
+				results := humanitec.FanOut(ctx, apps, func(ctx context.Context, app client.ApplicationResponse) (appstate, error) {
+					environments, _, err := hc.ListAllEnvironments(ctx, orgId, app.Id, humanitec.PageOptions{})
+					if err != nil {
+						return appstate{}, err
+					}
+					envs := make(map[string]envstate)
+					for _, e := range environments {
+						if envTypeFilter != "" && e.Type != envTypeFilter {
 							continue
 						}
-						wg.Add(1)
-						sem <- struct{}{}
-						go func() {
-							defer wg.Done()
-							defer func() { <-sem }()
-							if r, err := humanitec.CheckResponse(func() (*client.ListEnvironmentsResponse, error) {
-								return hc.ListEnvironmentsWithResponse(ctx, orgId, app.Id)
-							}).AndStatusCodeEq(http.StatusOK).RespAndError(); err != nil {
-								apps.Store(app.Id, err.Error())
-							} else {
-								envs := make(map[string]envstate)
-								for _, e := range *r.JSON200 {
-									if envTypeFilter != "" && e.Type != envTypeFilter {
-										continue
-									}
-									es := envstate{
-										Name:        e.Name,
-										Type:        e.Type,
-										CreatedTime: e.CreatedAt,
-									}
-									if e.LastDeploy != nil {
-										es.LastDeploymentId = e.LastDeploy.Id
-										es.LastDeploymentSet = e.LastDeploy.SetId
-										es.LastDeploymentTime = e.LastDeploy.CreatedAt
-									}
-									envs[e.Id] = es
-								}
-								apps.Store(app.Id, appstate{
-									Name:         app.Name,
-									CreatedTime:  app.CreatedAt,
-									Environments: envs,
-								})
-							}
-						}()
+						es := envstate{
+							Name:        e.Name,
+							Type:        e.Type,
+							CreatedTime: e.CreatedAt,
+						}
+						if e.LastDeploy != nil {
+							es.LastDeploymentId = e.LastDeploy.Id
+							es.LastDeploymentSet = e.LastDeploy.SetId
+							es.LastDeploymentTime = e.LastDeploy.CreatedAt
+						}
+						envs[e.Id] = es
 					}
-					wg.Wait()
-				}
+					return appstate{
+						Name:         app.Name,
+						CreatedTime:  app.CreatedAt,
+						Environments: envs,
+					}, nil
+				})
 
 				out := make(map[string]appstate)
-				apps.Range(func(key, value any) bool {
-					if e, ok := value.(error); ok {
-						err = errors.Join(err, fmt.Errorf("failed to fetch app '%s': %w", key, e))
-					} else if a, ok := value.(appstate); ok {
-						out[key.(string)] = a
+				warnings := make([]mcp.ToolWarning, 0)
+				for _, result := range results {
+					if result.Err != nil {
+						e := fmt.Errorf("failed to fetch app '%s': %w", result.Item.Id, result.Err)
+						err = errors.Join(err, e)
+						warnings = append(warnings, mcp.NewToolWarning(result.Item.Id, e))
+					} else {
+						out[result.Item.Id] = result.Output
 					}
-					return true
-				})
+				}
 
-				if err != nil {
+				if err != nil && len(out) == 0 {
 					return nil, err
 				}
 
 				rawApps := internal.PrettyJson(out)
-				return []mcp.CallToolResponseContent{mcp.NewTextToolResponseContent("The user is has access to the following Humanitec Applications with Organization '%s' in JSON format: %s", orgId, string(rawApps))}, nil
+				return []mcp.CallToolResponseContent{mcp.NewTextToolResponseContent("The user is has access to the following Humanitec Applications with Organization '%s' in JSON format: %s%s", orgId, string(rawApps), nextCursorText(nextCursor))}, mcp.AsPartialResult(warnings)
 			}
 		},
 	}
@@ -188,10 +251,12 @@ The profile schema includes the set of properties supported in Workloads specs t
 		InputSchema: map[string]interface{}{"type": "object", "properties": map[string]interface{}{
 			"org_id":              map[string]interface{}{"type": "string", "description": "The Humanitec Organization (org) ID to work with."},
 			"workload_profile_id": map[string]interface{}{"type": "string", "description": "The Humanitec Workload Profile (profile) ID to work with."},
+			"cache_control":       cacheControlProperty,
 		}, "required": []string{"org_id", "workload_profile_id"}},
 		Callable: func(ctx context.Context, arguments map[string]interface{}) ([]mcp.CallToolResponseContent, error) {
 			orgId := arguments["org_id"].(string)
 			workloadProfileId := arguments["workload_profile_id"].(string)
+			ctx = withCacheControl(ctx, arguments)
 			hc, err := humanitec.NewHumanitecClientWithCurrentToken(ctx)
 			if err != nil {
 				return nil, err
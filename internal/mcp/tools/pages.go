@@ -0,0 +1,38 @@
+package tools
+
+import (
+	"fmt"
+
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec"
+)
+
+// limitProperty and cursorProperty are the input schema properties accepted by list-based tools.
+var (
+	limitProperty = map[string]interface{}{
+		"type":        "integer",
+		"minimum":     1,
+		"description": "Optional maximum number of items to list in this call. When more items exist, a cursor is returned to continue from.",
+	}
+	cursorProperty = map[string]interface{}{
+		"type":        "string",
+		"description": "Optional cursor returned by a previous call to continue listing from where it stopped.",
+	}
+)
+
+// pageOptionsFromArguments reads the limit and cursor arguments.
+func pageOptionsFromArguments(arguments map[string]interface{}) humanitec.PageOptions {
+	out := humanitec.PageOptions{}
+	if v, ok := arguments["limit"].(float64); ok && v > 0 {
+		out.Limit = int(v)
+	}
+	out.Cursor, _ = arguments["cursor"].(string)
+	return out
+}
+
+// nextCursorText describes how to continue listing when a cursor was returned.
+func nextCursorText(cursor string) string {
+	if cursor == "" {
+		return ""
+	}
+	return fmt.Sprintf(" More items are available, call this tool again with cursor '%s' to continue.", cursor)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -18,51 +19,70 @@ func NewListPathsTool() mcp.Tool {
 		Description: `Returns a list of 'paths' supported by the canyon MCP server.
 Paths are remote functions which can be used to query or achieve a wide array of functionality.
 The list of available paths may change over time so consider listing the available paths when there is low confidence that an existing paths can be used to solve the user query.
-Canyon paths are not tools themselves and must be called through the call-canyon-path tool.`,
+Canyon paths are not tools themselves and must be called through the call-canyon-path tool.
+Large numbers of paths can be listed in batches with the limit and cursor arguments.`,
 		InputSchema: map[string]interface{}{
 			"type":     "object",
 			"required": []interface{}{"org_id"},
 			"properties": map[string]interface{}{
-				"org_id": map[string]interface{}{"type": "string", "description": "The organization ID"},
+				"org_id":        map[string]interface{}{"type": "string", "description": "The organization ID"},
+				"limit":         limitProperty,
+				"cursor":        cursorProperty,
+				"cache_control": cacheControlProperty,
 			}},
 		Callable: func(ctx context.Context, arguments map[string]interface{}) ([]mcp.CallToolResponseContent, error) {
+			ctx = withCacheControl(ctx, arguments)
 			hc, err := humanitec.NewHumanitecClientWithCurrentToken(ctx)
 			if err != nil {
 				return nil, err
 			}
 
 			var tools []mcp.ToolResponse
-			if sum, err := hc.ListActionPipelineSummaries(ctx, arguments["org_id"].(string)); err != nil {
-				return nil, err
-			} else if sum.JSON200 == nil {
+			var cursorText string
+			var errs error
+			warnings := make([]mcp.ToolWarning, 0)
+			if summaries, nextCursor, err := hc.ListAllActionPipelineSummaries(ctx, arguments["org_id"].(string), pageOptionsFromArguments(arguments)); err != nil {
 				// This is a hack for demos while the action pipelines are feature flagged off
-				if sum.StatusCode() == http.StatusForbidden || sum.StatusCode() == http.StatusMethodNotAllowed {
+				if he := (*humanitec.Error)(nil); errors.As(err, &he) && (he.StatusCode == http.StatusForbidden || he.StatusCode == http.StatusMethodNotAllowed) {
 					return []mcp.CallToolResponseContent{
 						mcp.NewTextToolResponseContent("There are no paths available in this org"),
 					}, nil
 				}
-				return nil, fmt.Errorf("unexpected response from humanitec: %s %s", sum.HTTPResponse.Status, string(sum.Body))
+				return nil, err
 			} else {
-				for _, summary := range sum.JSON200 {
-
+				cursorText = nextCursorText(nextCursor)
+				results := humanitec.FanOut(ctx, summaries, func(ctx context.Context, summary humanitec.ActionPipelineSummary) (*humanitec.ActionPipeline, error) {
 					if ap, err := hc.GetActionPipeline(ctx, summary.OrgId, summary.Id); err != nil {
 						return nil, err
 					} else if ap.JSON200 == nil {
 						return nil, fmt.Errorf("unexpected response from humanitec: %v", ap)
 					} else {
-						tools = append(tools, mcp.ToolResponse{
-							Name:        ap.JSON200.Id,
-							Description: ap.JSON200.Description,
-							InputSchema: ap.JSON200.InputsJsonSchema,
-						})
+						return ap.JSON200, nil
 					}
+				})
+				for _, result := range results {
+					if result.Err != nil {
+						e := fmt.Errorf("failed to fetch path '%s': %w", result.Item.Id, result.Err)
+						errs = errors.Join(errs, e)
+						warnings = append(warnings, mcp.NewToolWarning(result.Item.Id, e))
+						continue
+					}
+					tools = append(tools, mcp.ToolResponse{
+						Name:        result.Output.Id,
+						Description: result.Output.Description,
+						InputSchema: result.Output.InputsJsonSchema,
+					})
 				}
 			}
 
+			if errs != nil && len(tools) == 0 {
+				return nil, errs
+			}
+
 			raw := internal.PrettyJson(tools)
 			return []mcp.CallToolResponseContent{
-				mcp.NewTextToolResponseContent("Here's an array of the current canyon tools in JSON: %s", string(raw)),
-			}, nil
+				mcp.NewTextToolResponseContent("Here's an array of the current canyon tools in JSON: %s%s", string(raw), cursorText),
+			}, mcp.AsPartialResult(warnings)
 		},
 	}
 }
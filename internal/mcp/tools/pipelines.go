@@ -0,0 +1,785 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/humanitec/humanitec-go-autogen/client"
+
+	"github.com/humanitec/canyon-cli/internal"
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec"
+	"github.com/humanitec/canyon-cli/internal/mcp"
+)
+
+// defaultPipelineRunsLimit is the number of pipeline runs returned when no limit is given.
+const defaultPipelineRunsLimit = 20
+
+// pipelinePollInterval is how often the progress of a followed pipeline run is checked.
+var pipelinePollInterval = 2 * time.Second
+
+var pipelineRunStatuses = []string{"queued", "executing", "cancelling", "cancelled", "succeeded", "failed", "timed out"}
+
+// pipelineSummary is the representation of a Pipeline returned by the pipeline tools.
+type pipelineSummary struct {
+	AppId        string    `json:"appId"`
+	Id           string    `json:"id"`
+	Name         string    `json:"name"`
+	Status       string    `json:"status"`
+	Version      string    `json:"version"`
+	TriggerTypes []string  `json:"triggerTypes"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// pipelineRunSummary is the representation of a pipeline run returned by the pipeline tools. Jobs and pending
+// approvals are only filled in for a single run.
+type pipelineRunSummary struct {
+	Id               string                    `json:"id"`
+	PipelineId       string                    `json:"pipelineId"`
+	Status           string                    `json:"status"`
+	StatusMessage    string                    `json:"statusMessage,omitempty"`
+	EnvIds           []string                  `json:"envIds,omitempty"`
+	Inputs           map[string]interface{}    `json:"inputs,omitempty"`
+	CreatedBy        string                    `json:"createdBy"`
+	CreatedAt        time.Time                 `json:"createdAt"`
+	CompletedAt      *time.Time                `json:"completedAt,omitempty"`
+	Duration         string                    `json:"duration,omitempty"`
+	Jobs             []pipelineJobSummary      `json:"jobs,omitempty"`
+	PendingApprovals []pipelineApprovalSummary `json:"pendingApprovals,omitempty"`
+}
+
+type pipelineJobSummary struct {
+	Id            string                `json:"id"`
+	Status        string                `json:"status"`
+	StatusMessage string                `json:"statusMessage,omitempty"`
+	Steps         []pipelineStepSummary `json:"steps,omitempty"`
+}
+
+type pipelineStepSummary struct {
+	Index         int    `json:"index"`
+	Name          string `json:"name"`
+	Uses          string `json:"uses"`
+	Status        string `json:"status"`
+	StatusMessage string `json:"statusMessage,omitempty"`
+	Duration      string `json:"duration,omitempty"`
+}
+
+type pipelineApprovalSummary struct {
+	Id         string    `json:"id"`
+	PipelineId string    `json:"pipelineId"`
+	RunId      string    `json:"runId"`
+	JobId      string    `json:"jobId"`
+	EnvId      string    `json:"envId,omitempty"`
+	Message    string    `json:"message"`
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+func newPipelineRunSummary(r client.PipelineRun) pipelineRunSummary {
+	out := pipelineRunSummary{
+		Id:            r.Id,
+		PipelineId:    r.PipelineId,
+		Status:        r.Status,
+		StatusMessage: r.StatusMessage,
+		EnvIds:        r.EnvIds,
+		Inputs:        r.Inputs,
+		CreatedBy:     r.CreatedBy,
+		CreatedAt:     r.CreatedAt,
+		CompletedAt:   r.CompletedAt,
+	}
+	if r.CompletedAt != nil && r.CompletedAt.After(r.CreatedAt) {
+		out.Duration = r.CompletedAt.Sub(r.CreatedAt).Round(time.Second).String()
+	}
+	return out
+}
+
+func newPipelineJobSummary(j client.PipelineJob) pipelineJobSummary {
+	out := pipelineJobSummary{Id: j.Id, Status: j.Status, StatusMessage: j.StatusMessage, Steps: make([]pipelineStepSummary, 0, len(j.Steps))}
+	for _, s := range j.Steps {
+		step := pipelineStepSummary{Index: s.Index, Name: s.Name, Uses: s.Uses, Status: s.Status, StatusMessage: s.StatusMessage}
+		if s.CompletedAt != nil && s.CompletedAt.After(s.CreatedAt) {
+			step.Duration = s.CompletedAt.Sub(s.CreatedAt).Round(time.Second).String()
+		}
+		out.Steps = append(out.Steps, step)
+	}
+	return out
+}
+
+func newPipelineApprovalSummary(a client.PipelineApprovalRequest) pipelineApprovalSummary {
+	return pipelineApprovalSummary{
+		Id: a.Id, PipelineId: a.PipelineId, RunId: a.RunId, JobId: a.JobId, EnvId: a.EnvId, Message: a.Message, Status: string(a.Status), CreatedAt: a.CreatedAt,
+	}
+}
+
+// progress describes in one sentence how far the run got.
+func (s pipelineRunSummary) progress() string {
+	done := 0
+	var current *pipelineStepSummary
+	currentJob := ""
+	for _, j := range s.Jobs {
+		for i, step := range j.Steps {
+			if step.Status == "succeeded" {
+				done++
+			} else if current == nil {
+				current, currentJob = &j.Steps[i], j.Id
+			}
+		}
+	}
+	switch {
+	case s.CompletedAt != nil && s.StatusMessage != "":
+		return fmt.Sprintf("Pipeline run '%s' %s after %d steps: %s.", s.Id, s.Status, done, strings.TrimSuffix(s.StatusMessage, "."))
+	case s.CompletedAt != nil:
+		return fmt.Sprintf("Pipeline run '%s' %s after %d steps.", s.Id, s.Status, done)
+	case len(s.PendingApprovals) > 0:
+		a := s.PendingApprovals[0]
+		return fmt.Sprintf("Pipeline run '%s' is waiting for approval request '%s' of job '%s' after %d steps: %s", s.Id, a.Id, a.JobId, done, a.Message)
+	case current != nil:
+		return fmt.Sprintf("Pipeline run '%s' is %s, step '%s' of job '%s' is %s after %d steps.", s.Id, s.Status, current.Name, currentJob, current.Status, done)
+	}
+	return fmt.Sprintf("Pipeline run '%s' is %s after %d steps.", s.Id, s.Status, done)
+}
+
+// getPipelineRunDetails returns the run with its jobs, their steps, and its pending approval requests. Jobs whose steps
+// could not be fetched are returned without steps and with a warning.
+func getPipelineRunDetails(ctx context.Context, hc *humanitec.WrappedHumanitecClientImpl, orgId, appId, pipelineId, runId string) (pipelineRunSummary, []mcp.ToolWarning, error) {
+	r, err := humanitec.CheckResponse(func() (*client.GetPipelineRunResponse, error) {
+		return hc.GetPipelineRunWithResponse(ctx, orgId, appId, pipelineId, runId)
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	if err != nil {
+		return pipelineRunSummary{}, nil, err
+	}
+	out := newPipelineRunSummary(*r.JSON200)
+
+	jobs, _, err := humanitec.ListPages(ctx, humanitec.PageOptions{}, func(ctx context.Context, editor client.RequestEditorFn) (*client.ListPipelineJobsResponse, error) {
+		return hc.ListPipelineJobsWithResponse(ctx, orgId, appId, pipelineId, runId, &client.ListPipelineJobsParams{}, editor)
+	}, func(r *client.ListPipelineJobsResponse) []client.PipelineJobPartial {
+		return humanitec.DerefSlice(r.JSON200)
+	})
+	if err != nil {
+		return out, nil, fmt.Errorf("failed to list the jobs of the run: %w", err)
+	}
+	warnings := make([]mcp.ToolWarning, 0)
+	out.Jobs = make([]pipelineJobSummary, 0, len(jobs))
+	for _, result := range humanitec.FanOut(ctx, jobs, func(ctx context.Context, j client.PipelineJobPartial) (*client.GetPipelineJobResponse, error) {
+		return humanitec.CheckResponse(func() (*client.GetPipelineJobResponse, error) {
+			return hc.GetPipelineJobWithResponse(ctx, orgId, appId, pipelineId, runId, j.Id)
+		}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	}) {
+		if result.Err != nil {
+			warnings = append(warnings, mcp.NewToolWarning(result.Item.Id, fmt.Errorf("failed to fetch the steps of the job: %w", result.Err)))
+			out.Jobs = append(out.Jobs, pipelineJobSummary{Id: result.Item.Id, Status: result.Item.Status, StatusMessage: result.Item.StatusMessage})
+		} else {
+			out.Jobs = append(out.Jobs, newPipelineJobSummary(*result.Output.JSON200))
+		}
+	}
+
+	if out.CompletedAt == nil {
+		approvals, err := humanitec.CheckResponse(func() (*client.ListPipelineApprovalRequestsResponse, error) {
+			status := string(client.Waiting)
+			return hc.ListPipelineApprovalRequestsWithResponse(ctx, orgId, appId, &client.ListPipelineApprovalRequestsParams{
+				Pipeline: &[]string{pipelineId}, Run: &[]string{runId}, Status: &status,
+			})
+		}).AndStatusCodeEq(http.StatusOK).RespAndError()
+		if err != nil {
+			warnings = append(warnings, mcp.NewToolWarning(runId, fmt.Errorf("failed to list the pending approval requests: %w", err)))
+		} else {
+			for _, a := range humanitec.DerefSlice(approvals.JSON200) {
+				// the filters are applied again since a snapshot answers with every approval request of the app
+				if a.PipelineId != pipelineId || a.RunId != runId || a.Status != client.Waiting {
+					continue
+				}
+				out.PendingApprovals = append(out.PendingApprovals, newPipelineApprovalSummary(a))
+			}
+		}
+	}
+	return out, warnings, nil
+}
+
+// followPipelineRun polls the run until it completed, waits for an approval, or the wait elapsed. Every poll is
+// reported as progress of the tool call.
+func followPipelineRun(ctx context.Context, hc *humanitec.WrappedHumanitecClientImpl, orgId, appId string, run pipelineRunSummary, warnings []mcp.ToolWarning, wait time.Duration) (pipelineRunSummary, []mcp.ToolWarning) {
+	start := time.Now()
+	mcp.NotifyProgress(ctx, 0, wait.Seconds(), run.progress())
+	for run.CompletedAt == nil && len(run.PendingApprovals) == 0 && time.Since(start) < wait {
+		select {
+		case <-ctx.Done():
+			return run, warnings
+		case <-time.After(pipelinePollInterval):
+		}
+		if next, w, err := getPipelineRunDetails(ctx, hc, orgId, appId, run.PipelineId, run.Id); err == nil {
+			run, warnings = next, w
+		}
+		mcp.NotifyProgress(ctx, min(time.Since(start), wait).Seconds(), wait.Seconds(), run.progress())
+	}
+	return run, warnings
+}
+
+// pipelineRunResponse describes the run and what can be done next.
+func pipelineRunResponse(run pipelineRunSummary, prefix string) []mcp.CallToolResponseContent {
+	text := strings.TrimSpace(prefix + " " + run.progress())
+	switch {
+	case len(run.PendingApprovals) > 0:
+		text += " Ask the user whether to approve or deny it and use the respond_to_humanitec_pipeline_approval tool with their decision."
+	case run.Status == "failed":
+		for _, j := range run.Jobs {
+			for _, s := range j.Steps {
+				if s.Status == "failed" {
+					text += fmt.Sprintf(" The get_humanitec_pipeline_step_logs tool returns the logs of the failed step '%s' with job_id '%s' and step_index %d.", s.Name, j.Id, s.Index)
+				}
+			}
+		}
+	case run.CompletedAt == nil:
+		text += " Use the get_humanitec_pipeline_run tool with wait_seconds to follow its progress."
+	}
+	return []mcp.CallToolResponseContent{
+		mcp.NewTextToolResponseContent("%s The run in JSON format: %s", text, string(internal.PrettyJson(run))),
+	}
+}
+
+// pipelineInputProperties are the input schema properties that identify a Pipeline.
+var pipelineInputProperties = map[string]interface{}{
+	"org_id":      map[string]interface{}{"type": "string", "description": "The Humanitec Organization (org) ID to work with."},
+	"app_id":      map[string]interface{}{"type": "string", "description": "The Humanitec Application (app) ID to work with."},
+	"pipeline_id": map[string]interface{}{"type": "string", "description": "The id of the Pipeline in the Application."},
+	"run_id":      map[string]interface{}{"type": "string", "description": "The id of the pipeline run."},
+	"job_id":      map[string]interface{}{"type": "string", "description": "The id of the job in the pipeline run."},
+}
+
+// guardedPipelineProperties are the input schema properties shared by the tools that let pipeline runs act.
+var guardedPipelineProperties = map[string]interface{}{
+	"confirmation_token": map[string]interface{}{
+		"type":        "string",
+		"description": "The token returned by the dry-run. Without it the call is a dry-run that changes nothing. Only pass it after the user explicitly confirmed the dry-run.",
+	},
+	"wait_seconds": map[string]interface{}{
+		"type": "integer", "minimum": 0, "maximum": int(maxDeploymentWait.Seconds()),
+		"description": "Optional number of seconds to follow the run before returning its progress, defaults to 30. Following stops early when the run completes or waits for an approval.",
+	},
+}
+
+func withGuardedPipelineProperties(properties map[string]interface{}) map[string]interface{} {
+	for k, v := range guardedPipelineProperties {
+		properties[k] = v
+	}
+	return properties
+}
+
+func NewListPipelines() mcp.Tool {
+	return mcp.Tool{
+		Name: "list_humanitec_pipelines",
+		Description: `This tool lists the Humanitec Pipelines of an Application, or of all Applications in the Organization when no app_id is given.
+Each Pipeline includes its name, status, version, and trigger types. The approval requests of pipeline runs that wait for a decision are listed as well.
+Use get_humanitec_pipeline_definition for the steps of a Pipeline and list_humanitec_pipeline_runs for its run history.`,
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"org_id":        pipelineInputProperties["org_id"],
+				"app_id":        map[string]interface{}{"type": "string", "description": "Optional Humanitec Application (app) ID, defaults to all Applications."},
+				"cache_control": cacheControlProperty,
+			},
+			"required":             []string{"org_id"},
+			"additionalProperties": false,
+		},
+		Callable: func(ctx context.Context, arguments map[string]interface{}) ([]mcp.CallToolResponseContent, error) {
+			orgId, _ := arguments["org_id"].(string)
+			appId, _ := arguments["app_id"].(string)
+			ctx = withCacheControl(ctx, arguments)
+			hc, err := humanitec.NewHumanitecClientWithCurrentToken(ctx)
+			if err != nil {
+				return nil, err
+			}
+			appIds := []string{appId}
+			if appId == "" {
+				apps, _, err := hc.ListAllApplications(ctx, orgId, humanitec.PageOptions{})
+				if err != nil {
+					return nil, err
+				}
+				appIds = make([]string, 0, len(apps))
+				for _, a := range apps {
+					appIds = append(appIds, a.Id)
+				}
+			}
+
+			type appPipelines struct {
+				pipelines []client.Pipeline
+				approvals []client.PipelineApprovalRequest
+			}
+			pipelines := make([]pipelineSummary, 0)
+			approvals := make([]pipelineApprovalSummary, 0)
+			warnings := make([]mcp.ToolWarning, 0)
+			for _, result := range humanitec.FanOut(ctx, appIds, func(ctx context.Context, appId string) (appPipelines, error) {
+				var out appPipelines
+				var err error
+				if out.pipelines, _, err = humanitec.ListPages(ctx, humanitec.PageOptions{}, func(ctx context.Context, editor client.RequestEditorFn) (*client.ListPipelinesResponse, error) {
+					return hc.ListPipelinesWithResponse(ctx, orgId, appId, &client.ListPipelinesParams{}, editor)
+				}, func(r *client.ListPipelinesResponse) []client.Pipeline {
+					return humanitec.DerefSlice(r.JSON200)
+				}); err != nil {
+					return out, err
+				}
+				if len(out.pipelines) == 0 {
+					return out, nil
+				}
+				out.approvals, _, err = humanitec.ListMatchingPages(ctx, humanitec.PageOptions{}, func(ctx context.Context, editor client.RequestEditorFn) (*client.ListPipelineApprovalRequestsResponse, error) {
+					status := string(client.Waiting)
+					return hc.ListPipelineApprovalRequestsWithResponse(ctx, orgId, appId, &client.ListPipelineApprovalRequestsParams{Status: &status}, editor)
+				}, func(r *client.ListPipelineApprovalRequestsResponse) []client.PipelineApprovalRequest {
+					return humanitec.DerefSlice(r.JSON200)
+				}, func(a client.PipelineApprovalRequest) bool {
+					return a.Status == client.Waiting
+				})
+				return out, err
+			}) {
+				if result.Err != nil {
+					if appId != "" {
+						return nil, result.Err
+					}
+					warnings = append(warnings, mcp.NewToolWarning(result.Item, fmt.Errorf("failed to list pipelines: %w", result.Err)))
+					continue
+				}
+				for _, p := range result.Output.pipelines {
+					pipelines = append(pipelines, pipelineSummary{
+						AppId: result.Item, Id: p.Id, Name: p.Name, Status: p.Status, Version: p.Version, TriggerTypes: p.TriggerTypes, CreatedAt: p.CreatedAt,
+					})
+				}
+				for _, a := range result.Output.approvals {
+					approvals = append(approvals, newPipelineApprovalSummary(a))
+				}
+			}
+
+			scope := fmt.Sprintf("Application '%s'", appId)
+			if appId == "" {
+				scope = fmt.Sprintf("the %d Applications of Organization '%s'", len(appIds), orgId)
+			}
+			out := []mcp.CallToolResponseContent{
+				mcp.NewTextToolResponseContent("The %d Pipelines of %s in JSON format: %s", len(pipelines), scope, string(internal.PrettyJson(pipelines))),
+			}
+			if len(approvals) > 0 {
+				out = append(out, mcp.NewTextToolResponseContent("%d pipeline runs are waiting for an approval decision, the approval requests in JSON format: %s", len(approvals), string(internal.PrettyJson(approvals))))
+			}
+			return out, mcp.AsPartialResult(warnings)
+		},
+	}
+}
+
+// getPipelineDefinition returns the yaml definition of the Pipeline.
+func getPipelineDefinition(ctx context.Context, hc *humanitec.WrappedHumanitecClientImpl, orgId, appId, pipelineId string) (string, error) {
+	r, err := humanitec.CheckResponse(func() (*client.GetPipelineDefinitionResponse, error) {
+		accept := humanitec.PipelineDefinitionMediaType
+		return hc.GetPipelineDefinitionWithResponse(ctx, orgId, appId, pipelineId, &client.GetPipelineDefinitionParams{Accept: &accept})
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	if err != nil {
+		return "", err
+	}
+	return string(r.Body), nil
+}
+
+func NewGetPipelineDefinition() mcp.Tool {
+	return mcp.Tool{
+		Name: "get_humanitec_pipeline_definition",
+		Description: `This tool returns a Humanitec Pipeline with its yaml definition: its triggers and their inputs, and the jobs and steps that each run executes.
+Use list_humanitec_pipelines to find Pipeline ids.`,
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"org_id":        pipelineInputProperties["org_id"],
+				"app_id":        pipelineInputProperties["app_id"],
+				"pipeline_id":   pipelineInputProperties["pipeline_id"],
+				"cache_control": cacheControlProperty,
+			},
+			"required":             []string{"org_id", "app_id", "pipeline_id"},
+			"additionalProperties": false,
+		},
+		Callable: func(ctx context.Context, arguments map[string]interface{}) ([]mcp.CallToolResponseContent, error) {
+			orgId, _ := arguments["org_id"].(string)
+			appId, _ := arguments["app_id"].(string)
+			pipelineId, _ := arguments["pipeline_id"].(string)
+			ctx = withCacheControl(ctx, arguments)
+			hc, err := humanitec.NewHumanitecClientWithCurrentToken(ctx)
+			if err != nil {
+				return nil, err
+			}
+			p, err := humanitec.CheckResponse(func() (*client.GetPipelineResponse, error) {
+				return hc.GetPipelineWithResponse(ctx, orgId, appId, pipelineId, &client.GetPipelineParams{})
+			}).AndStatusCodeEq(http.StatusOK).RespAndError()
+			if err != nil {
+				return nil, err
+			}
+			definition, err := getPipelineDefinition(ctx, hc, orgId, appId, pipelineId)
+			if err != nil {
+				return nil, err
+			}
+			return []mcp.CallToolResponseContent{
+				mcp.NewTextToolResponseContent("Pipeline '%s' (%s) of Application '%s' at version '%s' can be triggered by %s. Its definition in yaml format:\n%s",
+					p.JSON200.Id, p.JSON200.Name, appId, p.JSON200.Version, strings.Join(p.JSON200.TriggerTypes, ", "), definition),
+			}, nil
+		},
+	}
+}
+
+func NewListPipelineRuns() mcp.Tool {
+	return mcp.Tool{
+		Name: "list_humanitec_pipeline_runs",
+		Description: `This tool returns the run history of a Humanitec Pipeline, newest first.
+Each run includes its status, inputs, the Environments it acts on, its author, and timings. Filter by Environment and status.
+Use get_humanitec_pipeline_run for the jobs, steps, and pending approvals of a run.`,
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"org_id":      pipelineInputProperties["org_id"],
+				"app_id":      pipelineInputProperties["app_id"],
+				"pipeline_id": pipelineInputProperties["pipeline_id"],
+				"env_id":      map[string]interface{}{"type": "string", "description": "Optional filter for the runs that act on this Environment."},
+				"status":      map[string]interface{}{"type": "string", "enum": pipelineRunStatuses, "description": "Optional filter for the run status."},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"minimum":     1,
+					"description": fmt.Sprintf("Optional maximum number of runs to return, defaults to %d.", defaultPipelineRunsLimit),
+				},
+				"cache_control": cacheControlProperty,
+			},
+			"required":             []string{"org_id", "app_id", "pipeline_id"},
+			"additionalProperties": false,
+		},
+		Callable: func(ctx context.Context, arguments map[string]interface{}) ([]mcp.CallToolResponseContent, error) {
+			orgId, _ := arguments["org_id"].(string)
+			appId, _ := arguments["app_id"].(string)
+			pipelineId, _ := arguments["pipeline_id"].(string)
+			params := &client.ListPipelineRunsParams{}
+			envId, _ := arguments["env_id"].(string)
+			if envId != "" {
+				params.Env = &envId
+			}
+			status, _ := arguments["status"].(string)
+			if status != "" {
+				if !slices.Contains(pipelineRunStatuses, status) {
+					return nil, fmt.Errorf("invalid status '%s': expected one of %s", status, strings.Join(pipelineRunStatuses, ", "))
+				}
+				params.Status = &[]string{status}
+			}
+			limit := defaultPipelineRunsLimit
+			if v, ok := arguments["limit"].(float64); ok && v > 0 {
+				limit = int(v)
+			}
+
+			ctx = withCacheControl(ctx, arguments)
+			hc, err := humanitec.NewHumanitecClientWithCurrentToken(ctx)
+			if err != nil {
+				return nil, err
+			}
+			// the filters are applied again since a snapshot answers with every exported run of the pipeline
+			runs, cursor, err := humanitec.ListMatchingPages(ctx, humanitec.PageOptions{Limit: limit}, func(ctx context.Context, editor client.RequestEditorFn) (*client.ListPipelineRunsResponse, error) {
+				return hc.ListPipelineRunsWithResponse(ctx, orgId, appId, pipelineId, params, editor)
+			}, func(r *client.ListPipelineRunsResponse) []client.PipelineRun {
+				return humanitec.DerefSlice(r.JSON200)
+			}, func(r client.PipelineRun) bool {
+				return (envId == "" || slices.Contains(r.EnvIds, envId)) && (status == "" || string(r.Status) == status)
+			})
+			if err != nil {
+				return nil, err
+			}
+			slices.SortStableFunc(runs, func(a, b client.PipelineRun) int {
+				return b.CreatedAt.Compare(a.CreatedAt)
+			})
+			out := make([]pipelineRunSummary, 0, len(runs))
+			for _, r := range runs {
+				out = append(out, newPipelineRunSummary(r))
+			}
+			more := ""
+			if cursor != "" {
+				more = fmt.Sprintf(" Only the newest %d matching runs are shown, narrow the filters or raise the limit to see more.", len(out))
+			}
+			return []mcp.CallToolResponseContent{
+				mcp.NewTextToolResponseContent("The runs of Pipeline '%s' in Application '%s', newest first, in JSON format: %s%s", pipelineId, appId, string(internal.PrettyJson(out)), more),
+			}, nil
+		},
+	}
+}
+
+func NewGetPipelineRun() mcp.Tool {
+	return mcp.Tool{
+		Name: "get_humanitec_pipeline_run",
+		Description: `This tool returns a Humanitec pipeline run with the status of its jobs and steps and the approval requests it waits for.
+Give wait_seconds to follow a running pipeline: the tool reports progress while it waits and returns when the run completes, waits for an approval, or the time is up.
+Use get_humanitec_pipeline_step_logs for the logs of a step.`,
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"org_id":      pipelineInputProperties["org_id"],
+				"app_id":      pipelineInputProperties["app_id"],
+				"pipeline_id": pipelineInputProperties["pipeline_id"],
+				"run_id":      pipelineInputProperties["run_id"],
+				"wait_seconds": map[string]interface{}{
+					"type": "integer", "minimum": 0, "maximum": int(maxDeploymentWait.Seconds()),
+					"description": "Optional number of seconds to follow the run while it executes, defaults to 0 which returns its current state.",
+				},
+				"cache_control": cacheControlProperty,
+			},
+			"required":             []string{"org_id", "app_id", "pipeline_id", "run_id"},
+			"additionalProperties": false,
+		},
+		Callable: func(ctx context.Context, arguments map[string]interface{}) ([]mcp.CallToolResponseContent, error) {
+			orgId, _ := arguments["org_id"].(string)
+			appId, _ := arguments["app_id"].(string)
+			pipelineId, _ := arguments["pipeline_id"].(string)
+			runId, _ := arguments["run_id"].(string)
+			var wait time.Duration
+			if _, ok := arguments["wait_seconds"]; ok {
+				wait = waitFromArgument(arguments)
+			}
+			ctx = withCacheControl(ctx, arguments)
+			if wait > 0 {
+				// following needs the live state of the run
+				ctx = humanitec.WithCacheBypass(ctx)
+			}
+			hc, err := humanitec.NewHumanitecClientWithCurrentToken(ctx)
+			if err != nil {
+				return nil, err
+			}
+			run, warnings, err := getPipelineRunDetails(ctx, hc, orgId, appId, pipelineId, runId)
+			if err != nil {
+				return nil, err
+			}
+			if wait > 0 {
+				run, warnings = followPipelineRun(ctx, hc, orgId, appId, run, warnings, wait)
+			}
+			return pipelineRunResponse(run, ""), mcp.AsPartialResult(warnings)
+		},
+	}
+}
+
+func NewGetPipelineStepLogs() mcp.Tool {
+	return mcp.Tool{
+		Name: "get_humanitec_pipeline_step_logs",
+		Description: `This tool returns the logs of a step of a Humanitec pipeline run, one line per entry with its time and level.
+The job ids and step indexes are returned by the get_humanitec_pipeline_run tool.`,
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"org_id":        pipelineInputProperties["org_id"],
+				"app_id":        pipelineInputProperties["app_id"],
+				"pipeline_id":   pipelineInputProperties["pipeline_id"],
+				"run_id":        pipelineInputProperties["run_id"],
+				"job_id":        pipelineInputProperties["job_id"],
+				"step_index":    map[string]interface{}{"type": "integer", "minimum": 0, "description": "The index of the step in the job, starting at 0."},
+				"cache_control": cacheControlProperty,
+			},
+			"required":             []string{"org_id", "app_id", "pipeline_id", "run_id", "job_id", "step_index"},
+			"additionalProperties": false,
+		},
+		Callable: func(ctx context.Context, arguments map[string]interface{}) ([]mcp.CallToolResponseContent, error) {
+			orgId, _ := arguments["org_id"].(string)
+			appId, _ := arguments["app_id"].(string)
+			pipelineId, _ := arguments["pipeline_id"].(string)
+			runId, _ := arguments["run_id"].(string)
+			jobId, _ := arguments["job_id"].(string)
+			stepIndex, _ := arguments["step_index"].(float64)
+			ctx = withCacheControl(ctx, arguments)
+			hc, err := humanitec.NewHumanitecClientWithCurrentToken(ctx)
+			if err != nil {
+				return nil, err
+			}
+			logs, _, err := humanitec.ListPages(ctx, humanitec.PageOptions{}, func(ctx context.Context, editor client.RequestEditorFn) (*client.ListPipelineStepLogsResponse, error) {
+				return hc.ListPipelineStepLogsWithResponse(ctx, orgId, appId, pipelineId, runId, jobId, int(stepIndex), &client.ListPipelineStepLogsParams{}, editor)
+			}, func(r *client.ListPipelineStepLogsResponse) []client.PipelineStepLog {
+				return humanitec.DerefSlice(r.JSON200)
+			})
+			if err != nil {
+				return nil, err
+			}
+			if len(logs) == 0 {
+				return []mcp.CallToolResponseContent{mcp.NewTextToolResponseContent("Step %d of job '%s' has no logs yet.", int(stepIndex), jobId)}, nil
+			}
+			lines := make([]string, 0, len(logs))
+			for _, l := range logs {
+				lines = append(lines, fmt.Sprintf("%s %s %s", l.At.UTC().Format(time.RFC3339), l.Level, l.Message))
+			}
+			return []mcp.CallToolResponseContent{
+				mcp.NewTextToolResponseContent("The %d log entries of step %d of job '%s' in run '%s':\n%s", len(lines), int(stepIndex), jobId, runId, strings.Join(lines, "\n")),
+			}, nil
+		},
+	}
+}
+
+func NewTriggerPipelineRun() mcp.Tool {
+	return mcp.Tool{
+		Name: "trigger_humanitec_pipeline_run",
+		Description: `This tool triggers a run of a Humanitec Pipeline with the given inputs. Pipeline runs usually deploy to Environments and change their running state.
+The first call without a confirmation_token is a dry-run that validates the inputs and returns the Pipeline definition and a confirmation token. Show what the run would do to the user and only call again with the token after they explicitly confirm.
+The confirmed call starts the run and follows it, reporting progress, until it completes, waits for an approval, or wait_seconds are up.`,
+		Annotations: guardedDeploymentAnnotations,
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": withGuardedPipelineProperties(map[string]interface{}{
+				"org_id":      pipelineInputProperties["org_id"],
+				"app_id":      pipelineInputProperties["app_id"],
+				"pipeline_id": pipelineInputProperties["pipeline_id"],
+				"inputs":      map[string]interface{}{"type": "object", "description": "Optional inputs of the pipeline_call trigger as declared in the Pipeline definition."},
+			}),
+			"required":             []string{"org_id", "app_id", "pipeline_id"},
+			"additionalProperties": false,
+		},
+		Callable: func(ctx context.Context, arguments map[string]interface{}) ([]mcp.CallToolResponseContent, error) {
+			orgId, _ := arguments["org_id"].(string)
+			appId, _ := arguments["app_id"].(string)
+			pipelineId, _ := arguments["pipeline_id"].(string)
+			inputs, _ := arguments["inputs"].(map[string]interface{})
+			if inputs == nil {
+				inputs = map[string]interface{}{}
+			}
+
+			ctx = humanitec.WithCacheBypass(ctx)
+			hc, err := humanitec.NewHumanitecClientWithCurrentToken(ctx)
+			if err != nil {
+				return nil, err
+			}
+			p, err := humanitec.CheckResponse(func() (*client.GetPipelineResponse, error) {
+				return hc.GetPipelineWithResponse(ctx, orgId, appId, pipelineId, &client.GetPipelineParams{})
+			}).AndStatusCodeEq(http.StatusOK).RespAndError()
+			if err != nil {
+				return nil, err
+			} else if !slices.Contains(p.JSON200.TriggerTypes, "pipeline_call") {
+				return nil, fmt.Errorf("pipeline '%s' cannot be triggered directly, its trigger types are %s", pipelineId, strings.Join(p.JSON200.TriggerTypes, ", "))
+			}
+			// json encodes map keys in order, so equal inputs always confirm with the same token
+			rawInputs, _ := json.Marshal(inputs)
+			parts := []string{"pipeline-run", orgId, appId, pipelineId, p.JSON200.Version, string(rawInputs)}
+
+			token, _ := arguments["confirmation_token"].(string)
+			if token == "" {
+				if _, err := humanitec.CheckResponse(func() (*client.CreatePipelineRunResponse, error) {
+					dryRun := true
+					return hc.CreatePipelineRunWithResponse(ctx, orgId, appId, pipelineId, &client.CreatePipelineRunParams{DryRun: &dryRun}, client.PipelineRunCreateBody{Inputs: inputs})
+				}).AndStatusCodeEq(http.StatusCreated, http.StatusNoContent).RespAndError(); err != nil {
+					return nil, fmt.Errorf("the pipeline run would be rejected: %w", err)
+				}
+				definition, err := getPipelineDefinition(ctx, hc, orgId, appId, pipelineId)
+				if err != nil {
+					return nil, err
+				}
+				now := time.Now()
+				return []mcp.CallToolResponseContent{
+					mcp.NewTextToolResponseContent("DRY-RUN, nothing was triggered. Pipeline '%s' (%s) of Application '%s' accepts the inputs %s and would run the jobs of its definition at version '%s'. Show the definition and inputs to the user and only after they explicitly confirm, call this tool again with the same arguments and confirmation_token '%s'. The token expires at %s and stops matching if the Pipeline is changed in the meantime.",
+						pipelineId, p.JSON200.Name, appId, string(rawInputs), p.JSON200.Version, confirmationToken(now, parts...), now.Add(confirmationTokenTTL).UTC().Format(time.RFC3339)),
+					mcp.NewTextToolResponseContent("The definition of the Pipeline in yaml format:\n%s", definition),
+				}, nil
+			} else if err := checkConfirmationToken(token, time.Now(), parts...); err != nil {
+				return nil, err
+			}
+
+			r, err := humanitec.CheckResponse(func() (*client.CreatePipelineRunResponse, error) {
+				// the token makes retries of the same confirmed call start at most one run
+				return hc.CreatePipelineRunWithResponse(ctx, orgId, appId, pipelineId, &client.CreatePipelineRunParams{IdempotencyKey: &token}, client.PipelineRunCreateBody{Inputs: inputs})
+			}).AndStatusCodeEq(http.StatusCreated).RespAndError()
+			if err != nil {
+				return nil, err
+			}
+			run, warnings, err := getPipelineRunDetails(ctx, hc, orgId, appId, pipelineId, r.JSON201.Id)
+			if err != nil {
+				return nil, fmt.Errorf("pipeline run '%s' was started but its progress could not be fetched: %w", r.JSON201.Id, err)
+			}
+			run, warnings = followPipelineRun(ctx, hc, orgId, appId, run, warnings, waitFromArgument(arguments))
+			return pipelineRunResponse(run, fmt.Sprintf("Pipeline run '%s' of Pipeline '%s' was started.", run.Id, pipelineId)), mcp.AsPartialResult(warnings)
+		},
+	}
+}
+
+func NewRespondToPipelineApproval() mcp.Tool {
+	return mcp.Tool{
+		Name: "respond_to_humanitec_pipeline_approval",
+		Description: `This tool approves or denies an approval request that a Humanitec pipeline run waits for. Approving lets the run continue, which usually deploys to an Environment, and denying fails the run.
+The pending approval requests are returned by the list_humanitec_pipelines and get_humanitec_pipeline_run tools.
+The first call without a confirmation_token is a dry-run that returns the approval request, the run, and a confirmation token. Show them to the user and only call again with the token after they explicitly confirm their decision.
+The confirmed call records the decision and follows the run, reporting progress, until it completes, waits for another approval, or wait_seconds are up.`,
+		Annotations: guardedDeploymentAnnotations,
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": withGuardedPipelineProperties(map[string]interface{}{
+				"org_id":      pipelineInputProperties["org_id"],
+				"app_id":      pipelineInputProperties["app_id"],
+				"pipeline_id": pipelineInputProperties["pipeline_id"],
+				"run_id":      pipelineInputProperties["run_id"],
+				"job_id":      pipelineInputProperties["job_id"],
+				"approval_id": map[string]interface{}{"type": "string", "description": "The id of the approval request."},
+				"decision":    map[string]interface{}{"type": "string", "enum": []string{"approve", "deny"}, "description": "Whether to approve or deny the approval request."},
+			}),
+			"required":             []string{"org_id", "app_id", "pipeline_id", "run_id", "job_id", "approval_id", "decision"},
+			"additionalProperties": false,
+		},
+		Callable: func(ctx context.Context, arguments map[string]interface{}) ([]mcp.CallToolResponseContent, error) {
+			orgId, _ := arguments["org_id"].(string)
+			appId, _ := arguments["app_id"].(string)
+			pipelineId, _ := arguments["pipeline_id"].(string)
+			runId, _ := arguments["run_id"].(string)
+			jobId, _ := arguments["job_id"].(string)
+			approvalId, _ := arguments["approval_id"].(string)
+			decision, _ := arguments["decision"].(string)
+			if decision != "approve" && decision != "deny" {
+				return nil, fmt.Errorf("invalid decision '%s': expected approve or deny", decision)
+			}
+
+			ctx = humanitec.WithCacheBypass(ctx)
+			hc, err := humanitec.NewHumanitecClientWithCurrentToken(ctx)
+			if err != nil {
+				return nil, err
+			}
+			a, err := humanitec.CheckResponse(func() (*client.GetPipelineApprovalRequestResponse, error) {
+				return hc.GetPipelineApprovalRequestWithResponse(ctx, orgId, appId, pipelineId, runId, jobId, approvalId)
+			}).AndStatusCodeEq(http.StatusOK).RespAndError()
+			if err != nil {
+				return nil, err
+			} else if a.JSON200.Status != client.Waiting {
+				return nil, fmt.Errorf("approval request '%s' was already %s", approvalId, a.JSON200.Status)
+			}
+			parts := []string{"pipeline-approval", orgId, appId, pipelineId, runId, jobId, approvalId, decision}
+
+			token, _ := arguments["confirmation_token"].(string)
+			if token == "" {
+				run, warnings, err := getPipelineRunDetails(ctx, hc, orgId, appId, pipelineId, runId)
+				if err != nil {
+					return nil, err
+				}
+				effect := "let the run continue with its next steps"
+				if decision == "deny" {
+					effect = "fail the run"
+				}
+				env := ""
+				if a.JSON200.EnvId != "" {
+					env = fmt.Sprintf(" for Environment '%s'", a.JSON200.EnvId)
+				}
+				now := time.Now()
+				return []mcp.CallToolResponseContent{
+					mcp.NewTextToolResponseContent("DRY-RUN, nothing was decided. Approval request '%s'%s asks: %s Choosing to %s it would %s. Show the request and the run to the user and only after they explicitly confirm, call this tool again with the same arguments and confirmation_token '%s'. The token expires at %s.",
+						approvalId, env, a.JSON200.Message, decision, effect, confirmationToken(now, parts...), now.Add(confirmationTokenTTL).UTC().Format(time.RFC3339)),
+					mcp.NewTextToolResponseContent("The run in JSON format: %s", string(internal.PrettyJson(run))),
+				}, mcp.AsPartialResult(warnings)
+			} else if err := checkConfirmationToken(token, time.Now(), parts...); err != nil {
+				return nil, err
+			}
+
+			if decision == "approve" {
+				_, err = humanitec.CheckResponse(func() (*client.ApprovePipelineApprovalRequestResponse, error) {
+					return hc.ApprovePipelineApprovalRequestWithResponse(ctx, orgId, appId, pipelineId, runId, jobId, approvalId)
+				}).AndStatusCodeEq(http.StatusOK).RespAndError()
+			} else {
+				_, err = humanitec.CheckResponse(func() (*client.DenyPipelineApprovalRequestResponse, error) {
+					return hc.DenyPipelineApprovalRequestWithResponse(ctx, orgId, appId, pipelineId, runId, jobId, approvalId)
+				}).AndStatusCodeEq(http.StatusOK).RespAndError()
+			}
+			if err != nil {
+				return nil, err
+			}
+			decided := map[string]string{"approve": "approved", "deny": "denied"}[decision]
+			run, warnings, err := getPipelineRunDetails(ctx, hc, orgId, appId, pipelineId, runId)
+			if err != nil {
+				return nil, fmt.Errorf("approval request '%s' was %s but the progress of the run could not be fetched: %w", approvalId, decided, err)
+			}
+			run, warnings = followPipelineRun(ctx, hc, orgId, appId, run, warnings, waitFromArgument(arguments))
+			return pipelineRunResponse(run, fmt.Sprintf("Approval request '%s' was %s.", approvalId, decided)), mcp.AsPartialResult(warnings)
+		},
+	}
+}
@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/humanitec/canyon-cli/internal"
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec"
+	"github.com/humanitec/canyon-cli/internal/mcp"
+)
+
+func NewSwitchHumanitecProfile() mcp.Tool {
+	return mcp.Tool{
+		Name: "switch_humanitec_profile",
+		Description: `This tool lists the available Humanitec credential profiles and switches the active profile used by all other tools.
+Each profile holds the credentials and default Organization for a particular Humanitec account or customer.
+Call this tool without a profile to list the profiles and see which is active. After switching, confirm the Organization to work in with the user.`,
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"profile": map[string]interface{}{"type": "string", "description": "Optional name of the profile to switch to."},
+			},
+			"additionalProperties": false,
+		},
+		Callable: func(ctx context.Context, arguments map[string]interface{}) ([]mcp.CallToolResponseContent, error) {
+			if name, _ := arguments["profile"].(string); name != "" {
+				if err := humanitec.SetActiveProfile(name); err != nil {
+					return nil, err
+				}
+			}
+			cfg, err := humanitec.LoadProfilesConfig()
+			if err != nil {
+				return nil, err
+			}
+			active, err := humanitec.ActiveProfile()
+			if err != nil {
+				return nil, err
+			}
+			type profileState struct {
+				*humanitec.Profile
+				Active   bool `json:"active"`
+				HasToken bool `json:"has_token"`
+			}
+			out := make([]profileState, 0)
+			for _, name := range cfg.ProfileNames() {
+				p, err := humanitec.LoadProfile(name)
+				if err != nil {
+					return nil, err
+				}
+				token, _ := p.ResolveToken()
+				out = append(out, profileState{Profile: p, Active: p.Name == active.Name, HasToken: token != ""})
+			}
+			msg := fmt.Sprintf("The active profile is '%s'.", active.Name)
+			if active.DefaultOrg != "" {
+				msg += fmt.Sprintf(" Its default Humanitec Organization is '%s'.", active.DefaultOrg)
+			}
+			return []mcp.CallToolResponseContent{
+				mcp.NewTextToolResponseContent("%s The available profiles in JSON format are: %s", msg, internal.PrettyJson(out)),
+			}, nil
+		},
+	}
+}
@@ -0,0 +1,148 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/humanitec/humanitec-go-autogen/client"
+
+	"github.com/humanitec/canyon-cli/internal"
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec"
+	"github.com/humanitec/canyon-cli/internal/mcp"
+)
+
+// graphNode and graphLink match the nodes and links accepted by the render_network_as_graph_in_browser tool.
+type graphNode struct {
+	Id    string                 `json:"id"`
+	Class string                 `json:"class"`
+	Data  map[string]interface{} `json:"data,omitempty"`
+}
+
+type graphLink struct {
+	Source      string `json:"source"`
+	Target      string `json:"target"`
+	Explanation string `json:"explanation,omitempty"`
+}
+
+type resourceGraph struct {
+	Nodes []graphNode `json:"nodes"`
+	Links []graphLink `json:"links"`
+}
+
+// resourceNodeId is a readable id for a resource that is unique within an environment.
+func resourceNodeId(resId, resType, class string) string {
+	if class == "" || class == "default" {
+		return fmt.Sprintf("%s (%s)", resId, resType)
+	}
+	return fmt.Sprintf("%s (%s/%s)", resId, resType, class)
+}
+
+func resourceNodeClass(resType string) string {
+	if resType == "workload" {
+		return "workload"
+	}
+	return "resource"
+}
+
+// newResourceGraph builds the graph of the active resources with the dependencies from the resource graph nodes. Nodes
+// that appear in the dependency graph without being active are included with an unknown status.
+func newResourceGraph(resources []client.ActiveResourceResponse, nodes []client.NodeBodyResponse) resourceGraph {
+	out := resourceGraph{Nodes: make([]graphNode, 0, len(resources)), Links: make([]graphLink, 0)}
+	ids := make(map[string]string)
+	for _, res := range resources {
+		id := resourceNodeId(res.ResId, res.Type, res.Class)
+		ids[res.GuResId] = id
+		data := map[string]interface{}{
+			"res_id":      res.ResId,
+			"type":        res.Type,
+			"class":       res.Class,
+			"def_id":      res.DefId,
+			"driver_type": res.DriverType,
+			"status":      res.Status,
+		}
+		if res.ScheduledDeletion {
+			data["scheduled_deletion"] = true
+		}
+		out.Nodes = append(out.Nodes, graphNode{Id: id, Class: resourceNodeClass(res.Type), Data: data})
+	}
+	for _, n := range nodes {
+		if _, ok := ids[n.Guresid]; !ok {
+			ids[n.Guresid] = resourceNodeId(n.Id, n.Type, n.Class)
+			out.Nodes = append(out.Nodes, graphNode{Id: ids[n.Guresid], Class: resourceNodeClass(n.Type), Data: map[string]interface{}{
+				"res_id":      n.Id,
+				"type":        n.Type,
+				"class":       n.Class,
+				"def_id":      n.DefId,
+				"driver_type": n.DriverType,
+				"status":      "unknown",
+			}})
+		}
+	}
+	for _, n := range nodes {
+		for _, dep := range n.DependsOn {
+			if target, ok := ids[dep]; ok {
+				out.Links = append(out.Links, graphLink{Source: ids[n.Guresid], Target: target, Explanation: "depends on"})
+			}
+		}
+	}
+	return out
+}
+
+func NewGetActiveResourceGraph() mcp.Tool {
+	return mcp.Tool{
+		Name: "get_humanitec_active_resources_graph",
+		Description: `This tool returns the active resources provisioned in a Humanitec Environment and the dependencies between them.
+Each resource includes its resource type, class, Resource Definition id, driver type, and status. Use this to find which resources a workload uses and how they were provisioned.
+The result has the nodes and links accepted by the render_network_as_graph_in_browser tool, set render to true to show the graph in the browser straight away.`,
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"org_id":        map[string]interface{}{"type": "string", "description": "The Humanitec Organization (org) ID to work with."},
+				"app_id":        map[string]interface{}{"type": "string", "description": "The Humanitec Application (app) ID to work with."},
+				"env_id":        map[string]interface{}{"type": "string", "description": "The Humanitec Environment (env) ID to work with."},
+				"render":        map[string]interface{}{"type": "boolean", "description": "Whether to also render the graph in the browser."},
+				"cache_control": cacheControlProperty,
+			},
+			"required":             []string{"org_id", "app_id", "env_id"},
+			"additionalProperties": false,
+		},
+		Callable: func(ctx context.Context, arguments map[string]interface{}) ([]mcp.CallToolResponseContent, error) {
+			orgId, _ := arguments["org_id"].(string)
+			appId, _ := arguments["app_id"].(string)
+			envId, _ := arguments["env_id"].(string)
+			render, _ := arguments["render"].(bool)
+			ctx = withCacheControl(ctx, arguments)
+			hc, err := humanitec.NewHumanitecClientWithCurrentToken(ctx)
+			if err != nil {
+				return nil, err
+			}
+			resources, err := hc.ListActiveResources(ctx, orgId, appId, envId)
+			if err != nil {
+				return nil, err
+			} else if len(resources) == 0 {
+				return []mcp.CallToolResponseContent{
+					mcp.NewTextToolResponseContent("There are no active resources in Environment '%s' of Application '%s'. Resources are provisioned by the first deployment that uses them.", envId, appId),
+				}, nil
+			}
+
+			warnings := make([]mcp.ToolWarning, 0)
+			nodes, err := hc.QueryActiveResourceGraph(ctx, orgId, appId, envId, resources)
+			if err != nil {
+				warnings = append(warnings, mcp.NewToolWarning(envId, fmt.Errorf("failed to fetch the resource dependency graph, the links are missing: %w", err)))
+			}
+			graph := newResourceGraph(resources, nodes)
+
+			out := []mcp.CallToolResponseContent{
+				mcp.NewTextToolResponseContent("The active resources of Environment '%s' in Application '%s' and their dependencies as a graph in JSON format: %s", envId, appId, string(internal.PrettyJson(graph))),
+			}
+			if render {
+				if _, err := NewRenderNetworkAsGraph().Callable(ctx, map[string]interface{}{"nodes": graph.Nodes, "links": graph.Links}); err != nil {
+					warnings = append(warnings, mcp.NewToolWarning(envId, fmt.Errorf("failed to render the graph: %w", err)))
+				} else {
+					out = append(out, mcp.NewTextToolResponseContent("The graph was rendered in the browser."))
+				}
+			}
+			return out, mcp.AsPartialResult(warnings)
+		},
+	}
+}
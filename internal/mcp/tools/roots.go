@@ -0,0 +1,78 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/humanitec/canyon-cli/internal/mcp"
+)
+
+type workspaceRootsKey struct{}
+
+// WithWorkspaceRoots sets the directories that tools may read local files from when the MCP client does not share its
+// roots. Without either, only the working directory is readable.
+func WithWorkspaceRoots(ctx context.Context, roots []string) context.Context {
+	return context.WithValue(ctx, workspaceRootsKey{}, roots)
+}
+
+func workspaceRoots(ctx context.Context) ([]string, error) {
+	roots, ok := mcp.ClientRoots(ctx)
+	if !ok {
+		roots, _ = ctx.Value(workspaceRootsKey{}).([]string)
+	}
+	if len(roots) == 0 {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine the working directory: %w", err)
+		}
+		roots = []string{wd}
+	}
+	out := make([]string, 0, len(roots))
+	for _, r := range roots {
+		abs, err := filepath.Abs(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid workspace root '%s': %w", r, err)
+		}
+		out = append(out, abs)
+		if resolved, err := filepath.EvalSymlinks(abs); err == nil && resolved != abs {
+			out = append(out, resolved)
+		}
+	}
+	return out, nil
+}
+
+func insideRoots(roots []string, p string) bool {
+	for _, r := range roots {
+		if rel, err := filepath.Rel(r, p); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveWorkspacePath resolves a local file path and checks that it is inside one of the workspace roots, both before
+// and after following symlinks. Relative paths are relative to the first root.
+func resolveWorkspacePath(ctx context.Context, p string) (string, error) {
+	roots, err := workspaceRoots(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(roots[0], p)
+	}
+	p = filepath.Clean(p)
+	outside := fmt.Errorf("'%s' is outside of the workspace roots %s and cannot be read", p, strings.Join(roots, ", "))
+	if !insideRoots(roots, p) {
+		return "", outside
+	}
+	resolved, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		return "", fmt.Errorf("failed to access '%s': %w", p, err)
+	} else if !insideRoots(roots, resolved) {
+		return "", outside
+	}
+	return resolved, nil
+}
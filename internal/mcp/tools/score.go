@@ -0,0 +1,236 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/humanitec/humanitec-go-autogen/client"
+
+	"github.com/humanitec/canyon-cli/internal"
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec"
+	"github.com/humanitec/canyon-cli/internal/mcp"
+	"github.com/humanitec/canyon-cli/internal/score"
+)
+
+// scoreInputProperties are the input schema properties of the tools that read a local Score file.
+var scoreInputProperties = map[string]interface{}{
+	"path":            map[string]interface{}{"type": "string", "description": "The path of the Score file, relative paths are relative to the workspace root."},
+	"extensions_path": map[string]interface{}{"type": "string", "description": "Optional path of the Humanitec extensions file, defaults to humanitec.score.yaml next to the Score file if it exists."},
+}
+
+// readScoreInputs reads the Score file and its extensions from the workspace roots.
+func readScoreInputs(ctx context.Context, arguments map[string]interface{}) (score.Input, *score.Input, error) {
+	p, _ := arguments["path"].(string)
+	resolved, err := resolveWorkspacePath(ctx, p)
+	if err != nil {
+		return score.Input{}, nil, err
+	}
+	input, err := score.ReadInput(resolved)
+	if err != nil {
+		return score.Input{}, nil, err
+	}
+	extensionsPath, _ := arguments["extensions_path"].(string)
+	if extensionsPath == "" {
+		extensionsPath = score.DefaultExtensionsPath(resolved)
+	}
+	if extensionsPath == "" {
+		return input, nil, nil
+	}
+	if extensionsPath, err = resolveWorkspacePath(ctx, extensionsPath); err != nil {
+		return score.Input{}, nil, err
+	}
+	ext, err := score.ReadInput(extensionsPath)
+	if err != nil {
+		return score.Input{}, nil, err
+	}
+	return input, &ext, nil
+}
+
+func NewValidateScoreFile() mcp.Tool {
+	return mcp.Tool{
+		Name: "validate_score_file",
+		Description: `This tool validates a local Score workload file before it is deployed to Humanitec.
+It checks the file against the Score specification, checks the Humanitec extensions file (humanitec.score.yaml next to the Score file by default), checks that placeholders reference declared resources, and when an Organization is given, checks the resulting workload spec against the schema of its workload profile and that a Resource Definition exists for every resource type.
+The file must be inside the workspace roots the server was started with. The errors and warnings are returned with file, line, and column so that they can be fixed directly.`,
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path":            scoreInputProperties["path"],
+				"extensions_path": scoreInputProperties["extensions_path"],
+				"org_id":          map[string]interface{}{"type": "string", "description": "Optional Humanitec Organization (org) ID to check the workload profile and resource types against."},
+				"profile":         map[string]interface{}{"type": "string", "description": "Optional workload profile id to check against, overriding the profile of the extensions file."},
+				"cache_control":   cacheControlProperty,
+			},
+			"required":             []string{"path"},
+			"additionalProperties": false,
+		},
+		Callable: func(ctx context.Context, arguments map[string]interface{}) ([]mcp.CallToolResponseContent, error) {
+			input, extensions, err := readScoreInputs(ctx, arguments)
+			if err != nil {
+				return nil, err
+			}
+			opts := score.Options{Extensions: extensions}
+			opts.Profile, _ = arguments["profile"].(string)
+
+			if orgId, _ := arguments["org_id"].(string); orgId != "" {
+				ctx = withCacheControl(ctx, arguments)
+				hc, err := humanitec.NewHumanitecClientWithCurrentToken(ctx)
+				if err != nil {
+					return nil, err
+				}
+				if opts, err = score.WithOrganization(ctx, hc, orgId, opts); err != nil {
+					return nil, err
+				}
+			}
+
+			report := score.Validate(input, opts)
+			lines := make([]string, 0, len(report.Issues))
+			for _, i := range report.Issues {
+				lines = append(lines, i.String())
+			}
+			summary := fmt.Sprintf("The Score file '%s' is valid for workload profile '%s'", input.File, report.Profile)
+			if report.Errors() > 0 {
+				summary = fmt.Sprintf("The Score file '%s' has %d errors that must be fixed before deploying it with workload profile '%s'", input.File, report.Errors(), report.Profile)
+			}
+			if report.Warnings() > 0 {
+				summary += fmt.Sprintf(" and %d warnings", report.Warnings())
+			}
+			if len(lines) == 0 {
+				return []mcp.CallToolResponseContent{mcp.NewTextToolResponseContent("%s.", summary)}, nil
+			}
+			return []mcp.CallToolResponseContent{
+				mcp.NewTextToolResponseContent("%s:\n%s", summary, strings.Join(lines, "\n")),
+				mcp.NewTextToolResponseContent("The issues in JSON format: %s", string(internal.PrettyJson(report.Issues))),
+			}, nil
+		},
+	}
+}
+
+// scoreResourceMatch is the Resource Definition a resource dependency of a previewed workload would get.
+type scoreResourceMatch struct {
+	ResId       string `json:"resId"`
+	Type        string `json:"type"`
+	Class       string `json:"class"`
+	Outcome     string `json:"outcome"`
+	DefId       string `json:"defId,omitempty"`
+	Explanation string `json:"explanation"`
+}
+
+func matchScoreResources(defs []client.ResourceDefinitionResponse, resources []score.ModuleResource, mc matchingContext) []scoreResourceMatch {
+	out := make([]scoreResourceMatch, 0, len(resources))
+	for _, r := range resources {
+		mc.Type, mc.Class, mc.ResId = r.Type, r.Class, r.ResId
+		m := scoreResourceMatch{ResId: r.ResId, Type: r.Type, Class: r.Class, Outcome: "unmatched"}
+		ranked := rankDefinitions(defs, mc)
+		switch {
+		case len(ranked) == 0:
+			m.Explanation = fmt.Sprintf("there are no Resource Definitions of type '%s'", r.Type)
+		case ranked[0].Outcome == "not matched":
+			m.Explanation = fmt.Sprintf("none of the %d Resource Definitions of type '%s' match", len(ranked), r.Type)
+		default:
+			m.Outcome, m.DefId, m.Explanation = ranked[0].Outcome, ranked[0].DefId, ranked[0].Explanation
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func NewPreviewScoreDeployment() mcp.Tool {
+	return mcp.Tool{
+		Name: "preview_humanitec_score_deployment",
+		Description: `This tool previews the effect of deploying a local Score workload file to a Humanitec Environment without deploying it.
+It converts the Score file and its Humanitec extensions into the Deployment Set module the Platform Orchestrator would apply, and returns a path-level diff against the current Deployment Set of the Environment.
+It also matches every resource dependency of the workload against the Resource Definitions of the Organization and highlights the ones that have no matching Resource Definition, since a deployment would fail on them.
+The diff is also returned as CSV which can be shown with the render_csv_as_table_in_browser tool.`,
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path":            scoreInputProperties["path"],
+				"extensions_path": scoreInputProperties["extensions_path"],
+				"org_id":          map[string]interface{}{"type": "string", "description": "The Humanitec Organization (org) ID to work with."},
+				"app_id":          map[string]interface{}{"type": "string", "description": "The Humanitec Application (app) ID to work with."},
+				"env_id":          map[string]interface{}{"type": "string", "description": "The Humanitec Environment (env) ID to preview the deployment in."},
+				"cache_control":   cacheControlProperty,
+			},
+			"required":             []string{"path", "org_id", "app_id", "env_id"},
+			"additionalProperties": false,
+		},
+		Callable: func(ctx context.Context, arguments map[string]interface{}) ([]mcp.CallToolResponseContent, error) {
+			orgId, _ := arguments["org_id"].(string)
+			appId, _ := arguments["app_id"].(string)
+			envId, _ := arguments["env_id"].(string)
+			input, extensions, err := readScoreInputs(ctx, arguments)
+			if err != nil {
+				return nil, err
+			}
+
+			ctx = withCacheControl(ctx, arguments)
+			hc, err := humanitec.NewHumanitecClientWithCurrentToken(ctx)
+			if err != nil {
+				return nil, err
+			}
+			opts, err := score.WithOrganization(ctx, hc, orgId, score.Options{Extensions: extensions})
+			if err != nil {
+				return nil, err
+			}
+			if report := score.Validate(input, opts); report.Errors() > 0 {
+				return nil, fmt.Errorf("the Score file has %d errors, fix them before previewing the deployment, the validate_score_file tool lists them", report.Errors())
+			}
+			module, err := score.ToModule(input, extensions)
+			if err != nil {
+				return nil, err
+			}
+
+			env, err := humanitec.CheckResponse(func() (*client.GetEnvironmentResponse, error) {
+				return hc.GetEnvironmentWithResponse(ctx, orgId, appId, envId)
+			}).AndStatusCodeEq(http.StatusOK).RespAndError()
+			if err != nil {
+				return nil, err
+			}
+			current := make(map[string]interface{})
+			currentText := fmt.Sprintf("the empty Deployment Set since Environment '%s' was never deployed", envId)
+			if env.JSON200.LastDeploy != nil {
+				if current, err = getSetContent(ctx, hc, orgId, appId, env.JSON200.LastDeploy.SetId); err != nil {
+					return nil, err
+				}
+				currentText = fmt.Sprintf("the current Deployment Set '%s' of Environment '%s'", env.JSON200.LastDeploy.SetId, envId)
+			}
+			changes := diffSetValues(nil, current, module.Apply(current), make([]setChange, 0))
+
+			defs, err := humanitec.CheckResponse(func() (*client.ListResourceDefinitionsResponse, error) {
+				return hc.ListResourceDefinitionsWithResponse(ctx, orgId, &client.ListResourceDefinitionsParams{})
+			}).AndStatusCodeEq(http.StatusOK).RespAndError()
+			if err != nil {
+				return nil, err
+			}
+			matches := matchScoreResources(humanitec.DerefSlice(defs.JSON200), module.Resources(), matchingContext{AppId: appId, EnvId: envId, EnvType: env.JSON200.Type})
+			unmatched := make([]string, 0)
+			for _, m := range matches {
+				if m.Outcome == "unmatched" {
+					unmatched = append(unmatched, fmt.Sprintf("'%s' (type '%s', class '%s')", m.ResId, m.Type, m.Class))
+				}
+			}
+
+			summary := fmt.Sprintf("Deploying workload '%s' to Environment '%s' of Application '%s' would make %d changes to %s. Nothing was deployed.", module.Name, envId, appId, len(changes), currentText)
+			if len(changes) == 0 {
+				summary = fmt.Sprintf("Deploying workload '%s' would not change %s. Nothing was deployed.", module.Name, currentText)
+			}
+			if len(unmatched) > 0 {
+				summary += fmt.Sprintf(" WARNING: %d resource dependencies have no matching Resource Definition and would fail the deployment: %s.", len(unmatched), strings.Join(unmatched, ", "))
+			}
+			for _, n := range module.Notes {
+				summary += fmt.Sprintf(" Note: %s.", n)
+			}
+			out := []mcp.CallToolResponseContent{
+				mcp.NewTextToolResponseContent("%s The Deployment Set delta in JSON format: %s", summary, string(internal.PrettyJson(module))),
+				mcp.NewTextToolResponseContent("The resource dependencies and the Resource Definitions they would match in JSON format: %s", string(internal.PrettyJson(matches))),
+			}
+			if len(changes) > 0 {
+				out = append(out, mcp.NewTextToolResponseContent("The differences as CSV: %s", setChangesAsCsv(changes)))
+			}
+			return out, nil
+		},
+	}
+}
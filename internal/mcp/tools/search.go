@@ -0,0 +1,211 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/humanitec/canyon-cli/internal"
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec"
+	"github.com/humanitec/canyon-cli/internal/mcp"
+)
+
+// defaultSearchMaxResults is the number of matches returned when max_results is not given.
+const defaultSearchMaxResults = 100
+
+// searchCriteria are the kinds of value the search tool can look for, in the order their arguments are listed.
+var searchCriteria = []string{"image", "workload", "resource_type", "env_var", "metadata_key"}
+
+// searchMatch is a location in the latest Deployment Set of an Environment that matches the search.
+type searchMatch struct {
+	AppId    string      `json:"appId"`
+	EnvId    string      `json:"envId"`
+	EnvType  string      `json:"envType"`
+	SetId    string      `json:"setId"`
+	Workload string      `json:"workload,omitempty"`
+	Kind     string      `json:"kind"`
+	Path     string      `json:"path"`
+	Value    interface{} `json:"value,omitempty"`
+}
+
+// compileSearchPattern compiles a search pattern. Patterns with '*' or '?' wildcards must match the whole value,
+// other patterns match any part of it. Both ignore case.
+func compileSearchPattern(pattern string) *regexp.Regexp {
+	expr := regexp.QuoteMeta(pattern)
+	if strings.ContainsAny(pattern, "*?") {
+		expr = "^" + strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(expr) + "$"
+	}
+	return regexp.MustCompile("(?i)" + expr)
+}
+
+func resourceMatchValue(r resourceIndex) interface{} {
+	if r.Class != "" {
+		return map[string]interface{}{"type": r.Type, "class": r.Class}
+	}
+	return r.Type
+}
+
+// searchWorkload returns the matches of the workload, or nothing when any of the criteria has no match. The workload
+// criterion scopes the other criteria and is only returned as a match on its own.
+func searchWorkload(w workloadIndex, criteria map[string]*regexp.Regexp) []searchMatch {
+	if pattern, ok := criteria["workload"]; ok && !pattern.MatchString(w.Name) {
+		return nil
+	}
+	out := make([]searchMatch, 0)
+	if len(criteria) == 1 && criteria["workload"] != nil {
+		return append(out, searchMatch{Workload: w.Name, Kind: "workload", Path: "modules." + w.Name, Value: w.Profile})
+	}
+	for _, criterion := range searchCriteria {
+		pattern, ok := criteria[criterion]
+		if !ok || criterion == "workload" {
+			continue
+		}
+		found := make([]searchMatch, 0)
+		switch criterion {
+		case "image":
+			for _, c := range w.Containers {
+				if c.Image != "" && pattern.MatchString(c.Image) {
+					found = append(found, searchMatch{Kind: "image", Path: "modules." + w.Name + ".spec.containers." + c.Name + ".image", Value: c.Image})
+				}
+			}
+		case "resource_type":
+			for _, r := range w.Externals {
+				if pattern.MatchString(r.Type) {
+					found = append(found, searchMatch{Kind: "resource", Path: r.Path, Value: resourceMatchValue(r)})
+				}
+			}
+		case "env_var":
+			for _, c := range w.Containers {
+				for _, name := range slices.Sorted(maps.Keys(c.Variables)) {
+					if pattern.MatchString(name) {
+						found = append(found, searchMatch{Kind: "variable", Path: "modules." + w.Name + ".spec.containers." + c.Name + ".variables." + name, Value: c.Variables[name]})
+					}
+				}
+			}
+		case "metadata_key":
+			for _, m := range w.Metadata {
+				if pattern.MatchString(m.Key) {
+					found = append(found, searchMatch{Kind: "metadata", Path: m.Path, Value: m.Value})
+				}
+			}
+		}
+		if len(found) == 0 {
+			return nil
+		}
+		out = append(out, found...)
+	}
+	for i := range out {
+		out[i].Workload = w.Name
+	}
+	return out
+}
+
+// searchIndex returns the matches of the criteria in every indexed Environment. Shared resources belong to no
+// workload so they are only searched when the resource type is the only criterion.
+func searchIndex(index *orgIndex, criteria map[string]*regexp.Regexp) ([]searchMatch, int) {
+	out := make([]searchMatch, 0)
+	workloads := make(map[string]bool)
+	for _, e := range index.Envs {
+		found := make([]searchMatch, 0)
+		for _, w := range e.Workloads {
+			if matches := searchWorkload(w, criteria); len(matches) > 0 {
+				workloads[e.AppId+"/"+e.EnvId+"/"+w.Name] = true
+				found = append(found, matches...)
+			}
+		}
+		if pattern, ok := criteria["resource_type"]; ok && len(criteria) == 1 {
+			for _, r := range e.Shared {
+				if pattern.MatchString(r.Type) {
+					found = append(found, searchMatch{Kind: "resource", Path: r.Path, Value: resourceMatchValue(r)})
+				}
+			}
+		}
+		for _, m := range found {
+			m.AppId, m.EnvId, m.EnvType, m.SetId = e.AppId, e.EnvId, e.EnvType, e.SetId
+			out = append(out, m)
+		}
+	}
+	return out, len(workloads)
+}
+
+func NewSearchOrganization() mcp.Tool {
+	return mcp.Tool{
+		Name: "search_humanitec_organization",
+		Description: `This tool searches the latest Deployment Set of every Environment in a Humanitec Organization and returns the locations that match.
+It answers questions such as which workloads run an image, where a workload is deployed, which workloads use a resource type such as postgres, which containers set an environment variable, or which workloads carry a metadata key.
+Search by image, workload name, resource_type, env_var name, and metadata_key (the keys of workload annotations, which include the metadata annotations of Score files, and labels). Patterns match any part of the value ignoring case, or the whole value when they contain '*' or '?' wildcards.
+When several criteria are given, only workloads that match all of them are returned. Each match has the app, env, set, workload, and the dotted path and value in the Deployment Set.
+The search can be limited to one Application with app_id or to one Environment Type with env_type. Deployment Sets are indexed with bounded concurrency and kept in memory, use cache_control to see Environments that were deployed in the last minute.`,
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"org_id":        map[string]interface{}{"type": "string", "description": "The Humanitec Organization (org) ID to work with."},
+				"app_id":        map[string]interface{}{"type": "string", "description": "Optional Humanitec Application (app) ID to limit the search to."},
+				"env_type":      map[string]interface{}{"type": "string", "description": "Optional Environment Type to limit the search to, such as development or production."},
+				"image":         map[string]interface{}{"type": "string", "description": "The container image to find, such as 'ghcr.io/org/api' or '*:latest'."},
+				"workload":      map[string]interface{}{"type": "string", "description": "The workload name to find."},
+				"resource_type": map[string]interface{}{"type": "string", "description": "The resource type to find, such as 'postgres'."},
+				"env_var":       map[string]interface{}{"type": "string", "description": "The container environment variable name to find."},
+				"metadata_key":  map[string]interface{}{"type": "string", "description": "The workload annotation or label key to find."},
+				"max_results":   map[string]interface{}{"type": "integer", "minimum": 1, "description": fmt.Sprintf("Optional maximum number of matches to return, defaults to %d.", defaultSearchMaxResults)},
+				"cache_control": cacheControlProperty,
+			},
+			"required":             []string{"org_id"},
+			"additionalProperties": false,
+		},
+		Callable: func(ctx context.Context, arguments map[string]interface{}) ([]mcp.CallToolResponseContent, error) {
+			orgId, _ := arguments["org_id"].(string)
+			appId, _ := arguments["app_id"].(string)
+			envType, _ := arguments["env_type"].(string)
+			criteria := make(map[string]*regexp.Regexp)
+			for _, criterion := range searchCriteria {
+				if pattern, _ := arguments[criterion].(string); pattern != "" {
+					criteria[criterion] = compileSearchPattern(pattern)
+				}
+			}
+			if len(criteria) == 0 {
+				return nil, fmt.Errorf("at least one of %s is required", strings.Join(searchCriteria, ", "))
+			}
+			maxResults := defaultSearchMaxResults
+			if v, ok := arguments["max_results"].(float64); ok && v >= 1 {
+				maxResults = int(v)
+			}
+
+			ctx = withCacheControl(ctx, arguments)
+			hc, err := humanitec.NewHumanitecClientWithCurrentToken(ctx)
+			if err != nil {
+				return nil, err
+			}
+			var appIds []string
+			if appId != "" {
+				appIds = []string{appId}
+			}
+			index, warnings, err := indexOrganization(ctx, hc, orgId, appIds, envType)
+			if err != nil {
+				return nil, err
+			}
+
+			matches, workloads := searchIndex(index, criteria)
+			summary := fmt.Sprintf("Searched the latest Deployment Sets of %d Environments in %d Applications", len(index.Envs), index.Apps)
+			if envType != "" {
+				summary = fmt.Sprintf("Searched the latest Deployment Sets of %d Environments of type '%s' in %d Applications", len(index.Envs), envType, index.Apps)
+			}
+			if index.Undeployed > 0 {
+				summary += fmt.Sprintf(", %d Environments have never been deployed", index.Undeployed)
+			}
+			summary += fmt.Sprintf(". Found %d matches in %d workloads", len(matches), workloads)
+			if len(matches) == 0 {
+				return []mcp.CallToolResponseContent{mcp.NewTextToolResponseContent("%s.", summary)}, mcp.AsPartialResult(warnings)
+			} else if len(matches) > maxResults {
+				summary += fmt.Sprintf(", the first %d are returned, narrow the search to see the others", maxResults)
+				matches = matches[:maxResults]
+			}
+			return []mcp.CallToolResponseContent{
+				mcp.NewTextToolResponseContent("%s. The matches in JSON format: %s", summary, string(internal.PrettyJson(matches))),
+			}, mcp.AsPartialResult(warnings)
+		},
+	}
+}
@@ -3,7 +3,7 @@ package tools
 import "github.com/humanitec/canyon-cli/internal/mcp"
 
 func New() mcp.McpIo {
-	return &mcp.Impl{
+	impl := &mcp.Impl{
 		Instructions: `The canyon MCP tools are used to support platform engineers working with Humanitec or Canyon platform orchestration.
 The provided tools are high quality and should be preferred for any humanitec-related tasks where possible rather than humctl commands.
 The AI documentation tool provides high accuracy answers to clear up any confusion or uncertainty on Humanitec related topics.
@@ -11,20 +11,48 @@ When using these tools, use the minimum amount of words to still convey the info
 A Humanitec organization aka org contains many applications which each container environments. Each deployed environment is described by a deployment set in the latest deployment.
 'workloads' may be another word used for the containers within the deployment set deployed in an environment.
 'resources' may be another word used for the externals and shared resources declared in the deployment set of an environment.
+Applications may have Humanitec Pipelines that automate delivery, such as promoting a deployment between environments. Pipeline runs have jobs made of steps and may wait for approval requests to be approved or denied.
 When starting a new chat, always confirm the humanitec organization to work in.
+The user may have multiple credential profiles for different organizations, use the switch_humanitec_profile tool to list and change between them.
+Required org_id, app_id, and env_id arguments may be left out to use the default Organization, Application, and Environment of the active profile, which the list_humanitec_orgs_and_session tool reports.
+Tools that deploy to an environment, trigger pipeline runs, or decide approval requests first return a dry-run with a confirmation_token. Never pass the confirmation_token without showing the dry-run changes to the user and getting their explicit confirmation.
 `,
 		Tools: []mcp.Tool{
 			NewKapaAiDocsTool(),
 			NewListPathsTool(),
 			NewCallPathTool(),
 			NewListHumanitecOrgsAndSession(),
+			NewSwitchHumanitecProfile(),
 			NewListAppsAndEnvsForOrganization(),
 			NewGetHumanitecDeploymentSets(),
+			NewDiffHumanitecDeploymentSets(),
+			NewDetectEnvironmentDrift(),
+			NewSearchOrganization(),
+			NewGetImageInventory(),
+			NewGetActiveResourceGraph(),
+			NewExplainResourceDefinitionMatching(),
+			NewListDeployments(),
+			NewGetDeployment(),
+			NewDeployToEnvironment(),
+			NewRollbackEnvironment(),
+			NewListPipelines(),
+			NewGetPipelineDefinition(),
+			NewListPipelineRuns(),
+			NewGetPipelineRun(),
+			NewGetPipelineStepLogs(),
+			NewTriggerPipelineRun(),
+			NewRespondToPipelineApproval(),
 			NewGetWorkloadProfileSchema(),
+			NewValidateScoreFile(),
+			NewPreviewScoreDeployment(),
 			NewRenderCSVAsTable(),
 			NewRenderNetworkAsGraph(),
 			NewRenderTreeAsTree(),
-			NewDummyMetadataKeysTool(),
+			NewListMetadataKeys(),
 		},
 	}
+	for i, tool := range impl.Tools {
+		impl.Tools[i] = withProfileDefaults(tool)
+	}
+	return impl
 }
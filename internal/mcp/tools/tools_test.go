@@ -0,0 +1,750 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/humanitec/humanitec-go-autogen/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec"
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec/fakeapi"
+	"github.com/humanitec/canyon-cli/internal/mcp"
+	"github.com/humanitec/canyon-cli/internal/rpc"
+	"github.com/humanitec/canyon-cli/internal/score"
+)
+
+// isolate isolates the test from the local credentials and response cache.
+func isolate(t *testing.T) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("HUMANITEC_TOKEN", "")
+	t.Setenv("CANYON_PROFILES_FILE", filepath.Join(home, "profiles.yaml"))
+	require.NoError(t, humanitec.ConfigureDefaultResponseCache(0, ""))
+}
+
+// replayContext serves Humanitec responses from the named cassette in testdata/cassettes.
+func replayContext(t *testing.T, cassette string) context.Context {
+	t.Helper()
+	isolate(t)
+	r, err := humanitec.LoadCassetteReplayer(filepath.Join("testdata", "cassettes", cassette))
+	require.NoError(t, err)
+	return humanitec.WithHttpRequestDoer(context.Background(), r)
+}
+
+// demoContext serves Humanitec responses from the bundled demo Organization.
+func demoContext(t *testing.T) context.Context {
+	t.Helper()
+	isolate(t)
+	return humanitec.WithHttpRequestDoer(context.Background(), fakeapi.New(fakeapi.DefaultFixture()))
+}
+
+func callTool(t *testing.T, ctx context.Context, tool mcp.Tool, arguments map[string]interface{}) *mcp.CallToolResponse {
+	t.Helper()
+	impl := &mcp.Impl{Tools: []mcp.Tool{tool}}
+	raw, _ := json.Marshal(arguments)
+	var args map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &args))
+	r, err := impl.CallTool(ctx, mcp.CallToolRequest{Name: tool.Name, Arguments: args})
+	require.NoError(t, err)
+	return r
+}
+
+func TestListAppsAndEnvsForOrganization(t *testing.T) {
+	ctx := replayContext(t, "list-apps")
+	r := callTool(t, ctx, NewListAppsAndEnvsForOrganization(), map[string]interface{}{"org_id": "demo"})
+	assert.False(t, r.IsError)
+	require.Len(t, r.Contents, 2)
+	assert.Contains(t, r.Contents[0].Text, `"lastDeploymentSetId": "set-1"`)
+	assert.NotContains(t, r.Contents[0].Text, `app-2`)
+	assert.Equal(t, true, r.StructuredContent["partial"])
+	warnings := r.StructuredContent["warnings"].([]mcp.ToolWarning)
+	require.Len(t, warnings, 1)
+	assert.Equal(t, "app-2", warnings[0].Item)
+	assert.Equal(t, "upstream-5xx", warnings[0].Error["kind"])
+}
+
+func TestListHumanitecOrgsAndSession(t *testing.T) {
+	ctx := replayContext(t, "session")
+	r := callTool(t, ctx, NewListHumanitecOrgsAndSession(), map[string]interface{}{})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, "The user is currently logged in.")
+	assert.Contains(t, r.Contents[0].Text, `"demo": "administrator"`)
+	assert.Contains(t, r.Contents[0].Text, `"other": "member"`)
+}
+
+func TestGetWorkloadProfileSchema(t *testing.T) {
+	ctx := replayContext(t, "workload-profile")
+	r := callTool(t, ctx, NewGetWorkloadProfileSchema(), map[string]interface{}{"org_id": "demo", "workload_profile_id": "humanitec/default-module"})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, `"replicas": {`)
+
+	r = callTool(t, ctx, NewGetWorkloadProfileSchema(), map[string]interface{}{"org_id": "demo", "workload_profile_id": "humanitec/unknown"})
+	assert.True(t, r.IsError)
+}
+
+func TestGetHumanitecDeploymentSets(t *testing.T) {
+	ctx := replayContext(t, "sets")
+	r := callTool(t, ctx, NewGetHumanitecDeploymentSets(), map[string]interface{}{"org_id": "demo", "app_id": "app-1", "set_ids": []string{"set-1", "set-2"}})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, "registry.example.com/api:1.0.0")
+	assert.Equal(t, true, r.StructuredContent["partial"])
+	warnings := r.StructuredContent["warnings"].([]mcp.ToolWarning)
+	require.Len(t, warnings, 1)
+	assert.Equal(t, "set-2", warnings[0].Item)
+}
+
+func TestPaths(t *testing.T) {
+	ctx := replayContext(t, "paths")
+	r := callTool(t, ctx, NewListPathsTool(), map[string]interface{}{"org_id": "demo"})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, `"name": "get-owner"`)
+	assert.Contains(t, r.Contents[0].Text, `"required": [`)
+
+	r = callTool(t, ctx, NewCallPathTool(), map[string]interface{}{
+		"org_id": "demo", "name": "get-owner", "idempotency_key": "key-1", "arguments": map[string]interface{}{"workload": "api"},
+	})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, `"team": "platform-squad"`)
+}
+
+func TestWithProfileDefaults(t *testing.T) {
+	ctx := demoContext(t)
+	t.Setenv("HUMANITEC_ORG", "canyon-demo")
+	t.Setenv("HUMANITEC_APP", "")
+	t.Setenv("HUMANITEC_ENV", "production")
+
+	tool := withProfileDefaults(NewListDeployments())
+	assert.Empty(t, tool.InputSchema["required"])
+	r := callTool(t, ctx, tool, map[string]interface{}{"app_id": "frontend"})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, `"id": "17e3c2a9f1b04d41"`)
+
+	r = callTool(t, ctx, tool, map[string]interface{}{})
+	assert.True(t, r.IsError)
+	assert.Contains(t, r.Contents[0].Text, "app_id is required since the active profile 'default' has no default Application")
+
+	// optional arguments keep their meaning when left out
+	tool = withProfileDefaults(NewSearchOrganization())
+	assert.NotContains(t, tool.InputSchema["properties"].(map[string]interface{})["app_id"].(map[string]interface{})["description"], "active profile")
+}
+
+func TestDemoMode(t *testing.T) {
+	ctx := demoContext(t)
+
+	r := callTool(t, ctx, NewListHumanitecOrgsAndSession(), map[string]interface{}{})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, `"canyon-demo": "administrator"`)
+	assert.Contains(t, r.Contents[0].Text, "served offline")
+
+	r = callTool(t, ctx, NewListPathsTool(), map[string]interface{}{"org_id": "canyon-demo"})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, `"name": "get-workload-owner"`)
+
+	r = callTool(t, ctx, NewCallPathTool(), map[string]interface{}{
+		"org_id": "canyon-demo", "name": "get-workload-owner", "idempotency_key": "",
+		"arguments": map[string]interface{}{"app_id": "checkout", "workload": "checkout"},
+	})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, `"team": "payments-squad"`)
+
+	r = callTool(t, ctx, NewKapaAiDocsTool(), map[string]interface{}{"query": "How do I promote a deployment?"})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, "Deployment Set of the source environment")
+
+	first := callTool(t, ctx, NewListAppsAndEnvsForOrganization(), map[string]interface{}{"org_id": "canyon-demo"})
+	second := callTool(t, ctx, NewListAppsAndEnvsForOrganization(), map[string]interface{}{"org_id": "canyon-demo"})
+	assert.Equal(t, first, second)
+}
+
+func TestListDeployments(t *testing.T) {
+	ctx := demoContext(t)
+	args := map[string]interface{}{"org_id": "canyon-demo", "app_id": "frontend", "env_id": "development"}
+
+	r := callTool(t, ctx, NewListDeployments(), args)
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, `"id": "17e3c2a9f1b04d52"`)
+	assert.Contains(t, r.Contents[0].Text, `"duration": "2m14s"`)
+	assert.Contains(t, r.Contents[0].Text, `"code": "DNS-001"`)
+
+	args["status"] = "failed"
+	args["since"] = "2025-03-01"
+	r = callTool(t, ctx, NewListDeployments(), args)
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, `"id": "17e3c2a9f1b04d47"`)
+	assert.NotContains(t, r.Contents[0].Text, `"status": "succeeded"`)
+
+	// a date as the end of the range includes the deployments of that day
+	r = callTool(t, ctx, NewListDeployments(), map[string]interface{}{"org_id": "canyon-demo", "app_id": "frontend", "env_id": "development", "until": "2025-03-01"})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, `"id": "17e3c2a9f1b04d47"`)
+	assert.Contains(t, r.Contents[0].Text, `"id": "17e3c2a9f1b04d33"`)
+	assert.NotContains(t, r.Contents[0].Text, `"id": "17e3c2a9f1b04d52"`)
+
+	delete(args, "status")
+	args["limit"] = 1
+	r = callTool(t, ctx, NewListDeployments(), args)
+	assert.Contains(t, r.Contents[0].Text, "Only the newest 1 of 2 matching deployments are shown")
+
+	args["since"] = "last week"
+	r = callTool(t, ctx, NewListDeployments(), args)
+	assert.True(t, r.IsError)
+
+	r = callTool(t, ctx, NewGetDeployment(), map[string]interface{}{"org_id": "canyon-demo", "app_id": "backend", "env_id": "development", "deploy_id": "29a0d5e7c3f14b18"})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, `"code": "WL-003"`)
+}
+
+func TestDiffHumanitecDeploymentSets(t *testing.T) {
+	ctx := demoContext(t)
+	r := callTool(t, ctx, NewDiffHumanitecDeploymentSets(), map[string]interface{}{
+		"org_id": "canyon-demo", "app_id": "checkout",
+		"from": map[string]interface{}{"env_id": "production"},
+		"to":   map[string]interface{}{"env_id": "staging"},
+	})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	require.Len(t, r.Contents, 2)
+	assert.Contains(t, r.Contents[0].Text, "There are 3 differences from set 'checkout-set-1' from the latest deployment of Environment 'production' to set 'checkout-set-2'")
+	assert.Equal(t, `The differences as CSV: kind,change,path,from,to
+resource,added,modules.checkout.externals.payments,,"{""type"":""config""}"
+image,changed,modules.checkout.spec.containers.checkout.image,ghcr.io/canyon-demo/checkout:0.9.0,ghcr.io/canyon-demo/checkout:1.0.0
+variable,added,modules.checkout.spec.containers.checkout.variables.PAYMENT_API_KEY,,${externals.payments.api_key}
+`, r.Contents[1].Text)
+
+	r = callTool(t, ctx, NewDiffHumanitecDeploymentSets(), map[string]interface{}{
+		"org_id": "canyon-demo", "app_id": "frontend",
+		"from": map[string]interface{}{"env_id": "development", "deploy_id": "17e3c2a9f1b04d33"},
+		"to":   map[string]interface{}{"set_id": "frontend-set-1"},
+	})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Equal(t, "There are no differences between set 'frontend-set-1' from deployment '17e3c2a9f1b04d33' of Environment 'development' and set 'frontend-set-1'.", r.Contents[0].Text)
+
+	r = callTool(t, ctx, NewDiffHumanitecDeploymentSets(), map[string]interface{}{
+		"org_id": "canyon-demo", "app_id": "frontend",
+		"from": map[string]interface{}{"deploy_id": "17e3c2a9f1b04d33"},
+		"to":   map[string]interface{}{"set_id": "frontend-set-1"},
+	})
+	assert.True(t, r.IsError)
+}
+
+func TestDetectEnvironmentDrift(t *testing.T) {
+	ctx := demoContext(t)
+	r := callTool(t, ctx, NewDetectEnvironmentDrift(), map[string]interface{}{
+		"org_id": "canyon-demo", "app_id": "checkout", "env_types": []string{"development", "staging", "production"},
+	})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	require.Len(t, r.Contents, 2)
+	assert.Contains(t, r.Contents[0].Text, "Found 3 drifting values in 1 workloads.")
+	assert.Equal(t, `The drift as CSV: workload,kind,path,development,staging,production
+checkout,resource,modules.checkout.externals.payments.type,config,config,
+checkout,image,modules.checkout.spec.containers.checkout.image,ghcr.io/canyon-demo/checkout:1.0.0,ghcr.io/canyon-demo/checkout:1.0.0,ghcr.io/canyon-demo/checkout:0.9.0
+checkout,variable,modules.checkout.spec.containers.checkout.variables.PAYMENT_API_KEY,${externals.payments.api_key},${externals.payments.api_key},
+`, r.Contents[1].Text)
+
+	r = callTool(t, ctx, NewDetectEnvironmentDrift(), map[string]interface{}{
+		"org_id": "canyon-demo", "app_id": "checkout", "env_ids": []string{"production", "development"},
+		"ignore": []string{"*.image", "*.payments.*", "*.PAYMENT_API_KEY"},
+	})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, "Found 0 drifting values in 0 workloads, 3 differing values were ignored.")
+
+	r = callTool(t, ctx, NewDetectEnvironmentDrift(), map[string]interface{}{
+		"org_id": "canyon-demo", "app_id": "backend", "env_ids": []string{"development", "staging"},
+	})
+	assert.True(t, r.IsError)
+	assert.Contains(t, r.Contents[0].Text, "at least 2 deployed environments are required")
+}
+
+func TestSearchOrganization(t *testing.T) {
+	ctx := demoContext(t)
+	search := func(arguments map[string]interface{}) []searchMatch {
+		t.Helper()
+		arguments["org_id"] = "canyon-demo"
+		r := callTool(t, ctx, NewSearchOrganization(), arguments)
+		require.False(t, r.IsError, r.Contents[0].Text)
+		_, raw, ok := strings.Cut(r.Contents[0].Text, "The matches in JSON format: ")
+		if !ok {
+			return nil
+		}
+		var out []searchMatch
+		require.NoError(t, json.Unmarshal([]byte(raw), &out))
+		return out
+	}
+	locations := func(matches []searchMatch) []string {
+		out := make([]string, 0, len(matches))
+		for _, m := range matches {
+			out = append(out, m.AppId+"/"+m.EnvId+" "+m.Path)
+		}
+		return out
+	}
+
+	r := callTool(t, ctx, NewSearchOrganization(), map[string]interface{}{"org_id": "canyon-demo", "image": "canyon-demo/web"})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, "Searched the latest Deployment Sets of 6 Environments in 3 Applications, 1 Environments have never been deployed. Found 2 matches in 2 workloads.")
+
+	assert.Equal(t, []string{
+		"frontend/development modules.web.spec.containers.web.image",
+		"frontend/production modules.web.spec.containers.web.image",
+	}, locations(search(map[string]interface{}{"image": "CANYON-DEMO/web"})))
+	r = callTool(t, ctx, NewSearchOrganization(), map[string]interface{}{"org_id": "canyon-demo", "image": "canyon-demo/web", "env_type": "production"})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, "Searched the latest Deployment Sets of 2 Environments of type 'production' in 3 Applications. Found 1 matches in 1 workloads.")
+	assert.Equal(t, []string{"backend/development modules.api.externals.db"}, locations(search(map[string]interface{}{"resource_type": "postgres"})))
+	assert.Equal(t, []string{
+		"checkout/development modules.checkout.spec.containers.checkout.variables.PAYMENT_API_KEY",
+		"checkout/staging modules.checkout.spec.containers.checkout.variables.PAYMENT_API_KEY",
+	}, locations(search(map[string]interface{}{"env_var": "payment_api_key"})))
+	assert.Equal(t, []string{
+		"checkout/production modules.reconcile.spec.containers.reconcile.image",
+	}, locations(search(map[string]interface{}{"workload": "reconcile", "image": "*:0.9.0", "env_type": "production"})))
+	assert.Empty(t, search(map[string]interface{}{"workload": "web", "resource_type": "postgres"}))
+
+	matches := search(map[string]interface{}{"metadata_key": "canyon.dev/owner", "app_id": "frontend"})
+	require.Len(t, matches, 2)
+	assert.Equal(t, searchMatch{
+		AppId: "frontend", EnvId: "development", EnvType: "development", SetId: "frontend-set-2", Workload: "web",
+		Kind: "metadata", Path: "modules.web.spec.annotations.canyon.dev/owner", Value: "web-squad",
+	}, matches[0])
+	assert.Len(t, search(map[string]interface{}{"workload": "*", "max_results": 2}), 2)
+
+	r = callTool(t, ctx, NewSearchOrganization(), map[string]interface{}{"org_id": "canyon-demo"})
+	assert.True(t, r.IsError)
+	r = callTool(t, ctx, NewSearchOrganization(), map[string]interface{}{"org_id": "canyon-demo", "app_id": "unknown", "workload": "web"})
+	assert.True(t, r.IsError)
+	assert.Contains(t, r.Contents[0].Text, "application 'unknown' does not exist")
+}
+
+func TestGetImageInventory(t *testing.T) {
+	ctx := demoContext(t)
+	r := callTool(t, ctx, NewGetImageInventory(), map[string]interface{}{"org_id": "canyon-demo"})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	require.Len(t, r.Contents, 2)
+	assert.Contains(t, r.Contents[0].Text, "Found 4 containers in the latest Deployment Sets of 6 Environments in 3 Applications, 2 have version skew and 2 have issues.")
+	assert.Equal(t, `The inventory as CSV: app,workload,container,development,production,staging,issues
+backend,api,api,ghcr.io/canyon-demo/api:2.0.1,,,
+checkout,checkout,checkout,ghcr.io/canyon-demo/checkout:1.0.0,ghcr.io/canyon-demo/checkout:0.9.0,ghcr.io/canyon-demo/checkout:1.0.0,version skew across 2 images
+checkout,reconcile,reconcile,ghcr.io/canyon-demo/checkout-reconcile:0.9.0,ghcr.io/canyon-demo/checkout-reconcile:0.9.0,ghcr.io/canyon-demo/checkout-reconcile:0.9.0,
+frontend,web,web,ghcr.io/canyon-demo/web:1.4.0,ghcr.io/canyon-demo/web:1.3.2,,version skew across 2 images
+`, r.Contents[1].Text)
+
+	r = callTool(t, ctx, NewGetImageInventory(), map[string]interface{}{"org_id": "canyon-demo", "app_id": "checkout", "only_issues": true})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Equal(t, `The inventory as CSV: app,workload,container,development,staging,production,issues
+checkout,checkout,checkout,ghcr.io/canyon-demo/checkout:1.0.0,ghcr.io/canyon-demo/checkout:1.0.0,ghcr.io/canyon-demo/checkout:0.9.0,version skew across 2 images
+`, r.Contents[1].Text)
+}
+
+func TestBuildImageInventory(t *testing.T) {
+	assert.Equal(t, imageRef{Repository: "registry:5000/team/api", Tag: "1.0"}, parseImageRef("registry:5000/team/api:1.0"))
+	assert.Equal(t, imageRef{Repository: "api", Digest: "sha256:abc"}, parseImageRef("api@sha256:abc"))
+
+	workload := func(image string) *setIndex {
+		return &setIndex{Workloads: []workloadIndex{{Name: "api", Containers: []containerIndex{{Name: "main", Image: image}}}}}
+	}
+	index := &orgIndex{Envs: []envIndex{
+		{AppId: "a", EnvId: "dev", EnvType: "development", setIndex: workload("api")},
+		{AppId: "a", EnvId: "preview", EnvType: "development", setIndex: workload("api:latest")},
+		{AppId: "a", EnvId: "prod", EnvType: "production", setIndex: workload("api@sha256:abc")},
+	}}
+	entries, columns := buildImageInventory(index, false)
+	assert.Equal(t, []string{"dev", "preview", "prod"}, columns)
+	require.Len(t, entries, 1)
+	assert.True(t, entries[0].Skew)
+	assert.Equal(t, []string{"version skew across 3 images", "untagged image in dev", "latest tag in preview"}, entries[0].Issues)
+
+	// environments of the same type share a column without hiding each other
+	entries, columns = buildImageInventory(index, true)
+	assert.Equal(t, []string{"development", "production"}, columns)
+	assert.Equal(t, map[string][]string{"development": {"api", "api:latest"}, "production": {"api@sha256:abc"}}, entries[0].Images)
+	assert.True(t, entries[0].Skew)
+	assert.Equal(t, []string{"version skew across 3 images", "untagged image in dev", "environments of type development run different images", "latest tag in preview"}, entries[0].Issues)
+	assert.Equal(t, "app,workload,container,development,production,issues\na,api,main,\"api, api:latest\",api@sha256:abc,version skew across 3 images; untagged image in dev; environments of type development run different images; latest tag in preview\n", imageInventoryAsCsv(entries, columns))
+
+	index.Envs[2].setIndex = workload("api")
+	entries, _ = buildImageInventory(&orgIndex{Envs: []envIndex{index.Envs[0], index.Envs[2]}}, true)
+	assert.Equal(t, map[string][]string{"development": {"api"}, "production": {"api"}}, entries[0].Images)
+	assert.False(t, entries[0].Skew)
+	assert.Equal(t, []string{"untagged image in dev", "untagged image in prod"}, entries[0].Issues)
+}
+
+func TestListMetadataKeys(t *testing.T) {
+	ctx := demoContext(t)
+	r := callTool(t, ctx, NewListMetadataKeys(), map[string]interface{}{"org_id": "canyon-demo"})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, "Found 4 metadata keys in Organization 'canyon-demo' as of ")
+	assert.Contains(t, r.Contents[0].Text, ": canyon.dev/owner, canyon.dev/repo, tier, gcp-project.")
+	_, raw, ok := strings.Cut(r.Contents[0].Text, "The keys in JSON format: ")
+	require.True(t, ok)
+	var keys []metadataKeyUsage
+	require.NoError(t, json.Unmarshal([]byte(raw), &keys))
+	require.Len(t, keys, 4)
+	assert.Equal(t, metadataKeyUsage{
+		Key: "canyon.dev/owner", Count: 7, Sources: []string{"workload annotation", "resource definition"},
+		Examples: []interface{}{"web-squad", "payments-squad", "platform-squad"},
+		Entities: []string{
+			"workload frontend/development/web", "workload frontend/production/web",
+			"workload checkout/development/checkout", "workload checkout/staging/checkout", "workload checkout/production/checkout",
+			"resource definition dns-canyon-demo", "resource definition postgres-prod",
+		},
+	}, keys[0])
+	assert.Equal(t, []string{"workload label"}, keys[2].Sources)
+	assert.Equal(t, []interface{}{"frontend"}, keys[2].Examples)
+}
+
+func TestMetadataKeysCollector(t *testing.T) {
+	c := make(metadataKeysCollector)
+	for i := 0; i < maxMetadataEntities+2; i++ {
+		c.add("team", "workload label", fmt.Sprintf("workload a/env-%d/api", i), fmt.Sprintf("team-%d", i%5))
+	}
+	c.add("arn", "resource definition", "resource definition s3", "arn:aws:s3:::bucket")
+	usages := c.usages()
+	require.Len(t, usages, 2)
+	assert.Equal(t, maxMetadataEntities+2, usages[0].Count)
+	assert.Len(t, usages[0].Entities, maxMetadataEntities)
+	assert.Equal(t, []interface{}{"team-0", "team-1", "team-2"}, usages[0].Examples)
+	assert.Equal(t, "arn", usages[1].Key)
+}
+
+func TestGetActiveResourceGraph(t *testing.T) {
+	ctx := demoContext(t)
+	r := callTool(t, ctx, NewGetActiveResourceGraph(), map[string]interface{}{"org_id": "canyon-demo", "app_id": "backend", "env_id": "development"})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, `"id": "modules.api.externals.db (postgres)"`)
+	assert.Contains(t, r.Contents[0].Text, `"status": "pending"`)
+	assert.Contains(t, r.Contents[0].Text, `{
+      "source": "modules.api (workload)",
+      "target": "modules.api.externals.db (postgres)",
+      "explanation": "depends on"
+    }`)
+
+	r = callTool(t, ctx, NewGetActiveResourceGraph(), map[string]interface{}{"org_id": "canyon-demo", "app_id": "backend", "env_id": "staging"})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, "There are no active resources")
+}
+
+func TestExplainResourceDefinitionMatching(t *testing.T) {
+	ctx := demoContext(t)
+	r := callTool(t, ctx, NewExplainResourceDefinitionMatching(), map[string]interface{}{
+		"org_id": "canyon-demo", "app_id": "backend", "env_id": "development", "type": "postgres", "res_id": "modules.api.externals.db",
+	})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	require.Len(t, r.Contents, 2)
+	assert.Contains(t, r.Contents[0].Text, "Resource Definition 'postgres-dev' is selected for the resource 'modules.api.externals.db' of resource type 'postgres' of class 'default' in Environment 'development' (type 'development') of Application 'backend'. The active resource was provisioned from Resource Definition 'postgres-dev' in deployment '29a0d5e7c3f14b18'.")
+	assert.Equal(t, `The ranked Resource Definitions as CSV: rank,definition,driver,outcome,criteria,explanation
+1,postgres-dev,humanitec/postgres-cloudsql-static,selected,"env_type=development, class=default","its criteria env_type=development, class=default is the most specific match"
+2,postgres-default,humanitec/postgres-cloudsql-static,lost,class=default,"its criteria class=default is less specific than env_type=development, class=default of 'postgres-dev'"
+,postgres-ha,humanitec/terraform,not matched,,"no criteria matches: env_type=production, class=ha: env_type 'production' is not 'development', class 'ha' is not 'default'"
+,postgres-prod,humanitec/terraform,not matched,,"no criteria matches: env_type=production, class=default: env_type 'production' is not 'development'"
+`, r.Contents[1].Text)
+
+	r = callTool(t, ctx, NewExplainResourceDefinitionMatching(), map[string]interface{}{
+		"org_id": "canyon-demo", "app_id": "frontend", "env_id": "production", "type": "s3",
+	})
+	assert.True(t, r.IsError)
+}
+
+func TestRankDefinitions_ambiguous(t *testing.T) {
+	app := "backend"
+	defs := []client.ResourceDefinitionResponse{
+		{Id: "a", Type: "redis", Criteria: &[]client.MatchingCriteriaResponse{{AppId: &app}}},
+		{Id: "b", Type: "redis", Criteria: &[]client.MatchingCriteriaResponse{{AppId: &app, Class: "default"}}},
+		{Id: "c", Type: "redis"},
+	}
+	out := rankDefinitions(defs, matchingContext{AppId: "backend", EnvId: "development", EnvType: "development", Type: "redis", Class: "default"})
+	require.Len(t, out, 3)
+	assert.Equal(t, []string{"ambiguous", "ambiguous", "not matched"}, []string{out[0].Outcome, out[1].Outcome, out[2].Outcome})
+	assert.Equal(t, 1, out[1].Rank)
+}
+
+func TestValidateScoreFile(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "score.yaml"), []byte(`apiVersion: score.dev/v1b1
+metadata:
+  name: reconcile
+containers:
+  main:
+    image: ghcr.io/canyon-demo/checkout-reconcile:1.0.0
+    variables:
+      DB: ${resources.db.host}
+resources:
+  db:
+    type: postgres
+`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, score.ExtensionsFileName), []byte(`apiVersion: humanitec.org/v1b1
+profile: humanitec/default-cronjob
+spec: {}
+`), 0600))
+	ctx := WithWorkspaceRoots(demoContext(t), []string{root})
+
+	r := callTool(t, ctx, NewValidateScoreFile(), map[string]interface{}{"path": "score.yaml", "org_id": "canyon-demo"})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, "has 1 errors that must be fixed before deploying it with workload profile 'humanitec/default-cronjob'")
+	assert.Contains(t, r.Contents[0].Text, "humanitec.score.yaml:3:7: error: spec: workload profile 'humanitec/default-cronjob': missing required property 'schedule'")
+
+	r = callTool(t, ctx, NewValidateScoreFile(), map[string]interface{}{"path": "score.yaml", "profile": "humanitec/default-module", "org_id": "canyon-demo"})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, "is valid for workload profile 'humanitec/default-module'.")
+
+	r = callTool(t, ctx, NewValidateScoreFile(), map[string]interface{}{"path": "../score.yaml"})
+	assert.True(t, r.IsError)
+	assert.Contains(t, r.Contents[0].Text, "outside of the workspace roots")
+}
+
+func TestPreviewScoreDeployment(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "score.yaml"), []byte(`apiVersion: score.dev/v1b1
+metadata:
+  name: api
+containers:
+  api:
+    image: ghcr.io/canyon-demo/api:2.1.0
+    variables:
+      DATABASE_URL: postgres://${resources.db.username}@${resources.db.host}/${resources.db.name}
+      QUEUE: ${resources.queue.url}
+resources:
+  db:
+    type: postgres
+  queue:
+    type: rabbitmq
+`), 0600))
+	ctx := WithWorkspaceRoots(demoContext(t), []string{root})
+
+	r := callTool(t, ctx, NewPreviewScoreDeployment(), map[string]interface{}{"path": "score.yaml", "org_id": "canyon-demo", "app_id": "backend", "env_id": "development"})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	require.Len(t, r.Contents, 3)
+	assert.Contains(t, r.Contents[0].Text, "Deploying workload 'api' to Environment 'development' of Application 'backend' would make 3 changes to the current Deployment Set 'backend-set-1' of Environment 'development'. Nothing was deployed. WARNING: 1 resource dependencies have no matching Resource Definition and would fail the deployment: 'modules.api.externals.queue' (type 'rabbitmq', class 'default').")
+	assert.Contains(t, r.Contents[1].Text, `"defId": "postgres-dev"`)
+	assert.Equal(t, `The differences as CSV: kind,change,path,from,to
+resource,added,modules.api.externals.queue,,"{""type"":""rabbitmq""}"
+image,changed,modules.api.spec.containers.api.image,ghcr.io/canyon-demo/api:2.0.1,ghcr.io/canyon-demo/api:2.1.0
+variable,added,modules.api.spec.containers.api.variables.QUEUE,,${externals.queue.url}
+`, r.Contents[2].Text)
+}
+
+func TestConfirmationToken(t *testing.T) {
+	now := time.Now()
+	token := confirmationToken(now, "deploy", "a", "b")
+	assert.NoError(t, checkConfirmationToken(token, now, "deploy", "a", "b"))
+	assert.ErrorContains(t, checkConfirmationToken(token, now, "deploy", "a", "c"), "does not match")
+	assert.ErrorContains(t, checkConfirmationToken(token, now.Add(confirmationTokenTTL+time.Minute), "deploy", "a", "b"), "expired")
+	assert.ErrorContains(t, checkConfirmationToken("made-up", now, "deploy", "a", "b"), "invalid")
+}
+
+func TestDeployToEnvironment(t *testing.T) {
+	ctx := demoContext(t)
+	deploymentPollInterval = time.Millisecond
+	t.Cleanup(func() { deploymentPollInterval = 2 * time.Second })
+	tool := NewDeployToEnvironment()
+	assert.True(t, tool.Annotations.DestructiveHint)
+	args := map[string]interface{}{"org_id": "canyon-demo", "app_id": "backend", "env_id": "development", "delta_id": "upgrade-api"}
+
+	r := callTool(t, ctx, tool, args)
+	require.False(t, r.IsError, r.Contents[0].Text)
+	require.Len(t, r.Contents, 3)
+	assert.Contains(t, r.Contents[0].Text, "DRY-RUN, nothing was deployed. Deploying Deployment Delta 'upgrade-api' to Environment 'development' of Application 'backend' would make 1 changes to its current Deployment Set.")
+	assert.Equal(t, `The changes as CSV: kind,change,path,from,to
+image,changed,modules.api.spec.containers.api.image,ghcr.io/canyon-demo/api:2.0.1,ghcr.io/canyon-demo/api:2.1.0
+`, r.Contents[2].Text)
+	token := regexp.MustCompile(`confirmation_token '([^']+)'`).FindStringSubmatch(r.Contents[0].Text)[1]
+
+	args["confirmation_token"] = "1.wrong"
+	r = callTool(t, ctx, tool, args)
+	assert.True(t, r.IsError)
+
+	args["confirmation_token"] = token
+	args["comment"] = "Upgrade the api"
+	r = callTool(t, ctx, tool, args)
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Regexp(t, `^Deployment '[0-9a-f]+' of Deployment Delta 'upgrade-api' to Environment 'development' of Application 'backend' succeeded\.`, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, `"comment": "Upgrade the api"`)
+
+	// the environment changed, so the token of the earlier dry-run no longer matches
+	r = callTool(t, ctx, tool, args)
+	assert.True(t, r.IsError)
+	assert.Contains(t, r.Contents[0].Text, "changed since the dry-run")
+
+	// the only earlier deployment failed, so it must be chosen explicitly
+	r = callTool(t, ctx, NewRollbackEnvironment(), map[string]interface{}{"org_id": "canyon-demo", "app_id": "backend", "env_id": "development"})
+	assert.True(t, r.IsError)
+	assert.Contains(t, r.Contents[0].Text, "no previous successful deployment")
+	r = callTool(t, ctx, NewRollbackEnvironment(), map[string]interface{}{"org_id": "canyon-demo", "app_id": "backend", "env_id": "development", "deploy_id": "29a0d5e7c3f14b18"})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, "DRY-RUN, nothing was deployed. Deploying Deployment Set 'backend-set-1' of deployment '29a0d5e7c3f14b18'")
+	assert.Contains(t, r.Contents[2].Text, "image,changed,modules.api.spec.containers.api.image,ghcr.io/canyon-demo/api:2.1.0,ghcr.io/canyon-demo/api:2.0.1")
+}
+
+func TestRollbackEnvironment(t *testing.T) {
+	ctx := demoContext(t)
+	deploymentPollInterval = time.Millisecond
+	t.Cleanup(func() { deploymentPollInterval = 2 * time.Second })
+	args := map[string]interface{}{"org_id": "canyon-demo", "app_id": "frontend", "env_id": "development"}
+
+	r := callTool(t, ctx, NewRollbackEnvironment(), args)
+	require.False(t, r.IsError, r.Contents[0].Text)
+	args["confirmation_token"] = regexp.MustCompile(`confirmation_token '([^']+)'`).FindStringSubmatch(r.Contents[0].Text)[1]
+
+	// follow the deployment with a progress token and collect the progress notifications
+	notifications := make(chan rpc.JsonRpcNotification, 100)
+	ctx = context.WithValue(ctx, rpc.NotificationChannelKey, (chan<- rpc.JsonRpcNotification)(notifications))
+	impl := &mcp.Impl{Tools: []mcp.Tool{NewRollbackEnvironment()}}
+	raw, _ := json.Marshal(args)
+	var request mcp.CallToolRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"name":"rollback_humanitec_environment","_meta":{"progressToken":"rollback"},"arguments":`+string(raw)+`}`), &request))
+	resp, err := impl.CallTool(ctx, request)
+	require.NoError(t, err)
+	require.False(t, resp.IsError, resp.Contents[0].Text)
+	assert.Contains(t, resp.Contents[0].Text, "succeeded")
+	assert.Contains(t, resp.Contents[0].Text, `"comment": "Rollback to deployment 17e3c2a9f1b04d33"`)
+	require.GreaterOrEqual(t, len(notifications), 2)
+	first := (<-notifications).ToJsonRpcNotificationInner()
+	assert.Equal(t, "notifications/progress", first.Method)
+	assert.Contains(t, string(first.Params), `"progressToken":"rollback"`)
+	for len(notifications) > 1 {
+		<-notifications
+	}
+	last := (<-notifications).ToJsonRpcNotificationInner()
+	assert.Contains(t, string(last.Params), "has status 'succeeded'.")
+}
+
+func TestPipelineTools(t *testing.T) {
+	ctx := demoContext(t)
+	pipeline := map[string]interface{}{"org_id": "canyon-demo", "app_id": "frontend", "pipeline_id": "promote"}
+	with := func(extra map[string]interface{}) map[string]interface{} {
+		out := maps.Clone(pipeline)
+		maps.Copy(out, extra)
+		return out
+	}
+
+	r := callTool(t, ctx, NewListPipelines(), map[string]interface{}{"org_id": "canyon-demo"})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	require.Len(t, r.Contents, 1)
+	assert.Contains(t, r.Contents[0].Text, "The 1 Pipelines of the 3 Applications of Organization 'canyon-demo'")
+
+	r = callTool(t, ctx, NewGetPipelineDefinition(), pipeline)
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, "can be triggered by pipeline_call. Its definition in yaml format:\nname: Promote to production\n")
+
+	r = callTool(t, ctx, NewListPipelineRuns(), with(map[string]interface{}{"status": "failed"}))
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, `"id": "5b2d8e61a9c04f17"`)
+	assert.NotContains(t, r.Contents[0].Text, `"id": "3e8a0c94d7b15f26"`)
+
+	r = callTool(t, ctx, NewGetPipelineRun(), with(map[string]interface{}{"run_id": "5b2d8e61a9c04f17"}))
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, "Pipeline run '5b2d8e61a9c04f17' failed after 0 steps: Job 'promote' failed. The get_humanitec_pipeline_step_logs tool returns the logs of the failed step 'Approve the promotion' with job_id 'promote' and step_index 0.")
+
+	r = callTool(t, ctx, NewGetPipelineStepLogs(), with(map[string]interface{}{"run_id": "5b2d8e61a9c04f17", "job_id": "promote", "step_index": 0}))
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, "2025-03-10T15:20:00Z ERROR The approval request was denied by 0b7a1f0e-demo-user")
+}
+
+func TestPipelineTools_snapshot(t *testing.T) {
+	ctx := demoContext(t)
+	hc, err := humanitec.NewHumanitecClientWithCurrentToken(ctx)
+	require.NoError(t, err)
+	snapshot, err := humanitec.ExportSnapshot(ctx, hc, "canyon-demo", humanitec.SnapshotOptions{})
+	require.NoError(t, err)
+	require.Empty(t, snapshot.Manifest.Warnings)
+	ctx = humanitec.WithHttpRequestDoer(context.Background(), snapshot)
+	pipeline := map[string]interface{}{"org_id": "canyon-demo", "app_id": "frontend", "pipeline_id": "promote"}
+	with := func(extra map[string]interface{}) map[string]interface{} {
+		out := maps.Clone(pipeline)
+		maps.Copy(out, extra)
+		return out
+	}
+
+	r := callTool(t, ctx, NewListPipelines(), map[string]interface{}{"org_id": "canyon-demo", "app_id": "frontend"})
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, `"id": "promote"`)
+
+	r = callTool(t, ctx, NewGetPipelineDefinition(), pipeline)
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, "Its definition in yaml format:\nname: Promote to production\n")
+
+	// the snapshot answers with every run, so the status filter is applied by the tool
+	r = callTool(t, ctx, NewListPipelineRuns(), with(map[string]interface{}{"status": "failed"}))
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, `"id": "5b2d8e61a9c04f17"`)
+	assert.NotContains(t, r.Contents[0].Text, `"id": "3e8a0c94d7b15f26"`)
+
+	r = callTool(t, ctx, NewGetPipelineRun(), with(map[string]interface{}{"run_id": "5b2d8e61a9c04f17"}))
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, "Pipeline run '5b2d8e61a9c04f17' failed after 0 steps")
+
+	r = callTool(t, ctx, NewGetPipelineStepLogs(), with(map[string]interface{}{"run_id": "5b2d8e61a9c04f17", "job_id": "promote", "step_index": 0}))
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, "2025-03-10T15:20:00Z ERROR The approval request was denied by 0b7a1f0e-demo-user")
+}
+
+func TestTriggerPipelineRun(t *testing.T) {
+	fixture := fakeapi.DefaultFixture()
+	fixture.PipelineStepDuration = 5 * time.Millisecond
+	isolate(t)
+	ctx := humanitec.WithHttpRequestDoer(context.Background(), fakeapi.New(fixture))
+	pipelinePollInterval = time.Millisecond
+	t.Cleanup(func() { pipelinePollInterval = 2 * time.Second })
+	tool := NewTriggerPipelineRun()
+	assert.True(t, tool.Annotations.DestructiveHint)
+	args := map[string]interface{}{"org_id": "canyon-demo", "app_id": "frontend", "pipeline_id": "promote", "inputs": map[string]interface{}{"comment": "Release 1.4.0"}}
+
+	r := callTool(t, ctx, tool, map[string]interface{}{"org_id": "canyon-demo", "app_id": "frontend", "pipeline_id": "promote", "inputs": map[string]interface{}{"unknown": "x"}})
+	assert.True(t, r.IsError)
+	assert.Contains(t, r.Contents[0].Text, "unknown input 'unknown'")
+
+	r = callTool(t, ctx, tool, args)
+	require.False(t, r.IsError, r.Contents[0].Text)
+	require.Len(t, r.Contents, 2)
+	assert.Contains(t, r.Contents[0].Text, `DRY-RUN, nothing was triggered. Pipeline 'promote' (Promote to production) of Application 'frontend' accepts the inputs {"comment":"Release 1.4.0"}`)
+	token := regexp.MustCompile(`confirmation_token '([^']+)'`).FindStringSubmatch(r.Contents[0].Text)[1]
+
+	// the token only confirms the inputs of the dry-run
+	r = callTool(t, ctx, tool, map[string]interface{}{"org_id": "canyon-demo", "app_id": "frontend", "pipeline_id": "promote", "confirmation_token": token})
+	assert.True(t, r.IsError)
+
+	args["confirmation_token"] = token
+	r = callTool(t, ctx, tool, args)
+	require.False(t, r.IsError, r.Contents[0].Text)
+	match := regexp.MustCompile(`Pipeline run '([0-9a-f]+)' of Pipeline 'promote' was started. Pipeline run '[0-9a-f]+' is waiting for approval request '([0-9a-f]+)' of job 'promote' after 0 steps`).FindStringSubmatch(r.Contents[0].Text)
+	require.NotNil(t, match, r.Contents[0].Text)
+	runId, approvalId := match[1], match[2]
+
+	r = callTool(t, ctx, NewListPipelines(), map[string]interface{}{"org_id": "canyon-demo", "app_id": "frontend"})
+	require.Len(t, r.Contents, 2)
+	assert.Contains(t, r.Contents[1].Text, approvalId)
+
+	respond := map[string]interface{}{"org_id": "canyon-demo", "app_id": "frontend", "pipeline_id": "promote", "run_id": runId, "job_id": "promote", "approval_id": approvalId, "decision": "approve"}
+	r = callTool(t, ctx, NewRespondToPipelineApproval(), respond)
+	require.False(t, r.IsError, r.Contents[0].Text)
+	assert.Contains(t, r.Contents[0].Text, "DRY-RUN, nothing was decided. Approval request '"+approvalId+"' for Environment 'production' asks: Promote the current development deployment of the frontend to production? Choosing to approve it would let the run continue")
+	respond["confirmation_token"] = regexp.MustCompile(`confirmation_token '([^']+)'`).FindStringSubmatch(r.Contents[0].Text)[1]
+
+	// follow the run with a progress token and collect the progress notifications
+	notifications := make(chan rpc.JsonRpcNotification, 100)
+	ctx = context.WithValue(ctx, rpc.NotificationChannelKey, (chan<- rpc.JsonRpcNotification)(notifications))
+	impl := &mcp.Impl{Tools: []mcp.Tool{NewRespondToPipelineApproval()}}
+	raw, _ := json.Marshal(respond)
+	var request mcp.CallToolRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"name":"respond_to_humanitec_pipeline_approval","_meta":{"progressToken":1},"arguments":`+string(raw)+`}`), &request))
+	resp, err := impl.CallTool(ctx, request)
+	require.NoError(t, err)
+	require.False(t, resp.IsError, resp.Contents[0].Text)
+	assert.Contains(t, resp.Contents[0].Text, "Approval request '"+approvalId+"' was approved. Pipeline run '"+runId+"' succeeded after 3 steps.")
+	require.NotEmpty(t, notifications)
+	first := (<-notifications).ToJsonRpcNotificationInner()
+	assert.Equal(t, "notifications/progress", first.Method)
+	assert.Contains(t, string(first.Params), `"progressToken":1`)
+
+	r = callTool(t, ctx, NewRespondToPipelineApproval(), respond)
+	assert.True(t, r.IsError)
+	assert.Contains(t, r.Contents[0].Text, "was already approved")
+}
@@ -0,0 +1,76 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Caller sends requests from the server to the client, such as asking an MCP client for its roots.
+type Caller interface {
+	Call(ctx context.Context, method string, params interface{}) (json.RawMessage, error)
+}
+
+type ctxKeyCaller struct {
+}
+
+var CallerKey = &ctxKeyCaller{}
+
+// GetCaller returns the caller of the server handling the request, if any.
+func GetCaller(ctx context.Context) Caller {
+	v, _ := ctx.Value(CallerKey).(Caller)
+	return v
+}
+
+// Call sends a request to the client and waits for its response. The request is written to Out like a response that
+// carries a method, and the client response must be passed back with Respond.
+func (e *Generic) Call(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	e.setup()
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal params: %w", err)
+	}
+	responses := make(chan JsonRpcResponseInner, 1)
+	e.callsLock.Lock()
+	e.lastCallId++
+	id := e.lastCallId
+	e.calls[id] = responses
+	e.callsLock.Unlock()
+	defer func() {
+		e.callsLock.Lock()
+		delete(e.calls, id)
+		e.callsLock.Unlock()
+	}()
+
+	select {
+	case e.out <- JsonRpcResponse{
+		JsonRpcResponseInner:     &JsonRpcResponseInner{Id: id},
+		JsonRpcNotificationInner: &JsonRpcNotificationInner{Method: method, Params: raw},
+	}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	select {
+	case r := <-responses:
+		if r.Error != nil {
+			return nil, *r.Error
+		}
+		return r.Result, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("no response from the client to %s: %w", method, ctx.Err())
+	}
+}
+
+// Respond passes a response from the client to the pending Call with the same id. It returns false when no call is
+// waiting for it.
+func (e *Generic) Respond(r JsonRpcResponseInner) bool {
+	e.setup()
+	e.callsLock.Lock()
+	defer e.callsLock.Unlock()
+	responses, ok := e.calls[r.Id]
+	if ok {
+		delete(e.calls, r.Id)
+		responses <- r
+	}
+	return ok
+}
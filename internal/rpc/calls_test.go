@@ -0,0 +1,46 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenericCall(t *testing.T) {
+	server := &Generic{Handler: HandlerFunc(func(req JsonRpcRequest) (*JsonRpcResponse, error) {
+		return nil, nil
+	})}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	type result struct {
+		raw json.RawMessage
+		err error
+	}
+	results := make(chan result)
+	go func() {
+		raw, err := server.Call(ctx, "roots/list", struct{}{})
+		results <- result{raw, err}
+	}()
+	req := <-server.Out()
+	raw, _ := json.Marshal(req)
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"method":"roots/list","params":{}}`, string(raw))
+	assert.False(t, server.Respond(JsonRpcResponseInner{Id: 2}))
+	assert.True(t, server.Respond(JsonRpcResponseInner{Id: 1, Result: json.RawMessage(`{"roots":[]}`)}))
+	r := <-results
+	require.NoError(t, r.err)
+	assert.Equal(t, `{"roots":[]}`, string(r.raw))
+
+	go func() {
+		raw, err := server.Call(ctx, "roots/list", nil)
+		results <- result{raw, err}
+	}()
+	<-server.Out()
+	assert.True(t, server.Respond(JsonRpcResponseInner{Id: 2, Error: &JsonRpcError{Code: JsonRpcMethodNotFoundError, Message: "no roots"}}))
+	r = <-results
+	assert.EqualError(t, r.err, "json rpc error: -32601: no roots")
+}
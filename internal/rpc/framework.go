@@ -40,8 +40,16 @@ type Generic struct {
 	in   chan JsonRpcRequest
 	out  chan JsonRpcResponse
 	once sync.Once
+
+	calls      map[int]chan JsonRpcResponseInner
+	lastCallId int
+	callsLock  sync.Mutex
 }
 
+// inBufferSize is the number of requests that can be queued while a request is handled, so that the responses to the
+// calls of a handler can still be read.
+const inBufferSize = 64
+
 func (e *Generic) In() chan<- JsonRpcRequest {
 	e.setup()
 	return e.in
@@ -54,8 +62,9 @@ func (e *Generic) Out() <-chan JsonRpcResponse {
 
 func (e *Generic) setup() {
 	e.once.Do(func() {
-		e.in = make(chan JsonRpcRequest)
+		e.in = make(chan JsonRpcRequest, inBufferSize)
 		e.out = make(chan JsonRpcResponse)
+		e.calls = make(map[int]chan JsonRpcResponseInner)
 
 		notifications := make(chan JsonRpcNotification)
 		notificationCtx, notificationsCancel := context.WithCancel(context.Background())
@@ -79,6 +88,10 @@ func (e *Generic) setup() {
 			defer notificationsCancel()
 			for req := range e.in {
 				req = req.WithContext(context.WithValue(req.Context(), NotificationChannelKey, sendOnlyNotifications))
+				req = req.WithContext(context.WithValue(req.Context(), CallerKey, Caller(e)))
+				if req.Id != nil {
+					req = req.WithContext(context.WithValue(req.Context(), RequestIdKey, *req.Id))
+				}
 				r, err := e.Handler.Handle(req)
 				if err != nil {
 					var rpcErr JsonRpcError
@@ -90,6 +103,9 @@ func (e *Generic) setup() {
 								"message": err.Error(),
 							},
 						}
+						for k, v := range GetErrorData(err) {
+							rpcErr.Data[k] = v
+						}
 					}
 					r = ref.Ref(JsonRpcResponse{
 						JsonRpcResponseInner: &JsonRpcResponseInner{
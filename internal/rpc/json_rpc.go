@@ -106,9 +106,24 @@ func (err JsonRpcError) Error() string {
 	return fmt.Sprintf("json rpc error: %d: %s", err.Code, err.Message)
 }
 
+// ErrorWithData is implemented by errors that carry machine-readable details for the client such as the error kind,
+// whether it is retryable, and a remediation hint.
+type ErrorWithData interface {
+	error
+	ErrorData() map[string]interface{}
+}
+
+// GetErrorData returns the structured data of the first error in the chain that carries any.
+func GetErrorData(err error) map[string]interface{} {
+	if e := ErrorWithData(nil); errors.As(err, &e) {
+		return e.ErrorData()
+	}
+	return nil
+}
+
 func NewJsonRpcErrorFromErr(err error) JsonRpcError {
 	if e := (*JsonRpcError)(nil); errors.As(err, &e) {
 		return *e
 	}
-	return JsonRpcError{Code: JsonRpcInternalError, Message: err.Error()}
+	return JsonRpcError{Code: JsonRpcInternalError, Message: err.Error(), Data: GetErrorData(err)}
 }
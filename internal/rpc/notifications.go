@@ -11,3 +11,14 @@ func GetNotificationChannel(ctx context.Context) chan<- JsonRpcNotification {
 	v, _ := ctx.Value(NotificationChannelKey).(chan<- JsonRpcNotification)
 	return v
 }
+
+type ctxKeyRequestId struct {
+}
+
+var RequestIdKey = &ctxKeyRequestId{}
+
+// GetRequestId returns the id of the JSON-RPC request being handled, if any.
+func GetRequestId(ctx context.Context) (int, bool) {
+	v, ok := ctx.Value(RequestIdKey).(int)
+	return v, ok
+}
@@ -0,0 +1,44 @@
+package score
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/humanitec/humanitec-go-autogen/client"
+
+	"github.com/humanitec/canyon-cli/internal/clients/humanitec"
+)
+
+// WithOrganization fills in the workload profile schema and resource types of a Humanitec Organization.
+func WithOrganization(ctx context.Context, hc *humanitec.WrappedHumanitecClientImpl, orgId string, opts Options) (Options, error) {
+	defs, err := humanitec.CheckResponse(func() (*client.ListResourceDefinitionsResponse, error) {
+		return hc.ListResourceDefinitionsWithResponse(ctx, orgId, &client.ListResourceDefinitionsParams{})
+	}).AndStatusCodeEq(http.StatusOK).RespAndError()
+	if err != nil {
+		return opts, err
+	}
+	opts.ResourceTypes = make([]string, 0)
+	if defs.JSON200 != nil {
+		for _, d := range *defs.JSON200 {
+			if !d.IsDeleted && !slices.Contains(opts.ResourceTypes, d.Type) {
+				opts.ResourceTypes = append(opts.ResourceTypes, d.Type)
+			}
+		}
+	}
+	opts.ProfileSchema = func(profileId string) (map[string]interface{}, error) {
+		r, err := humanitec.CheckResponse(func() (*client.GetWorkloadProfileResponse, error) {
+			return hc.GetWorkloadProfileWithResponse(ctx, orgId, profileId)
+		}).AndStatusCodeEq(http.StatusOK).RespAndError()
+		if err != nil {
+			return nil, err
+		}
+		schema, ok := r.JSON200.SpecSchema.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("the workload profile has no spec schema")
+		}
+		return schema, nil
+	}
+	return opts, nil
+}
@@ -0,0 +1,275 @@
+package score
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// escapablePlaceholderPattern also matches the $${ escape of a literal ${ so that it is kept as it is.
+var escapablePlaceholderPattern = regexp.MustCompile(`\$?\$\{([^}]*)\}`)
+
+// Module is the Deployment Set delta that deploying a Score file applies.
+type Module struct {
+	// Name is the workload name and module id.
+	Name string `json:"name"`
+	// Module is the Deployment Set module of the workload.
+	Module map[string]interface{} `json:"module"`
+	// Shared are the shared resources the workload adds to the Deployment Set.
+	Shared map[string]interface{} `json:"shared,omitempty"`
+	// Notes describe the parts of the Score file that were not converted.
+	Notes []string `json:"notes,omitempty"`
+}
+
+// ModuleResource is a resource dependency of the workload, with its resource id in the Deployment Set.
+type ModuleResource struct {
+	ResId string
+	Type  string
+	Class string
+}
+
+type scoreFile struct {
+	Metadata   map[string]interface{}    `yaml:"metadata"`
+	Containers map[string]scoreContainer `yaml:"containers"`
+	Service    struct {
+		Ports map[string]struct {
+			Port       int    `yaml:"port"`
+			TargetPort int    `yaml:"targetPort"`
+			Protocol   string `yaml:"protocol"`
+		} `yaml:"ports"`
+	} `yaml:"service"`
+	Resources map[string]struct {
+		Type   string                 `yaml:"type"`
+		Class  string                 `yaml:"class"`
+		Id     string                 `yaml:"id"`
+		Params map[string]interface{} `yaml:"params"`
+	} `yaml:"resources"`
+}
+
+type scoreContainer struct {
+	Image     string                 `yaml:"image"`
+	Command   []string               `yaml:"command"`
+	Args      []string               `yaml:"args"`
+	Variables map[string]string      `yaml:"variables"`
+	Files     interface{}            `yaml:"files"`
+	Volumes   interface{}            `yaml:"volumes"`
+	Resources map[string]interface{} `yaml:"resources"`
+}
+
+type extensionsFile struct {
+	Profile   string                 `yaml:"profile"`
+	Spec      map[string]interface{} `yaml:"spec"`
+	Resources map[string]struct {
+		Scope string `yaml:"scope"`
+	} `yaml:"resources"`
+}
+
+// ToModule converts a Score file and its optional Humanitec extensions into the Deployment Set module the Platform
+// Orchestrator would deploy. Resources become externals of the module unless they are shared, environment resources
+// become values, and placeholders are rewritten to match. The file should be validated first.
+func ToModule(score Input, extensions *Input) (*Module, error) {
+	var sf scoreFile
+	if err := yaml.Unmarshal(score.Content, &sf); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", score.File, err)
+	}
+	var ef extensionsFile
+	if extensions != nil {
+		if err := yaml.Unmarshal(extensions.Content, &ef); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", extensions.File, err)
+		}
+	}
+	name, _ := sf.Metadata["name"].(string)
+	if name == "" {
+		return nil, fmt.Errorf("%s has no metadata.name", score.File)
+	}
+	out := &Module{Name: name, Notes: make([]string, 0)}
+
+	// replacements maps the resource name used in placeholders to its Deployment Set reference.
+	replacements := make(map[string]string)
+	externals := make(map[string]interface{})
+	shared := make(map[string]interface{})
+	for _, resName := range slices.Sorted(maps.Keys(sf.Resources)) {
+		r := sf.Resources[resName]
+		entry := map[string]interface{}{"type": r.Type}
+		if r.Class != "" {
+			entry["class"] = r.Class
+		}
+		if len(r.Params) > 0 {
+			entry["params"] = r.Params
+		}
+		switch {
+		case r.Type == "environment":
+			replacements[resName] = "values"
+		case ef.Resources[resName].Scope == "shared" || r.Id != "":
+			id := resName
+			if r.Id != "" {
+				id = r.Id
+			}
+			shared[id] = entry
+			replacements[resName] = "shared." + id
+		default:
+			externals[resName] = entry
+			replacements[resName] = "externals." + resName
+		}
+	}
+
+	rewrite := func(s string) string {
+		return escapablePlaceholderPattern.ReplaceAllStringFunc(s, func(m string) string {
+			if strings.HasPrefix(m, "$${") {
+				return m
+			}
+			parts := strings.SplitN(m[2:len(m)-1], ".", 3)
+			if parts[0] == "metadata" && len(parts) == 2 {
+				if v, ok := sf.Metadata[parts[1]].(string); ok {
+					return v
+				}
+			} else if parts[0] == "resources" && len(parts) >= 2 {
+				if ref, ok := replacements[parts[1]]; ok {
+					if len(parts) == 3 {
+						return "${" + ref + "." + parts[2] + "}"
+					}
+					return "${" + ref + "}"
+				}
+			}
+			return m
+		})
+	}
+
+	containers := make(map[string]interface{})
+	for _, cName := range slices.Sorted(maps.Keys(sf.Containers)) {
+		c := sf.Containers[cName]
+		container := map[string]interface{}{"image": c.Image}
+		if len(c.Command) > 0 {
+			container["command"] = rewriteAll(c.Command, rewrite)
+		}
+		if len(c.Args) > 0 {
+			container["args"] = rewriteAll(c.Args, rewrite)
+		}
+		if len(c.Variables) > 0 {
+			variables := make(map[string]interface{}, len(c.Variables))
+			for k, v := range c.Variables {
+				variables[k] = rewrite(v)
+			}
+			container["variables"] = variables
+		}
+		if len(c.Resources) > 0 {
+			container["resources"] = c.Resources
+		}
+		if c.Files != nil {
+			out.Notes = append(out.Notes, fmt.Sprintf("the files of container '%s' are not previewed", cName))
+		}
+		if c.Volumes != nil {
+			out.Notes = append(out.Notes, fmt.Sprintf("the volumes of container '%s' are not previewed", cName))
+		}
+		containers[cName] = container
+	}
+	spec := map[string]interface{}{"containers": containers}
+	if len(sf.Service.Ports) > 0 {
+		ports := make(map[string]interface{}, len(sf.Service.Ports))
+		for pName, p := range sf.Service.Ports {
+			targetPort := p.TargetPort
+			if targetPort == 0 {
+				targetPort = p.Port
+			}
+			protocol := p.Protocol
+			if protocol == "" {
+				protocol = "TCP"
+			}
+			ports[pName] = map[string]interface{}{"service_port": p.Port, "container_port": targetPort, "protocol": protocol}
+		}
+		spec["service"] = map[string]interface{}{"ports": ports}
+	}
+	// the annotations of the metadata are deployed as the annotations of the workload
+	if annotations, _ := sf.Metadata["annotations"].(map[string]interface{}); len(annotations) > 0 {
+		spec["annotations"] = maps.Clone(annotations)
+	}
+	mergeValues(spec, ef.Spec)
+
+	profile := DefaultProfile
+	if ef.Profile != "" {
+		profile = ef.Profile
+	}
+	out.Module = map[string]interface{}{"profile": profile, "spec": spec}
+	if len(externals) > 0 {
+		out.Module["externals"] = externals
+	}
+	if len(shared) > 0 {
+		out.Shared = shared
+	}
+	return out, nil
+}
+
+func rewriteAll(in []string, fn func(string) string) []interface{} {
+	out := make([]interface{}, 0, len(in))
+	for _, s := range in {
+		out = append(out, fn(s))
+	}
+	return out
+}
+
+// mergeValues merges src into dst, recursing into maps present in both.
+func mergeValues(dst, src map[string]interface{}) {
+	for k, v := range src {
+		existing, ok1 := dst[k].(map[string]interface{})
+		incoming, ok2 := v.(map[string]interface{})
+		if ok1 && ok2 {
+			mergeValues(existing, incoming)
+		} else {
+			dst[k] = v
+		}
+	}
+}
+
+// Resources returns the resource dependencies of the module and its shared resources, in resource id order.
+func (m *Module) Resources() []ModuleResource {
+	out := make([]ModuleResource, 0)
+	add := func(prefix string, entries map[string]interface{}) {
+		for _, k := range slices.Sorted(maps.Keys(entries)) {
+			entry, _ := entries[k].(map[string]interface{})
+			r := ModuleResource{ResId: prefix + k, Class: "default"}
+			r.Type, _ = entry["type"].(string)
+			if c, _ := entry["class"].(string); c != "" {
+				r.Class = c
+			}
+			out = append(out, r)
+		}
+	}
+	externals, _ := m.Module["externals"].(map[string]interface{})
+	add("modules."+m.Name+".externals.", externals)
+	add("shared.", m.Shared)
+	return out
+}
+
+// Apply returns a copy of the Deployment Set content with the module and its shared resources applied. Shared resources
+// already in the set are kept as they are.
+func (m *Module) Apply(set map[string]interface{}) map[string]interface{} {
+	out := maps.Clone(set)
+	if out == nil {
+		out = make(map[string]interface{})
+	}
+	modules, _ := out["modules"].(map[string]interface{})
+	modules = maps.Clone(modules)
+	if modules == nil {
+		modules = make(map[string]interface{})
+	}
+	modules[m.Name] = m.Module
+	out["modules"] = modules
+	if len(m.Shared) > 0 {
+		shared, _ := out["shared"].(map[string]interface{})
+		shared = maps.Clone(shared)
+		if shared == nil {
+			shared = make(map[string]interface{})
+		}
+		for k, v := range m.Shared {
+			if _, ok := shared[k]; !ok {
+				shared[k] = v
+			}
+		}
+		out["shared"] = shared
+	}
+	return out
+}
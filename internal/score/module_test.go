@@ -0,0 +1,85 @@
+package score
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToModule(t *testing.T) {
+	m, err := ToModule(Input{File: "score.yaml", Content: []byte(`apiVersion: score.dev/v1b1
+metadata:
+  name: web
+  annotations:
+    canyon.dev/owner: web-squad
+    canyon.dev/tier: frontend
+containers:
+  main:
+    image: nginx
+    args: ["--name", "${metadata.name}"]
+    variables:
+      HOST: ${resources.dns.host}
+      DB: ${resources.db}
+      MODE: ${resources.env.MODE}
+      LITERAL: $${resources.db.host}
+    files:
+      - target: /etc/x
+        content: x
+service:
+  ports:
+    http:
+      port: 80
+      targetPort: 8080
+resources:
+  db:
+    type: postgres
+    class: ha
+  dns:
+    type: dns
+  env:
+    type: environment
+`)}, &Input{File: "humanitec.score.yaml", Content: []byte(`apiVersion: humanitec.org/v1b1
+profile: humanitec/default-module
+spec:
+  replicas: 2
+  annotations:
+    canyon.dev/tier: edge
+resources:
+  dns:
+    scope: shared
+`)})
+	require.NoError(t, err)
+	assert.Equal(t, "web", m.Name)
+	assert.Equal(t, map[string]interface{}{
+		"profile": "humanitec/default-module",
+		"spec": map[string]interface{}{
+			"replicas":    2,
+			"annotations": map[string]interface{}{"canyon.dev/owner": "web-squad", "canyon.dev/tier": "edge"},
+			"containers": map[string]interface{}{"main": map[string]interface{}{
+				"image": "nginx",
+				"args":  []interface{}{"--name", "web"},
+				"variables": map[string]interface{}{
+					"HOST":    "${shared.dns.host}",
+					"DB":      "${externals.db}",
+					"MODE":    "${values.MODE}",
+					"LITERAL": "$${resources.db.host}",
+				},
+			}},
+			"service": map[string]interface{}{"ports": map[string]interface{}{
+				"http": map[string]interface{}{"service_port": 80, "container_port": 8080, "protocol": "TCP"},
+			}},
+		},
+		"externals": map[string]interface{}{"db": map[string]interface{}{"type": "postgres", "class": "ha"}},
+	}, m.Module)
+	assert.Equal(t, map[string]interface{}{"dns": map[string]interface{}{"type": "dns"}}, m.Shared)
+	assert.Equal(t, []string{"the files of container 'main' are not previewed"}, m.Notes)
+	assert.Equal(t, []ModuleResource{
+		{ResId: "modules.web.externals.db", Type: "postgres", Class: "ha"},
+		{ResId: "shared.dns", Type: "dns", Class: "default"},
+	}, m.Resources())
+
+	set := m.Apply(map[string]interface{}{"shared": map[string]interface{}{"dns": map[string]interface{}{"type": "dns", "class": "custom"}}})
+	assert.Equal(t, map[string]interface{}{"type": "dns", "class": "custom"}, set["shared"].(map[string]interface{})["dns"])
+	assert.Contains(t, set["modules"], "web")
+}
@@ -0,0 +1,258 @@
+package score
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// schemaValidator checks yaml nodes against a json schema so that violations can be reported with the line and
+// column they occur at. It supports the subset of json schema used by the Score specification and workload profiles:
+// type, enum, const, properties, required, additionalProperties, minProperties, items, minItems, minLength,
+// maxLength, pattern, minimum, maximum, allOf, anyOf, oneOf, and local $ref.
+type schemaValidator struct {
+	root map[string]interface{}
+	// prefix is added to every message, eg: to name the workload profile being checked.
+	prefix string
+	// fileOf returns the file a node was read from.
+	fileOf func(node *yaml.Node) string
+}
+
+func (v *schemaValidator) issue(node *yaml.Node, path []string, format string, args ...interface{}) Issue {
+	return Issue{
+		Severity: SeverityError,
+		File:     v.fileOf(node),
+		Line:     node.Line,
+		Column:   node.Column,
+		Path:     strings.Join(path, "."),
+		Message:  v.prefix + fmt.Sprintf(format, args...),
+	}
+}
+
+func (v *schemaValidator) resolveRef(ref string) (map[string]interface{}, error) {
+	current := interface{}(v.root)
+	rest, ok := strings.CutPrefix(ref, "#/")
+	if !ok {
+		return nil, fmt.Errorf("unsupported schema reference '%s'", ref)
+	}
+	for _, part := range strings.Split(rest, "/") {
+		m, _ := current.(map[string]interface{})
+		if current = m[part]; current == nil {
+			return nil, fmt.Errorf("unresolved schema reference '%s'", ref)
+		}
+	}
+	out, ok := current.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("schema reference '%s' is not an object", ref)
+	}
+	return out, nil
+}
+
+// nodeType returns the json type of a node.
+func nodeType(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	}
+	switch node.ShortTag() {
+	case "!!int":
+		return "integer"
+	case "!!float":
+		return "number"
+	case "!!bool":
+		return "boolean"
+	case "!!null":
+		return "null"
+	}
+	return "string"
+}
+
+func typeMatches(actual string, allowed []string) bool {
+	return slices.Contains(allowed, actual) || (actual == "integer" && slices.Contains(allowed, "number"))
+}
+
+func stringsOf(v interface{}) []string {
+	switch x := v.(type) {
+	case string:
+		return []string{x}
+	case []interface{}:
+		out := make([]string, 0, len(x))
+		for _, item := range x {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func numberOf(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case int:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case float64:
+		return x, true
+	}
+	return 0, false
+}
+
+// equalValues compares a decoded yaml value with a schema value, treating all numbers alike.
+func equalValues(a, b interface{}) bool {
+	if an, ok := numberOf(a); ok {
+		bn, ok := numberOf(b)
+		return ok && an == bn
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+func (v *schemaValidator) validate(node *yaml.Node, schema map[string]interface{}, path []string) []Issue {
+	for node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	out := make([]Issue, 0)
+
+	if ref, ok := schema["$ref"].(string); ok {
+		target, err := v.resolveRef(ref)
+		if err != nil {
+			return append(out, v.issue(node, path, "%v", err))
+		}
+		out = append(out, v.validate(node, target, path)...)
+	}
+	for _, sub := range schemasOf(schema["allOf"]) {
+		out = append(out, v.validate(node, sub, path)...)
+	}
+	if branches := schemasOf(schema["anyOf"]); len(branches) > 0 {
+		if best := v.bestBranch(node, branches, path); len(best) > 0 {
+			out = append(out, best...)
+		}
+	}
+	if branches := schemasOf(schema["oneOf"]); len(branches) > 0 {
+		matching := 0
+		for _, b := range branches {
+			if len(v.validate(node, b, path)) == 0 {
+				matching++
+			}
+		}
+		if matching == 0 {
+			out = append(out, v.bestBranch(node, branches, path)...)
+		} else if matching > 1 {
+			out = append(out, v.issue(node, path, "matches more than one of the allowed forms"))
+		}
+	}
+
+	actual := nodeType(node)
+	if types := stringsOf(schema["type"]); len(types) > 0 && !typeMatches(actual, types) {
+		return append(out, v.issue(node, path, "expected %s but found %s", strings.Join(types, " or "), actual))
+	}
+
+	if node.Kind == yaml.ScalarNode {
+		var value interface{}
+		_ = node.Decode(&value)
+		if enum, ok := schema["enum"].([]interface{}); ok && !slices.ContainsFunc(enum, func(e interface{}) bool { return equalValues(value, e) }) {
+			allowed := make([]string, 0, len(enum))
+			for _, e := range enum {
+				allowed = append(allowed, fmt.Sprint(e))
+			}
+			out = append(out, v.issue(node, path, "'%s' is not one of %s", node.Value, strings.Join(allowed, ", ")))
+		}
+		if c, ok := schema["const"]; ok && !equalValues(value, c) {
+			out = append(out, v.issue(node, path, "must be '%v'", c))
+		}
+		if actual == "string" {
+			if n, ok := numberOf(schema["minLength"]); ok && float64(len(node.Value)) < n {
+				out = append(out, v.issue(node, path, "must be at least %v characters long", n))
+			}
+			if n, ok := numberOf(schema["maxLength"]); ok && float64(len(node.Value)) > n {
+				out = append(out, v.issue(node, path, "must be at most %v characters long", n))
+			}
+			if p, ok := schema["pattern"].(string); ok {
+				if re, err := regexp.Compile(p); err == nil && !re.MatchString(node.Value) {
+					out = append(out, v.issue(node, path, "'%s' does not match the pattern %s", node.Value, p))
+				}
+			}
+		}
+		if n, ok := numberOf(value); ok && (actual == "integer" || actual == "number") {
+			if m, ok := numberOf(schema["minimum"]); ok && n < m {
+				out = append(out, v.issue(node, path, "must be at least %v", m))
+			}
+			if m, ok := numberOf(schema["maximum"]); ok && n > m {
+				out = append(out, v.issue(node, path, "must be at most %v", m))
+			}
+		}
+	}
+
+	if node.Kind == yaml.MappingNode {
+		properties, _ := schema["properties"].(map[string]interface{})
+		present := make(map[string]bool)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			present[key.Value] = true
+			childPath := append(slices.Clip(path), key.Value)
+			if sub, ok := properties[key.Value].(map[string]interface{}); ok {
+				out = append(out, v.validate(value, sub, childPath)...)
+			} else if additional, ok := schema["additionalProperties"]; ok {
+				if allowed, ok := additional.(bool); ok && !allowed {
+					out = append(out, v.issue(key, childPath, "unknown property '%s'", key.Value))
+				} else if sub, ok := additional.(map[string]interface{}); ok {
+					out = append(out, v.validate(value, sub, childPath)...)
+				}
+			}
+		}
+		for _, r := range stringsOf(schema["required"]) {
+			if !present[r] {
+				out = append(out, v.issue(node, path, "missing required property '%s'", r))
+			}
+		}
+		if n, ok := numberOf(schema["minProperties"]); ok && float64(len(node.Content)/2) < n {
+			out = append(out, v.issue(node, path, "must have at least %v entries", n))
+		}
+	}
+
+	if node.Kind == yaml.SequenceNode {
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range node.Content {
+				out = append(out, v.validate(item, items, append(slices.Clip(path), fmt.Sprint(i)))...)
+			}
+		}
+		if n, ok := numberOf(schema["minItems"]); ok && float64(len(node.Content)) < n {
+			out = append(out, v.issue(node, path, "must have at least %v items", n))
+		}
+	}
+	return out
+}
+
+// bestBranch returns the issues of the branch that is closest to matching, or nothing when any branch matches.
+func (v *schemaValidator) bestBranch(node *yaml.Node, branches []map[string]interface{}, path []string) []Issue {
+	var best []Issue
+	for i, b := range branches {
+		issues := v.validate(node, b, path)
+		if len(issues) == 0 {
+			return nil
+		} else if i == 0 || len(issues) < len(best) {
+			best = issues
+		}
+	}
+	return best
+}
+
+func schemasOf(v interface{}) []map[string]interface{} {
+	raw, _ := v.([]interface{})
+	out := make([]map[string]interface{}, 0, len(raw))
+	for _, r := range raw {
+		if m, ok := r.(map[string]interface{}); ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
@@ -0,0 +1,354 @@
+// Package score validates Score workload files and their Humanitec extensions before they are deployed.
+package score
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+
+	// DefaultProfile is the workload profile used when the extensions do not name one.
+	DefaultProfile = "humanitec/default-module"
+	// ExtensionsFileName is the conventional name of the Humanitec extensions file next to a Score file.
+	ExtensionsFileName = "humanitec.score.yaml"
+)
+
+var (
+	//go:embed score-v1b1.schema.json
+	rawScoreSchema []byte
+	//go:embed humanitec-extensions.schema.json
+	rawExtensionsSchema []byte
+
+	scoreSchema      = mustParseSchema(rawScoreSchema)
+	extensionsSchema = mustParseSchema(rawExtensionsSchema)
+
+	placeholderPattern = regexp.MustCompile(`\$\{([^}]*)\}`)
+	yamlLinePattern    = regexp.MustCompile(`line (\d+)`)
+)
+
+func mustParseSchema(raw []byte) map[string]interface{} {
+	var out map[string]interface{}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		panic(fmt.Errorf("invalid bundled schema: %w", err))
+	}
+	return out
+}
+
+// Issue is a single problem found in a file.
+type Issue struct {
+	Severity string `json:"severity"`
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (i Issue) String() string {
+	location := i.File
+	if i.Line > 0 {
+		location += fmt.Sprintf(":%d:%d", i.Line, i.Column)
+	}
+	if i.Path != "" {
+		return fmt.Sprintf("%s: %s: %s: %s", location, i.Severity, i.Path, i.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", location, i.Severity, i.Message)
+}
+
+// Report is the outcome of validating a Score file.
+type Report struct {
+	// Profile is the workload profile the workload is checked against.
+	Profile string  `json:"profile"`
+	Issues  []Issue `json:"issues"`
+}
+
+func (r *Report) count(severity string) int {
+	out := 0
+	for _, i := range r.Issues {
+		if i.Severity == severity {
+			out++
+		}
+	}
+	return out
+}
+
+func (r *Report) Errors() int {
+	return r.count(SeverityError)
+}
+
+func (r *Report) Warnings() int {
+	return r.count(SeverityWarning)
+}
+
+// Input is the name and content of a file to validate.
+type Input struct {
+	File    string
+	Content []byte
+}
+
+// Options adds the context needed for the Humanitec specific checks.
+type Options struct {
+	// Extensions is the optional Humanitec extensions file.
+	Extensions *Input
+	// Profile overrides the workload profile named in the extensions.
+	Profile string
+	// ProfileSchema returns the spec schema of a workload profile. The profile checks are skipped with a warning when
+	// it is nil or fails.
+	ProfileSchema func(profileId string) (map[string]interface{}, error)
+	// ResourceTypes are the resource types that have a Resource Definition. The check is skipped when nil.
+	ResourceTypes []string
+}
+
+// parse decodes the file and reports a syntax error as an issue.
+func parse(in Input) (*yaml.Node, *Issue) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(in.Content, &doc); err != nil {
+		out := &Issue{Severity: SeverityError, File: in.File, Message: strings.TrimPrefix(err.Error(), "yaml: ")}
+		if m := yamlLinePattern.FindStringSubmatch(err.Error()); m != nil {
+			out.Line, _ = strconv.Atoi(m[1])
+			out.Column = 1
+		}
+		return nil, out
+	} else if len(doc.Content) == 0 {
+		return nil, &Issue{Severity: SeverityError, File: in.File, Message: "the file is empty"}
+	}
+	return doc.Content[0], nil
+}
+
+// lookup returns the value node of a key in a mapping node.
+func lookup(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+func walkScalars(node *yaml.Node, path []string, fn func(node *yaml.Node, path []string)) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		fn(node, path)
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			walkScalars(node.Content[i+1], append(slices.Clip(path), node.Content[i].Value), fn)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			walkScalars(item, append(slices.Clip(path), strconv.Itoa(i)), fn)
+		}
+	}
+}
+
+// Validate checks a Score file against the Score specification, the Humanitec extensions, and the workload profile,
+// and returns the line numbered errors and warnings.
+func Validate(score Input, opts Options) *Report {
+	report := &Report{Profile: DefaultProfile, Issues: make([]Issue, 0)}
+	files := make(map[*yaml.Node]string)
+	fileOf := func(node *yaml.Node) string {
+		if f, ok := files[node]; ok {
+			return f
+		}
+		return score.File
+	}
+	warn := func(file string, node *yaml.Node, path []string, format string, args ...interface{}) {
+		report.Issues = append(report.Issues, Issue{
+			Severity: SeverityWarning, File: file, Line: node.Line, Column: node.Column, Path: strings.Join(path, "."), Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	root, syntaxIssue := parse(score)
+	if syntaxIssue != nil {
+		report.Issues = append(report.Issues, *syntaxIssue)
+		return report
+	}
+	report.Issues = append(report.Issues, (&schemaValidator{root: scoreSchema, fileOf: fileOf}).validate(root, scoreSchema, nil)...)
+
+	var extRoot *yaml.Node
+	if opts.Extensions != nil {
+		if extRoot, syntaxIssue = parse(*opts.Extensions); syntaxIssue != nil {
+			report.Issues = append(report.Issues, *syntaxIssue)
+		} else {
+			walkNodes(extRoot, func(n *yaml.Node) { files[n] = opts.Extensions.File })
+			report.Issues = append(report.Issues, (&schemaValidator{root: extensionsSchema, fileOf: fileOf}).validate(extRoot, extensionsSchema, nil)...)
+			if _, p := lookup(extRoot, "profile"); p != nil && p.Kind == yaml.ScalarNode && p.Value != "" {
+				report.Profile = p.Value
+			}
+		}
+	}
+	if opts.Profile != "" {
+		report.Profile = opts.Profile
+	}
+
+	_, resources := lookup(root, "resources")
+	_, containers := lookup(root, "containers")
+	if containers != nil && containers.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(containers.Content); i += 2 {
+			name, container := containers.Content[i].Value, containers.Content[i+1]
+			if _, image := lookup(container, "image"); image != nil && image.Value == "." {
+				warn(score.File, image, []string{"containers", name, "image"}, "the image '.' must be replaced with a built image when deploying")
+			}
+			walkScalars(container, []string{"containers", name}, func(node *yaml.Node, path []string) {
+				report.Issues = append(report.Issues, checkPlaceholders(score.File, node, path, root, resources)...)
+			})
+		}
+	}
+
+	if resources != nil && resources.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(resources.Content); i += 2 {
+			name := resources.Content[i].Value
+			if _, t := lookup(resources.Content[i+1], "type"); t != nil && opts.ResourceTypes != nil && t.Value != "environment" && !slices.Contains(opts.ResourceTypes, t.Value) {
+				warn(score.File, t, []string{"resources", name, "type"}, "no Resource Definition of type '%s' exists in the Organization, deployments will fail unless one is added", t.Value)
+			}
+		}
+	}
+	if _, extResources := lookup(extRoot, "resources"); extResources != nil && extResources.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(extResources.Content); i += 2 {
+			if key, _ := lookup(resources, extResources.Content[i].Value); key == nil {
+				warn(opts.Extensions.File, extResources.Content[i], []string{"resources", extResources.Content[i].Value}, "the resource is not declared in the Score file")
+			}
+		}
+	}
+
+	if opts.ProfileSchema == nil {
+		report.Issues = append(report.Issues, Issue{Severity: SeverityWarning, File: score.File, Message: fmt.Sprintf("the workload was not checked against workload profile '%s' since no Organization was given", report.Profile)})
+	} else if schema, err := opts.ProfileSchema(report.Profile); err != nil {
+		report.Issues = append(report.Issues, Issue{Severity: SeverityWarning, File: score.File, Message: fmt.Sprintf("the workload was not checked against workload profile '%s': %v", report.Profile, err)})
+	} else if schema != nil {
+		spec := buildSpec(root, extRoot)
+		if _, extSpec := lookup(extRoot, "spec"); extSpec != nil {
+			files[spec] = opts.Extensions.File
+		}
+		v := &schemaValidator{root: schema, prefix: fmt.Sprintf("workload profile '%s': ", report.Profile), fileOf: fileOf}
+		report.Issues = append(report.Issues, v.validate(spec, schema, []string{"spec"})...)
+	}
+
+	slices.SortStableFunc(report.Issues, func(a, b Issue) int {
+		if a.File != b.File {
+			return strings.Compare(a.File, b.File)
+		} else if a.Line != b.Line {
+			return a.Line - b.Line
+		}
+		return a.Column - b.Column
+	})
+	return report
+}
+
+func walkNodes(node *yaml.Node, fn func(n *yaml.Node)) {
+	fn(node)
+	for _, c := range node.Content {
+		walkNodes(c, fn)
+	}
+}
+
+// checkPlaceholders reports ${...} references that cannot be resolved. $${ escapes a literal ${.
+func checkPlaceholders(file string, node *yaml.Node, path []string, root, resources *yaml.Node) []Issue {
+	out := make([]Issue, 0)
+	value := strings.ReplaceAll(node.Value, "$${", "")
+	for _, m := range placeholderPattern.FindAllStringSubmatch(value, -1) {
+		parts := strings.Split(m[1], ".")
+		var problem string
+		switch parts[0] {
+		case "metadata":
+			if _, metadata := lookup(root, "metadata"); len(parts) < 2 {
+				problem = "must name a metadata field"
+			} else if key, _ := lookup(metadata, parts[1]); key == nil {
+				problem = fmt.Sprintf("metadata field '%s' is not set", parts[1])
+			}
+		case "resources":
+			if len(parts) < 2 {
+				problem = "must name a resource"
+			} else if key, _ := lookup(resources, parts[1]); key == nil {
+				problem = fmt.Sprintf("resource '%s' is not declared in resources", parts[1])
+			}
+		default:
+			problem = "only metadata and resources can be referenced"
+		}
+		if problem != "" {
+			out = append(out, Issue{
+				Severity: SeverityError, File: file, Line: node.Line, Column: node.Column, Path: strings.Join(path, "."),
+				Message: fmt.Sprintf("placeholder '%s' %s", m[0], problem),
+			})
+		}
+	}
+	return out
+}
+
+// buildSpec converts the Score workload into the spec of a Deployment Set module so that it can be checked against the
+// workload profile. The nodes of the original files are reused so that issues keep their line numbers.
+func buildSpec(root, extRoot *yaml.Node) *yaml.Node {
+	containersKey, containers := lookup(root, "containers")
+	spec := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if containersKey != nil {
+		spec.Line, spec.Column = containersKey.Line, containersKey.Column
+	}
+	if containers != nil && containers.Kind == yaml.MappingNode {
+		specContainers := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: containers.Line, Column: containers.Column}
+		for i := 0; i+1 < len(containers.Content); i += 2 {
+			c := containers.Content[i+1]
+			specContainer := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: c.Line, Column: c.Column}
+			for _, field := range []string{"image", "command", "args", "variables"} {
+				if k, v := lookup(c, field); k != nil {
+					specContainer.Content = append(specContainer.Content, k, v)
+				}
+			}
+			specContainers.Content = append(specContainers.Content, containers.Content[i], specContainer)
+		}
+		spec.Content = append(spec.Content, containersKey, specContainers)
+	}
+	if _, extSpec := lookup(extRoot, "spec"); extSpec != nil && extSpec.Kind == yaml.MappingNode {
+		mergeMapping(spec, extSpec)
+		spec.Line, spec.Column = extSpec.Line, extSpec.Column
+	}
+	return spec
+}
+
+// mergeMapping merges the entries of src into dst, recursing into mappings present in both.
+func mergeMapping(dst, src *yaml.Node) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		j := slices.IndexFunc(dst.Content, func(n *yaml.Node) bool { return n.Value == key.Value })
+		if j < 0 || j%2 != 0 {
+			dst.Content = append(dst.Content, key, value)
+		} else if existing := dst.Content[j+1]; existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode {
+			merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: value.Line, Column: value.Column, Content: slices.Clone(existing.Content)}
+			mergeMapping(merged, value)
+			dst.Content[j+1] = merged
+		} else {
+			dst.Content[j+1] = value
+		}
+	}
+}
+
+// ReadInput reads a file to validate.
+func ReadInput(p string) (Input, error) {
+	raw, err := os.ReadFile(p)
+	if err != nil {
+		return Input{}, fmt.Errorf("failed to read '%s': %w", p, err)
+	}
+	return Input{File: p, Content: raw}, nil
+}
+
+// DefaultExtensionsPath returns the extensions file next to a Score file, or nothing when there is none.
+func DefaultExtensionsPath(scorePath string) string {
+	p := filepath.Join(filepath.Dir(scorePath), ExtensionsFileName)
+	if info, err := os.Stat(p); err == nil && info.Mode().IsRegular() {
+		return p
+	}
+	return ""
+}
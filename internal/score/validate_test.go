@@ -0,0 +1,95 @@
+package score
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func issueStrings(r *Report) []string {
+	out := make([]string, 0, len(r.Issues))
+	for _, i := range r.Issues {
+		out = append(out, i.String())
+	}
+	return out
+}
+
+func TestValidate_valid(t *testing.T) {
+	r := Validate(Input{File: "score.yaml", Content: []byte(`apiVersion: score.dev/v1b1
+metadata:
+  name: api
+containers:
+  api:
+    image: ghcr.io/example/api:1.0.0
+    variables:
+      DB_HOST: ${resources.db.host}
+      NAME: ${metadata.name}
+      LITERAL: $${not.a.placeholder}
+resources:
+  db:
+    type: postgres
+`)}, Options{ResourceTypes: []string{"postgres"}, ProfileSchema: func(string) (map[string]interface{}, error) {
+		return map[string]interface{}{"type": "object"}, nil
+	}})
+	assert.Empty(t, r.Issues)
+	assert.Equal(t, DefaultProfile, r.Profile)
+}
+
+func TestValidate_issues(t *testing.T) {
+	r := Validate(Input{File: "score.yaml", Content: []byte(`apiVersion: score.dev/v1b1
+metadata:
+  name: Not_Valid
+containers:
+  api:
+    image: .
+    variables:
+      CACHE: ${resources.cache.host}
+    unknown: true
+resources:
+  queue:
+    type: rabbitmq
+`)}, Options{
+		Extensions: &Input{File: "humanitec.score.yaml", Content: []byte(`apiVersion: humanitec.org/v1b1
+profile: example/cronjob
+spec:
+  replicas: many
+`)},
+		ResourceTypes: []string{"postgres"},
+		ProfileSchema: func(id string) (map[string]interface{}, error) {
+			return map[string]interface{}{
+				"type":     "object",
+				"required": []interface{}{"schedule"},
+				"properties": map[string]interface{}{
+					"replicas": map[string]interface{}{"type": "integer"},
+				},
+			}, nil
+		},
+	})
+	assert.Equal(t, "example/cronjob", r.Profile)
+	assert.Equal(t, []string{
+		"humanitec.score.yaml:4:3: error: spec: workload profile 'example/cronjob': missing required property 'schedule'",
+		"humanitec.score.yaml:4:13: error: spec.replicas: workload profile 'example/cronjob': expected integer but found string",
+		"score.yaml:3:9: error: metadata.name: 'Not_Valid' does not match the pattern ^[a-z0-9][a-z0-9-]*[a-z0-9]$",
+		"score.yaml:6:12: warning: containers.api.image: the image '.' must be replaced with a built image when deploying",
+		"score.yaml:8:14: error: containers.api.variables.CACHE: placeholder '${resources.cache.host}' resource 'cache' is not declared in resources",
+		"score.yaml:9:5: error: containers.api.unknown: unknown property 'unknown'",
+		"score.yaml:12:11: warning: resources.queue.type: no Resource Definition of type 'rabbitmq' exists in the Organization, deployments will fail unless one is added",
+	}, issueStrings(r))
+	assert.Equal(t, 5, r.Errors())
+	assert.Equal(t, 2, r.Warnings())
+}
+
+func TestValidate_syntaxError(t *testing.T) {
+	r := Validate(Input{File: "score.yaml", Content: []byte("apiVersion: score.dev/v1b1\nmetadata:\n  name: [\n")}, Options{})
+	assert.Len(t, r.Issues, 1)
+	assert.Equal(t, SeverityError, r.Issues[0].Severity)
+	assert.Greater(t, r.Issues[0].Line, 0)
+}
+
+func TestValidate_profileUnavailable(t *testing.T) {
+	r := Validate(Input{File: "score.yaml", Content: []byte("apiVersion: score.dev/v1b1\nmetadata:\n  name: api\ncontainers:\n  api:\n    image: busybox\n")}, Options{
+		ProfileSchema: func(id string) (map[string]interface{}, error) { return nil, fmt.Errorf("not found") },
+	})
+	assert.Equal(t, []string{"score.yaml: warning: the workload was not checked against workload profile 'humanitec/default-module': not found"}, issueStrings(r))
+}